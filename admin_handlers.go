@@ -24,7 +24,7 @@ func handleAdminCreateUser(w http.ResponseWriter, r *http.Request, adminUser mod
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(newUser)
-	logAuditEvent(adminUser.UserID, "ADMIN_CREATE_USER", fmt.Sprintf("Admin '%s' created new user '%s' with role '%s'", adminUser.Username, newUser.Username, newUser.Role))
+	logAuditEvent(adminUser.UserID, models.AuditActionCreateUser, fmt.Sprintf("Admin '%s' created new user '%s' with role '%s'", adminUser.Username, newUser.Username, newUser.Role))
 }
 
 // handleAdminUpdateUserRole handles updating a user's role.
@@ -49,7 +49,7 @@ func handleAdminUpdateUserRole(w http.ResponseWriter, r *http.Request, adminUser
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
-	logAuditEvent(adminUser.UserID, "ADMIN_UPDATE_ROLE", fmt.Sprintf("Admin '%s' changed role of user '%s' to '%s'", adminUser.Username, user.Username, user.Role))
+	logAuditEvent(adminUser.UserID, models.AuditActionUpdateRole, fmt.Sprintf("Admin '%s' changed role of user '%s' to '%s'", adminUser.Username, user.Username, user.Role))
 }
 
 // handleAdminCreateCheckpoint handles creating a new checkpoint.
@@ -65,7 +65,7 @@ func handleAdminCreateCheckpoint(w http.ResponseWriter, r *http.Request, adminUs
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(newCheckpoint)
-	logAuditEvent(adminUser.UserID, "ADMIN_CREATE_CHECKPOINT", fmt.Sprintf("Admin '%s' created new checkpoint '%s'", adminUser.Username, newCheckpoint.Name))
+	logAuditEvent(adminUser.UserID, models.AuditActionCreateCheckpoint, fmt.Sprintf("Admin '%s' created new checkpoint '%s'", adminUser.Username, newCheckpoint.Name))
 }
 
 // handleAdminGetCheckpoints returns all checkpoints.
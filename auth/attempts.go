@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// attemptRecord tracks consecutive failed logins for a single key
+type attemptRecord struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// LoginAttemptTracker counts failed logins per key (e.g. username or IP) within a window
+type LoginAttemptTracker struct {
+	records map[string]*attemptRecord
+	mu      sync.Mutex
+	window  time.Duration
+}
+
+// NewLoginAttemptTracker creates a tracker whose counters reset after window of inactivity
+func NewLoginAttemptTracker(window time.Duration) *LoginAttemptTracker {
+	t := &LoginAttemptTracker{
+		records: make(map[string]*attemptRecord),
+		window:  window,
+	}
+
+	ticker := time.NewTicker(window)
+	go func() {
+		for range ticker.C {
+			t.pruneExpired()
+		}
+	}()
+
+	return t
+}
+
+// RecordFailure increments the failure count for key and returns the new count
+func (t *LoginAttemptTracker) RecordFailure(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, exists := t.records[key]
+	if !exists || time.Since(record.lastFailure) > t.window {
+		record = &attemptRecord{}
+		t.records[key] = record
+	}
+	record.failures++
+	record.lastFailure = time.Now()
+
+	return record.failures
+}
+
+// Failures returns the current failure count for key
+func (t *LoginAttemptTracker) Failures(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, exists := t.records[key]
+	if !exists || time.Since(record.lastFailure) > t.window {
+		return 0
+	}
+	return record.failures
+}
+
+// LastFailure returns the time of the most recent counted failure for key,
+// or the zero time if it has none
+func (t *LoginAttemptTracker) LastFailure(key string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record, exists := t.records[key]
+	if !exists || time.Since(record.lastFailure) > t.window {
+		return time.Time{}
+	}
+	return record.lastFailure
+}
+
+// KeysAtLeast returns the keys starting with prefix whose current failure count is at
+// least threshold, with the prefix removed
+func (t *LoginAttemptTracker) KeysAtLeast(prefix string, threshold int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var keys []string
+	for key, record := range t.records {
+		if strings.HasPrefix(key, prefix) && record.failures >= threshold && time.Since(record.lastFailure) <= t.window {
+			keys = append(keys, strings.TrimPrefix(key, prefix))
+		}
+	}
+	return keys
+}
+
+// Window returns how long a key's failures are remembered after its last failure
+func (t *LoginAttemptTracker) Window() time.Duration {
+	return t.window
+}
+
+// Reset clears the failure count for key, e.g. after a successful login
+func (t *LoginAttemptTracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.records, key)
+}
+
+func (t *LoginAttemptTracker) pruneExpired() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, record := range t.records {
+		if time.Since(record.lastFailure) > t.window {
+			delete(t.records, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Known CAPTCHA provider verification endpoints
+const (
+	HCaptchaVerifyURL  = "https://hcaptcha.com/siteverify"
+	TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// CaptchaVerifier checks a CAPTCHA token submitted by a client
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// HTTPCaptchaVerifier verifies tokens against a siteverify-style API (hCaptcha, Turnstile)
+type HTTPCaptchaVerifier struct {
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+// NewHTTPCaptchaVerifier creates a verifier for the given siteverify endpoint and secret
+func NewHTTPCaptchaVerifier(verifyURL, secret string) *HTTPCaptchaVerifier {
+	return &HTTPCaptchaVerifier{
+		verifyURL: verifyURL,
+		secret:    secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify posts the token to the provider and reports whether it was accepted
+func (v *HTTPCaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to build captcha request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to parse captcha response: %w", err)
+	}
+
+	return result.Success, nil
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PasswordDenyList rejects common and breached passwords. Both sources are optional:
+// a list of banned passwords matched case-insensitively, and a local copy of a
+// k-anonymity breach dataset (one file per 5-character SHA-1 prefix holding
+// "SUFFIX:COUNT" lines, as published by Have I Been Pwned).
+type PasswordDenyList struct {
+	banned    map[string]bool
+	rangesDir string
+}
+
+// passwordDenyList is consulted by ValidatePasswordStrength; nil disables the check
+var passwordDenyList *PasswordDenyList
+
+// SetPasswordDenyList installs the deny list checked by ValidatePasswordStrength
+func SetPasswordDenyList(list *PasswordDenyList) {
+	passwordDenyList = list
+}
+
+// LoadPasswordDenyList reads the banned passwords at listPath (one per line, # starts a
+// comment) and remembers rangesDir for breach lookups. Either may be empty.
+func LoadPasswordDenyList(listPath, rangesDir string) (*PasswordDenyList, error) {
+	list := &PasswordDenyList{banned: make(map[string]bool), rangesDir: rangesDir}
+
+	if listPath != "" {
+		file, err := os.Open(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open password deny list: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			list.banned[strings.ToLower(line)] = true
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read password deny list: %w", err)
+		}
+	}
+
+	if rangesDir != "" {
+		info, err := os.Stat(rangesDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open breached password ranges: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("breached password ranges path %s is not a directory", rangesDir)
+		}
+	}
+
+	return list, nil
+}
+
+// Size returns the number of banned passwords loaded from the list file
+func (l *PasswordDenyList) Size() int {
+	return len(l.banned)
+}
+
+// violations returns the reasons the password is denied, if any
+func (l *PasswordDenyList) violations(password string) []string {
+	var violations []string
+	if l.banned[strings.ToLower(password)] {
+		violations = append(violations, "password is too common")
+	}
+	if l.rangesDir != "" {
+		breached, err := l.breached(password)
+		if err != nil {
+			// A missing or unreadable range file must not block password changes
+			log.Printf("⚠️  Breached password lookup failed: %v", err)
+		} else if breached {
+			violations = append(violations, "password appears in a known data breach")
+		}
+	}
+	return violations
+}
+
+// breached looks the password's SHA-1 up in the local range file for its 5-character
+// prefix, so only the prefix is ever used to locate data
+func (l *PasswordDenyList) breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	file, err := os.Open(filepath.Join(l.rangesDir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(l.rangesDir, prefix+".txt"))
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hashSuffix, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(hashSuffix, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"gatekeeper/models"
+	"time"
+)
+
+// ActivityWriteInterval is how often a user's last activity is persisted while their
+// session is in use. Idle expiry is therefore accurate to within this interval.
+const ActivityWriteInterval = time.Minute
+
+// IdlePolicy forces users to log in again after a period without authenticated requests,
+// independently of token expiry. A zero timeout disables the check.
+type IdlePolicy struct {
+	Timeout     time.Duration
+	RoleTimeout map[models.UserRole]time.Duration // Overrides Timeout for the listed roles
+}
+
+// TimeoutFor returns the idle timeout that applies to the role
+func (p IdlePolicy) TimeoutFor(role models.UserRole) time.Duration {
+	if timeout, ok := p.RoleTimeout[role]; ok {
+		return timeout
+	}
+	return p.Timeout
+}
+
+// Enabled reports whether any role has an idle timeout
+func (p IdlePolicy) Enabled() bool {
+	if p.Timeout > 0 {
+		return true
+	}
+	for _, timeout := range p.RoleTimeout {
+		if timeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the user has been inactive for longer than their role allows.
+// Users with no recorded activity are not expired; their next request records it.
+func (p IdlePolicy) Expired(user *models.User, now time.Time) bool {
+	timeout := p.TimeoutFor(user.Role)
+	return timeout > 0 && !user.LastActivityAt.IsZero() && now.Sub(user.LastActivityAt) > timeout
+}
+
+// ActivityDue reports whether the user's activity should be persisted now, which is at
+// most once per ActivityWriteInterval and only for roles with an idle timeout
+func (p IdlePolicy) ActivityDue(user *models.User, now time.Time) bool {
+	return p.TimeoutFor(user.Role) > 0 && now.Sub(user.LastActivityAt) >= ActivityWriteInterval
+}
@@ -9,19 +9,57 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Token types, carried in the typ claim so each kind of token is only accepted where it belongs
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// ErrWrongTokenType is returned when a valid token is presented where the other kind is expected
+var ErrWrongTokenType = errors.New("wrong token type")
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID   string          `json:"user_id"`
 	Username string          `json:"username"`
 	Role     models.UserRole `json:"role"`
+	OrgID    string          `json:"org_id,omitempty"`
+	// TokenType is TokenTypeAccess or TokenTypeRefresh
+	TokenType string `json:"typ"`
+	// PasswordChangedAt is the user's password_changed_at (Unix seconds) when the token was issued
+	PasswordChangedAt int64 `json:"pwd_changed_at,omitempty"`
+	// Permissions are the user's effective permissions when the token was issued, for
+	// clients deciding what to show. Authorization always uses the stored user.
+	Permissions []models.Permission `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IssuedBeforePasswordChange reports whether the token predates the user's latest
+// password change, in which case it must no longer be accepted
+func (c *Claims) IssuedBeforePasswordChange(user *models.User) bool {
+	return c.PasswordChangedAt < passwordChangedAt(user)
+}
+
+func passwordChangedAt(user *models.User) int64 {
+	if user.PasswordChangedAt.IsZero() {
+		return 0
+	}
+	return user.PasswordChangedAt.Unix()
+}
+
+// DefaultLeeway is the clock skew tolerated when validating exp and nbf
+const DefaultLeeway = 30 * time.Second
+
+// SupportedAlgorithms are the signing algorithms the manager can verify with its shared secret
+var SupportedAlgorithms = []string{"HS256", "HS384", "HS512"}
+
 // JWTManager handles JWT token generation and validation
 type JWTManager struct {
 	secretKey              []byte
 	tokenExpiration        time.Duration
 	refreshTokenExpiration time.Duration
+	leeway                 time.Duration
+	validMethods           []string // Accepted alg header values; everything else, including none, is rejected
 }
 
 // NewJWTManager creates a new JWT manager
@@ -30,15 +68,33 @@ func NewJWTManager(secretKey string, tokenExpiration, refreshTokenExpiration tim
 		secretKey:              []byte(secretKey),
 		tokenExpiration:        tokenExpiration,
 		refreshTokenExpiration: refreshTokenExpiration,
+		leeway:                 DefaultLeeway,
+		validMethods:           []string{jwt.SigningMethodHS256.Alg()},
 	}
 }
 
+// SetValidMethods sets the signing algorithms accepted during validation. Tokens whose
+// alg header names any other algorithm are rejected before their signature is checked.
+func (m *JWTManager) SetValidMethods(algorithms []string) {
+	m.validMethods = algorithms
+}
+
+// SetLeeway sets the clock skew tolerated when validating token times, for edge
+// devices whose clocks drift
+func (m *JWTManager) SetLeeway(leeway time.Duration) {
+	m.leeway = leeway
+}
+
 // GenerateToken generates a new JWT token for a user
 func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
 	claims := Claims{
-		UserID:   user.UserID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:            user.UserID,
+		Username:          user.Username,
+		Role:              user.Role,
+		OrgID:             user.OrgID,
+		TokenType:         TokenTypeAccess,
+		PasswordChangedAt: passwordChangedAt(user),
+		Permissions:       user.EffectivePermissions(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -57,18 +113,29 @@ func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
 	return signedToken, nil
 }
 
-// GenerateRefreshToken generates a refresh token with longer expiration
-func (m *JWTManager) GenerateRefreshToken(user *models.User) (string, error) {
+// RefreshTokenExpiration returns how long refresh tokens stay valid
+func (m *JWTManager) RefreshTokenExpiration() time.Duration {
+	return m.refreshTokenExpiration
+}
+
+// GenerateRefreshToken generates a refresh token with longer expiration.
+// The sessionID becomes the token's jti and ties it to a session in the TokenStore.
+func (m *JWTManager) GenerateRefreshToken(user *models.User, sessionID string) (string, error) {
 	claims := Claims{
-		UserID:   user.UserID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:            user.UserID,
+		Username:          user.Username,
+		Role:              user.Role,
+		OrgID:             user.OrgID,
+		TokenType:         TokenTypeRefresh,
+		PasswordChangedAt: passwordChangedAt(user),
+		Permissions:       user.EffectivePermissions(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.refreshTokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "gatekeeper-api",
 			Subject:   user.UserID,
+			ID:        sessionID,
 		},
 	}
 
@@ -89,7 +156,7 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return m.secretKey, nil
-	})
+	}, jwt.WithLeeway(m.leeway), jwt.WithValidMethods(m.validMethods))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -103,6 +170,29 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// ValidateAccessToken validates an access token. Refresh tokens are rejected with
+// ErrWrongTokenType, so a long-lived refresh token can't be used to call the API.
+func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return m.validateTokenType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a refresh token. Access tokens are rejected with
+// ErrWrongTokenType.
+func (m *JWTManager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return m.validateTokenType(tokenString, TokenTypeRefresh)
+}
+
+func (m *JWTManager) validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("%w: got %q, want %q", ErrWrongTokenType, claims.TokenType, tokenType)
+	}
+	return claims, nil
+}
+
 // ExtractToken extracts the token from the Authorization header
 // Expected format: "Bearer <token>"
 func ExtractToken(authHeader string) (string, error) {
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+	"gatekeeper/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-at-least-32-characters-long"
+
+func testUser() *models.User {
+	return &models.User{
+		UserID:   "user-1",
+		Username: "operator",
+		Role:     models.RoleGateOperator,
+		OrgID:    "org-1",
+	}
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	token, err := m.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	claims, err := m.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.Subject != "user-1" || claims.OrgID != "org-1" {
+		t.Errorf("claims = %+v, want user-1 in org-1", claims)
+	}
+	if claims.TokenType != TokenTypeAccess {
+		t.Errorf("TokenType = %q, want %q", claims.TokenType, TokenTypeAccess)
+	}
+	if claims.ID != "" {
+		t.Errorf("access token has jti %q, want none", claims.ID)
+	}
+}
+
+func TestRefreshTokenRejectedAsAccessToken(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	refresh, err := m.GenerateRefreshToken(testUser(), "session-1")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+
+	if _, err := m.ValidateAccessToken(refresh); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateAccessToken(refresh token) error = %v, want ErrWrongTokenType", err)
+	}
+	claims, err := m.ValidateRefreshToken(refresh)
+	if err != nil {
+		t.Fatalf("ValidateRefreshToken: %v", err)
+	}
+	if claims.ID != "session-1" {
+		t.Errorf("jti = %q, want session-1", claims.ID)
+	}
+}
+
+func TestAccessTokenRejectedAsRefreshToken(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	access, err := m.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if _, err := m.ValidateRefreshToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("ValidateRefreshToken(access token) error = %v, want ErrWrongTokenType", err)
+	}
+}
+
+func TestExpiredTokenRejected(t *testing.T) {
+	m := NewJWTManager(testSecret, -time.Hour, -time.Hour)
+	m.SetLeeway(0)
+	access, err := m.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	refresh, err := m.GenerateRefreshToken(testUser(), "session-1")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+
+	if _, err := m.ValidateAccessToken(access); err == nil {
+		t.Error("expired access token was accepted")
+	}
+	if _, err := m.ValidateRefreshToken(refresh); err == nil {
+		t.Error("expired refresh token was accepted")
+	}
+}
+
+func TestTokenSignedWithOtherSecretRejected(t *testing.T) {
+	other := NewJWTManager("another-secret-at-least-32-characters", time.Hour, 24*time.Hour)
+	token, err := other.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	if _, err := m.ValidateAccessToken(token); err == nil {
+		t.Error("token signed with another secret was accepted")
+	}
+}
+
+// signClaims signs an access token for testUser with the given method and key, so tests
+// can control the times and header the manager would otherwise set
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, notBefore time.Time) string {
+	t.Helper()
+	claims := &Claims{
+		UserID:    "user-1",
+		Username:  "operator",
+		Role:      models.RoleGateOperator,
+		TokenType: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(notBefore),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return token
+}
+
+func TestNotBeforeWithinLeewayAccepted(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+
+	// Issued by a server whose clock runs 10s ahead
+	skewed := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), time.Now().Add(10*time.Second))
+	if _, err := m.ValidateAccessToken(skewed); err != nil {
+		t.Errorf("token with nbf inside the leeway was rejected: %v", err)
+	}
+
+	future := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), time.Now().Add(2*time.Minute))
+	if _, err := m.ValidateAccessToken(future); err == nil {
+		t.Error("token with nbf beyond the leeway was accepted")
+	}
+
+	m.SetLeeway(0)
+	if _, err := m.ValidateAccessToken(skewed); err == nil {
+		t.Error("token with nbf in the future was accepted without leeway")
+	}
+}
+
+func TestUnexpectedAlgorithmRejected(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+
+	hs512 := signClaims(t, jwt.SigningMethodHS512, []byte(testSecret), time.Now())
+	if _, err := m.ValidateAccessToken(hs512); err == nil {
+		t.Error("HS512 token was accepted while only HS256 is allowed")
+	}
+
+	none := signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now())
+	if _, err := m.ValidateAccessToken(none); err == nil {
+		t.Error("unsigned token was accepted")
+	}
+
+	m.SetValidMethods([]string{"HS256", "HS512"})
+	if _, err := m.ValidateAccessToken(hs512); err != nil {
+		t.Errorf("HS512 token was rejected after allowing it: %v", err)
+	}
+	if _, err := m.ValidateAccessToken(none); err == nil {
+		t.Error("unsigned token was accepted after allowing HS512")
+	}
+}
@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -40,10 +43,23 @@ func CheckPassword(password, hash string) error {
 	return nil
 }
 
-// ValidatePasswordStrength checks if a password meets security requirements
+// PasswordPolicyError lists every password rule a candidate password failed
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+// Error joins the violations into a single summary for callers that only show one message
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// ValidatePasswordStrength checks if a password meets security requirements, including
+// the deny list when one is installed. It reports all failed rules at once as a
+// *PasswordPolicyError.
 func ValidatePasswordStrength(password string) error {
+	var violations []string
 	if len(password) < MinPasswordLength {
-		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+		violations = append(violations, fmt.Sprintf("password must be at least %d characters", MinPasswordLength))
 	}
 
 	// Check for at least one letter
@@ -61,11 +77,43 @@ func ValidatePasswordStrength(password string) error {
 	}
 
 	if !hasLetter {
-		return errors.New("password must contain at least one letter")
+		violations = append(violations, "password must contain at least one letter")
 	}
 	if !hasNumber {
-		return errors.New("password must contain at least one number")
+		violations = append(violations, "password must contain at least one number")
+	}
+	if passwordDenyList != nil {
+		violations = append(violations, passwordDenyList.violations(password)...)
 	}
 
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
 	return nil
 }
+
+// generatedPasswordLength is long enough that generated passwords need no symbols
+const generatedPasswordLength = 16
+
+// generatedPasswordAlphabet leaves out characters that are easily misread when a
+// password is read out or copied by hand (0/O, 1/l/I)
+const generatedPasswordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GeneratePassword returns a random password that satisfies ValidatePasswordStrength
+func GeneratePassword() (string, error) {
+	max := big.NewInt(int64(len(generatedPasswordAlphabet)))
+	for {
+		password := make([]byte, generatedPasswordLength)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate password: %w", err)
+			}
+			password[i] = generatedPasswordAlphabet[n.Int64()]
+		}
+		// Retry the rare draw with no letter or no digit
+		if ValidatePasswordStrength(string(password)) == nil {
+			return string(password), nil
+		}
+	}
+}
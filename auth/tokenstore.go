@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"gatekeeper/models"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a refresh session does not exist
+var ErrSessionNotFound = errors.New("refresh session not found")
+
+// TokenStore persists refresh-session state so refresh tokens can be revoked
+// individually or per user. db.FirestoreDB implements it for production use.
+type TokenStore interface {
+	SaveRefreshSession(session *models.RefreshSession) error
+	GetRefreshSession(sessionID string) (*models.RefreshSession, error)
+	RevokeRefreshSession(sessionID string) error
+	RevokeUserRefreshSessions(userID string) error
+}
+
+// SessionActive reports whether a refresh token with the given claims is backed by a
+// live session in the store
+func SessionActive(store TokenStore, claims *Claims) bool {
+	if claims.ID == "" {
+		return false
+	}
+
+	session, err := store.GetRefreshSession(claims.ID)
+	if err != nil {
+		return false
+	}
+	return session.UserID == claims.UserID && !session.Revoked && time.Now().Before(session.ExpiresAt)
+}
+
+// MemoryTokenStore keeps refresh sessions in process memory. It suits tests and
+// single-instance development servers; sessions are lost on restart.
+type MemoryTokenStore struct {
+	sessions map[string]models.RefreshSession
+	mu       sync.Mutex
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		sessions: make(map[string]models.RefreshSession),
+	}
+}
+
+func (s *MemoryTokenStore) SaveRefreshSession(session *models.RefreshSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneExpired()
+	s.sessions[session.SessionID] = *session
+	return nil
+}
+
+func (s *MemoryTokenStore) GetRefreshSession(sessionID string) (*models.RefreshSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (s *MemoryTokenStore) RevokeRefreshSession(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	session.Revoked = true
+	s.sessions[sessionID] = session
+	return nil
+}
+
+func (s *MemoryTokenStore) RevokeUserRefreshSessions(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			session.Revoked = true
+			s.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+// pruneExpired drops sessions past their expiry. Callers must hold the lock.
+func (s *MemoryTokenStore) pruneExpired() {
+	now := time.Now()
+	for id, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
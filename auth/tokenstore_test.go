@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"gatekeeper/models"
+	"testing"
+	"time"
+)
+
+// issueRefresh saves a session for user in store and returns the claims of a refresh
+// token issued for it, as the login handler does
+func issueRefresh(t *testing.T, m *JWTManager, store TokenStore, user *models.User, sessionID string, expiresAt time.Time) *Claims {
+	t.Helper()
+	session := &models.RefreshSession{
+		SessionID: sessionID,
+		UserID:    user.UserID,
+		IssuedAt:  time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := store.SaveRefreshSession(session); err != nil {
+		t.Fatalf("SaveRefreshSession: %v", err)
+	}
+	token, err := m.GenerateRefreshToken(user, sessionID)
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+	claims, err := m.ValidateRefreshToken(token)
+	if err != nil {
+		t.Fatalf("ValidateRefreshToken: %v", err)
+	}
+	return claims
+}
+
+func TestSessionActiveAfterIssue(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	claims := issueRefresh(t, m, store, testUser(), "session-1", time.Now().Add(time.Hour))
+
+	if !SessionActive(store, claims) {
+		t.Error("freshly issued session is not active")
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	user := testUser()
+	old := issueRefresh(t, m, store, user, "session-1", time.Now().Add(time.Hour))
+
+	// A new login replaces the old session, which is revoked
+	current := issueRefresh(t, m, store, user, "session-2", time.Now().Add(time.Hour))
+	if err := store.RevokeRefreshSession(old.ID); err != nil {
+		t.Fatalf("RevokeRefreshSession: %v", err)
+	}
+
+	if SessionActive(store, old) {
+		t.Error("rotated-out session is still active")
+	}
+	if !SessionActive(store, current) {
+		t.Error("current session is not active after rotation")
+	}
+}
+
+func TestRevokeUserRefreshSessions(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	user := testUser()
+	other := &models.User{UserID: "user-2", Username: "supervisor", Role: models.RoleSupervisor, OrgID: "org-1"}
+
+	first := issueRefresh(t, m, store, user, "session-1", time.Now().Add(time.Hour))
+	second := issueRefresh(t, m, store, user, "session-2", time.Now().Add(time.Hour))
+	unrelated := issueRefresh(t, m, store, other, "session-3", time.Now().Add(time.Hour))
+
+	if err := store.RevokeUserRefreshSessions(user.UserID); err != nil {
+		t.Fatalf("RevokeUserRefreshSessions: %v", err)
+	}
+
+	if SessionActive(store, first) || SessionActive(store, second) {
+		t.Error("a revoked user's session is still active")
+	}
+	if !SessionActive(store, unrelated) {
+		t.Error("another user's session was revoked")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	claims := issueRefresh(t, m, store, testUser(), "session-1", time.Now().Add(-time.Minute))
+
+	if SessionActive(store, claims) {
+		t.Error("expired session is active")
+	}
+}
+
+func TestSessionActiveRejectsOtherUsersSession(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	claims := issueRefresh(t, m, store, testUser(), "session-1", time.Now().Add(time.Hour))
+
+	forged := *claims
+	forged.UserID = "user-2"
+	if SessionActive(store, &forged) {
+		t.Error("session was accepted for a user it wasn't issued to")
+	}
+}
+
+func TestSessionActiveRequiresSessionID(t *testing.T) {
+	m := NewJWTManager(testSecret, time.Hour, 24*time.Hour)
+	store := NewMemoryTokenStore()
+	access, err := m.GenerateToken(testUser())
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := m.ValidateAccessToken(access)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+
+	if SessionActive(store, claims) {
+		t.Error("token without a session ID is backed by a session")
+	}
+}
@@ -0,0 +1,213 @@
+// gatekeeper-admin is an operator tool for bootstrapping and recovering admin access.
+// It talks to Firestore directly with the same configuration as the server, so it works
+// even when nobody can log in.
+//
+// Usage:
+//
+//	gatekeeper-admin create-admin -username <name> [-org <org_id>]
+//	gatekeeper-admin list-users
+//	gatekeeper-admin reset-password -username <name>
+//
+// Passwords are read from the GATEKEEPER_ADMIN_PASSWORD environment variable or, when it
+// is unset, from the first line of standard input. They are never taken as flags, which
+// would leave them in shell history and the process list.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gatekeeper/auth"
+	"gatekeeper/config"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "create-admin", "list-users", "reset-password":
+	default:
+		usage()
+	}
+
+	// Load configuration the same way the server does
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	}
+	cfg := config.Load()
+	cfg.Validate()
+
+	// Apply the server's password deny list to passwords set from the CLI too
+	denyList, err := cfg.PasswordDenyList()
+	if err != nil {
+		log.Fatalf("❌ Failed to load password deny list: %v", err)
+	}
+	auth.SetPasswordDenyList(denyList)
+
+	firestoreDB, err := db.NewFirestoreDB(context.Background(), cfg.FirestoreOptions())
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize Firestore: %v", err)
+	}
+	defer firestoreDB.Close()
+
+	switch command {
+	case "create-admin":
+		err = createAdmin(firestoreDB, args)
+	case "list-users":
+		err = listUsers(firestoreDB)
+	case "reset-password":
+		err = resetPassword(firestoreDB, args)
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  gatekeeper-admin create-admin -username <name> [-org <org_id>]
+  gatekeeper-admin list-users
+  gatekeeper-admin reset-password -username <name>
+
+The password is read from GATEKEEPER_ADMIN_PASSWORD, or from standard input when unset.`)
+	os.Exit(2)
+}
+
+// passwordEnv names the environment variable the password is read from
+const passwordEnv = "GATEKEEPER_ADMIN_PASSWORD"
+
+// parseCredentials parses -username (plus any extra flags registered by the caller),
+// reads the password and checks it against the server's policy
+func parseCredentials(fs *flag.FlagSet, args []string) (string, string, error) {
+	username := fs.String("username", "", "username")
+	fs.Parse(args)
+
+	if *username == "" {
+		return "", "", fmt.Errorf("-username is required")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return "", "", err
+	}
+	if err := auth.ValidatePasswordStrength(password); err != nil {
+		return "", "", err
+	}
+	return *username, password, nil
+}
+
+// readPassword returns the password from passwordEnv, or else the first line of stdin
+func readPassword() (string, error) {
+	if password := os.Getenv(passwordEnv); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Password (or set %s): ", passwordEnv)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("a password is required on stdin or in %s", passwordEnv)
+	}
+	return password, nil
+}
+
+func createAdmin(firestoreDB *db.FirestoreDB, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	orgID := fs.String("org", "", "organization the admin belongs to (empty for single-tenant deployments)")
+	username, password, err := parseCredentials(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if _, err := firestoreDB.GetUserByUsername(username); err == nil {
+		return fmt.Errorf("user %s already exists; use reset-password instead", username)
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("failed to check for existing user: %w", err)
+	}
+
+	user := &models.User{
+		UserID:             fmt.Sprintf("user-%s", username),
+		Username:           username,
+		Role:               models.RoleAdmin,
+		AllowedCheckpoints: []string{},
+		LastLogin:          models.Now(),
+		OrgID:              *orgID,
+	}
+	if err := firestoreDB.CreateUser(user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := storePassword(firestoreDB, user.UserID, password); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Created admin %s (%s)", user.Username, user.UserID)
+	return nil
+}
+
+func listUsers(firestoreDB *db.FirestoreDB) error {
+	users, err := firestoreDB.GetAllUsers()
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER ID\tUSERNAME\tROLE\tORG\tLAST LOGIN")
+	for _, user := range users {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", user.UserID, user.Username, user.Role, user.OrgID, user.LastLogin.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func resetPassword(firestoreDB *db.FirestoreDB, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	username, password, err := parseCredentials(fs, args)
+	if err != nil {
+		return err
+	}
+
+	user, err := firestoreDB.GetUserByUsername(username)
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("user %s not found", username)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	if err := storePassword(firestoreDB, user.UserID, password); err != nil {
+		return err
+	}
+	if err := firestoreDB.RevokeUserRefreshSessions(user.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions of %s: %w", username, err)
+	}
+
+	log.Printf("🔑 Password reset for %s; existing sessions are revoked", user.Username)
+	return nil
+}
+
+func storePassword(firestoreDB *db.FirestoreDB, userID, password string) error {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := firestoreDB.StorePasswordHash(userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to store password: %w", err)
+	}
+	return nil
+}
@@ -1,12 +1,26 @@
 package config
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"gatekeeper/auth"
+	"gatekeeper/db"
+	"gatekeeper/exports"
+	"gatekeeper/httputil"
+	"gatekeeper/models"
 	"log"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// RefreshExpirationCeiling is the highest REFRESH_TOKEN_MAX_EXPIRATION accepted in
+// production, so raising the bound can't make sessions effectively permanent either
+const RefreshExpirationCeiling = 90 * 24 * time.Hour
+
 // Config holds all application configuration
 type Config struct {
 	Server   ServerConfig
@@ -15,68 +29,247 @@ type Config struct {
 	CORS     CORSConfig
 	RateLimit RateLimitConfig
 	Logging  LoggingConfig
+	Retention RetentionConfig
+	Captcha  CaptchaConfig
+	Pagination PaginationConfig
+	Sync     SyncConfig
+	Lockout  LockoutConfig
+	Cache    CacheConfig
+	Export   ExportConfig
+	Password PasswordConfig
+	Compression CompressionConfig
+	UserDefaults UserDefaultsConfig
+	Features FeaturesConfig
+
+	invalidEnv []string // Environment values that failed to parse and were replaced by defaults
 }
 
 type ServerConfig struct {
-	Port        string
-	Host        string
-	Environment string
+	Port             string
+	Host             string
+	Environment      string
+	TLSCertFile      string
+	TLSKeyFile       string
+	HTTPRedirectPort string // When set with TLS, a plain HTTP listener on this port redirects to HTTPS
+	TLSMinVersion    string   // Lowest TLS version accepted: 1.2 or 1.3
+	TLSCipherSuites  []string // TLS 1.2 cipher suites by Go name; empty uses modern AEAD suites
+	HSTSMaxAge       time.Duration
+	// TrustedProxies are the CIDR ranges or IPs of load balancers whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Trusting a range that clients can connect from lets
+	// them spoof their IP and evade rate limits and lockouts; leave empty when not behind a proxy.
+	TrustedProxies []string
 }
 
 type JWTConfig struct {
-	Secret                string
+	Secret                string `secret:"true"`
 	Expiration            time.Duration
 	RefreshTokenExpiration time.Duration
+	MaxRefreshExpiration  time.Duration // Upper bound on RefreshTokenExpiration, enforced in production; itself at most RefreshExpirationCeiling
+	TokenStore            string // Refresh-session backend: firestore or memory
+	Leeway                time.Duration // Clock skew tolerated when validating exp and nbf
+	RejectStaleRole       bool          // Refuse tokens whose role differs from the user's current role
+	Algorithms            []string      // Signing algorithms accepted when validating tokens
+	IdleTimeout           time.Duration // Inactivity after which users must log in again; 0 disables
+	RoleIdleTimeouts      map[models.UserRole]time.Duration // Per-role overrides of IdleTimeout
 }
 
 type FirebaseConfig struct {
 	ProjectID       string
 	CredentialsPath string
+	CredentialsJSON string `secret:"true"` // Raw service account JSON, for platforms that pass secrets as env vars
+	EmulatorHost    string // When set, connect to the Firestore emulator without credentials
 }
 
 type CORSConfig struct {
-	AllowedOrigins []string
+	AllowedOrigins     []string
+	OperationalOrigins []string // Origins allowed to read OperationalPaths; empty keeps them same-origin only
+	OperationalPaths   []string // Health and metrics endpoints exempt from the app allowlist; a trailing / matches a subtree
 }
 
 type RateLimitConfig struct {
-	Requests int
-	Window   time.Duration
+	Requests      int
+	Window        time.Duration
+	ExemptPaths   []string // Paths that bypass rate limiting; a trailing / exempts a subtree
+	AdminRequests int      // Per admin and window, instead of per IP; 0 limits admins by IP too
+}
+
+type RetentionConfig struct {
+	EntryRetentionDays int           // 0 disables the entry purge job
+	EntryArchive       bool          // Move expired entries to entries_archive instead of deleting them
+	PurgeInterval      time.Duration // How often the purge job runs
+	PurgeBatchSize     int           // Documents processed per batched write
+	AuditRetentionDays int           // 0 disables the audit log archival job
+	AuditDelete        bool          // Delete exported audit logs instead of moving them to audit_logs_archive
+	AuditArchiveBucket string        // Cold-storage bucket audit logs are exported to before leaving audit_logs
+}
+
+type CaptchaConfig struct {
+	Enabled   bool
+	Provider  string // hcaptcha or turnstile
+	Secret    string `secret:"true"`
+	Threshold int // Failed logins per username or IP before a CAPTCHA is required
+}
+
+type LockoutConfig struct {
+	// Failed logins per username before the account is locked; 0, the default, disables
+	// lockout, since anyone who knows a username could otherwise lock its owner out
+	Threshold int
+}
+
+type ExportConfig struct {
+	Bucket string        // Cloud Storage bucket for uploaded exports; empty disables storage export
+	URLTTL time.Duration // Lifetime of signed download URLs
+}
+
+type PasswordConfig struct {
+	DenyListFile      string // Banned passwords, one per line; empty disables the check
+	BreachedRangesDir string // Local k-anonymity breach dataset (one file per SHA-1 prefix); empty disables the check
+}
+
+type CacheConfig struct {
+	CheckpointTTL time.Duration // How long checkpoint reads are cached; 0 disables the cache
+	UserTTL       time.Duration // How long users loaded for authentication are cached; 0 disables the cache
+}
+
+type PaginationConfig struct {
+	DefaultPageSize int // Applied when a list request has no limit
+	MaxPageSize     int // Larger requested limits are clamped to this
+}
+
+type SyncConfig struct {
+	PushConcurrency int // Entries of a single push processed in parallel
+	MaxPayloadBytes int           // Largest JSON-encoded entry payload accepted; 0 disables the check
+	MaxClockAhead   time.Duration // How far client_ts may be ahead of server time; 0 disables the check
+}
+
+type CompressionConfig struct {
+	MinSize int // Responses smaller than this many bytes are sent uncompressed; below ~1 KB gzip costs more than it saves
+	Level   int // compress/gzip level: -2 (Huffman only), -1 (default) or 1-9
+}
+
+// UserDefaultsConfig fills in fields a create-user request omits. A value given in the
+// request always wins, including an explicitly empty allowed_checkpoints list.
+type UserDefaultsConfig struct {
+	Role               models.UserRole // Role of users created without one; empty makes role required
+	AllowedCheckpoints []string        // Checkpoints of non-admin users created without allowed_checkpoints
+}
+
+// FeaturesConfig switches optional endpoints on and off per deployment. Flags not listed
+// are off, so new endpoints ship dark until a site enables them. Known flags:
+// sync_resolve (POST /api/sync/resolve).
+type FeaturesConfig struct {
+	Flags map[string]bool // From FEATURE_FLAGS, e.g. "search=true,sse=false"
 }
 
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level         string
+	Format        string
+	DebugRequests bool // Log a redacted line per request; meant for debugging, off by default
 }
 
 // Load reads configuration from environment variables
 func Load() *Config {
-	return &Config{
+	env := &envReader{}
+	cfg := &Config{
 		Server: ServerConfig{
-			Port:        getEnv("PORT", "8080"),
-			Host:        getEnv("HOST", "0.0.0.0"),
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Port:             getEnv("PORT", "8080"),
+			Host:             getEnv("HOST", "0.0.0.0"),
+			Environment:      getEnv("ENVIRONMENT", "development"),
+			TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+			HTTPRedirectPort: getEnv("HTTP_REDIRECT_PORT", ""),
+			TLSMinVersion:    getEnv("TLS_MIN_VERSION", "1.2"),
+			TLSCipherSuites:  parseStringSlice(getEnv("TLS_CIPHER_SUITES", "")),
+			HSTSMaxAge:       env.getDuration("HSTS_MAX_AGE", 365*24*time.Hour),
+			TrustedProxies:   parseStringSlice(getEnv("TRUSTED_PROXIES", "")),
 		},
 		JWT: JWTConfig{
 			Secret:                getEnv("JWT_SECRET", "dev-secret-key"),
-			Expiration:            parseDuration(getEnv("JWT_EXPIRATION", "30m"), 30*time.Minute),
-			RefreshTokenExpiration: parseDuration(getEnv("REFRESH_TOKEN_EXPIRATION", "7d"), 7*24*time.Hour),
+			Expiration:            env.getDuration("JWT_EXPIRATION", 30*time.Minute),
+			RefreshTokenExpiration: env.getDuration("REFRESH_TOKEN_EXPIRATION", 7*24*time.Hour),
+			MaxRefreshExpiration:  env.getDuration("REFRESH_TOKEN_MAX_EXPIRATION", RefreshExpirationCeiling),
+			TokenStore:            getEnv("TOKEN_STORE", "firestore"),
+			Leeway:                env.getDuration("JWT_LEEWAY", 30*time.Second),
+			RejectStaleRole:       env.getBool("JWT_REJECT_STALE_ROLE", false),
+			Algorithms:            parseStringSlice(getEnv("JWT_ALLOWED_ALGORITHMS", "HS256")),
+			IdleTimeout:           env.getDuration("SESSION_IDLE_TIMEOUT", 0),
+			RoleIdleTimeouts:      env.getRoleDurations("SESSION_IDLE_TIMEOUT_BY_ROLE"),
 		},
 		Firebase: FirebaseConfig{
 			ProjectID:       getEnv("FIREBASE_PROJECT_ID", "gatekeeper-e1209"),
 			CredentialsPath: getEnv("FIREBASE_CREDENTIALS_PATH", "./serviceAccountKey.json"),
+			CredentialsJSON: getEnv("FIREBASE_CREDENTIALS_JSON", ""),
+			EmulatorHost:    getEnv("FIRESTORE_EMULATOR_HOST", ""),
 		},
 		CORS: CORSConfig{
-			AllowedOrigins: parseStringSlice(getEnv("ALLOWED_ORIGINS", "http://localhost:5173")),
+			AllowedOrigins:     parseStringSlice(getEnv("ALLOWED_ORIGINS", "http://localhost:5173")),
+			OperationalOrigins: parseStringSlice(getEnv("CORS_OPERATIONAL_ORIGINS", "")),
+			OperationalPaths:   parseStringSlice(getEnv("CORS_OPERATIONAL_PATHS", "/health,/readyz,/metrics")),
 		},
 		RateLimit: RateLimitConfig{
-			Requests: parseInt(getEnv("RATE_LIMIT_REQUESTS", "100"), 100),
-			Window:   parseDuration(getEnv("RATE_LIMIT_WINDOW", "60"), 60*time.Second),
+			Requests:      env.getInt("RATE_LIMIT_REQUESTS", 100),
+			Window:        env.getDuration("RATE_LIMIT_WINDOW", 60*time.Second),
+			ExemptPaths:   parseStringSlice(getEnv("RATE_LIMIT_EXEMPT_PATHS", "/health,/readyz,/metrics")),
+			AdminRequests: env.getInt("RATE_LIMIT_ADMIN_REQUESTS", 0),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:         getEnv("LOG_LEVEL", "info"),
+			Format:        getEnv("LOG_FORMAT", "json"),
+			DebugRequests: env.getBool("LOG_REQUESTS", false),
+		},
+		Retention: RetentionConfig{
+			EntryRetentionDays: env.getInt("ENTRY_RETENTION_DAYS", 0),
+			EntryArchive:       getEnv("ENTRY_RETENTION_MODE", "delete") == "archive",
+			PurgeInterval:      env.getDuration("RETENTION_PURGE_INTERVAL", 24*time.Hour),
+			PurgeBatchSize:     env.getInt("RETENTION_PURGE_BATCH_SIZE", 200),
+			AuditRetentionDays: env.getInt("AUDIT_RETENTION_DAYS", 0),
+			AuditDelete:        getEnv("AUDIT_RETENTION_MODE", "archive") == "delete",
+			AuditArchiveBucket: getEnv("AUDIT_ARCHIVE_BUCKET", ""),
+		},
+		Captcha: CaptchaConfig{
+			Enabled:   env.getBool("CAPTCHA_ENABLED", false),
+			Provider:  getEnv("CAPTCHA_PROVIDER", "turnstile"),
+			Secret:    getEnv("CAPTCHA_SECRET", ""),
+			Threshold: env.getInt("CAPTCHA_THRESHOLD", 3),
+		},
+		Lockout: LockoutConfig{
+			Threshold: env.getInt("LOGIN_LOCKOUT_THRESHOLD", 0),
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: env.getInt("DEFAULT_PAGE_SIZE", 100),
+			MaxPageSize:     env.getInt("MAX_PAGE_SIZE", 1000),
+		},
+		Sync: SyncConfig{
+			PushConcurrency: env.getInt("SYNC_PUSH_CONCURRENCY", 8),
+			MaxPayloadBytes: env.getInt("MAX_ENTRY_PAYLOAD_BYTES", 262144),
+			MaxClockAhead:   env.getDuration("MAX_CLIENT_CLOCK_AHEAD", 5*time.Minute),
+		},
+		Export: ExportConfig{
+			Bucket: getEnv("EXPORT_BUCKET", ""),
+			URLTTL: env.getDuration("EXPORT_URL_TTL", 15*time.Minute),
+		},
+		Password: PasswordConfig{
+			DenyListFile:      getEnv("PASSWORD_DENY_LIST_FILE", ""),
+			BreachedRangesDir: getEnv("PASSWORD_BREACHED_RANGES_DIR", ""),
+		},
+		Cache: CacheConfig{
+			CheckpointTTL: env.getDuration("CHECKPOINT_CACHE_TTL", 60*time.Second),
+			UserTTL:       env.getDuration("USER_CACHE_TTL", 5*time.Second),
+		},
+		Compression: CompressionConfig{
+			MinSize: env.getInt("COMPRESSION_MIN_SIZE", 1024),
+			Level:   env.getInt("COMPRESSION_LEVEL", gzip.DefaultCompression),
+		},
+		UserDefaults: UserDefaultsConfig{
+			Role:               models.UserRole(getEnv("DEFAULT_USER_ROLE", "")),
+			AllowedCheckpoints: parseStringSlice(getEnv("DEFAULT_USER_CHECKPOINTS", "")),
+		},
+		Features: FeaturesConfig{
+			Flags: env.getFlags("FEATURE_FLAGS"),
 		},
 	}
+	cfg.invalidEnv = env.invalid
+	return cfg
 }
 
 func getEnv(key, defaultValue string) string {
@@ -86,23 +279,108 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func parseInt(s string, defaultValue int) int {
-	if i, err := strconv.Atoi(s); err == nil {
-		return i
+// ParsedTrustedProxies returns TRUSTED_PROXIES as address ranges
+func (c *Config) ParsedTrustedProxies() ([]netip.Prefix, error) {
+	proxies, err := httputil.ParseTrustedProxies(c.Server.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
 	}
-	return defaultValue
+	return proxies, nil
+}
+
+// envReader reads typed environment variables. Values that fail to parse fall back to
+// the default and are remembered so SelfCheck can fail loudly instead.
+type envReader struct {
+	invalid []string
+}
+
+func (e *envReader) getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not an integer", key, value))
+		return defaultValue
+	}
+	return i
+}
+
+func (e *envReader) getBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a boolean", key, value))
+		return defaultValue
+	}
+	return b
+}
+
+func (e *envReader) getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, ok := parseDuration(value)
+	if !ok {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s=%q is not a duration (use e.g. 30s, 15m, 24h or 7d)", key, value))
+		return defaultValue
+	}
+	return d
 }
 
-func parseDuration(s string, defaultValue time.Duration) time.Duration {
-	// Handle simple formats like "30m", "7d", "60"
+// getRoleDurations reads a comma-separated list of ROLE=duration pairs, e.g.
+// "ADMIN=15m,GATE_OPERATOR=12h"
+func (e *envReader) getRoleDurations(key string) map[models.UserRole]time.Duration {
+	durations := make(map[models.UserRole]time.Duration)
+	for _, pair := range parseStringSlice(os.Getenv(key)) {
+		role, value, _ := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		d, ok := parseDuration(strings.TrimSpace(value))
+		if !models.UserRole(role).IsValid() || !ok {
+			e.invalid = append(e.invalid, fmt.Sprintf("%s entry %q is not of the form ROLE=duration", key, pair))
+			continue
+		}
+		durations[models.UserRole(role)] = d
+	}
+	return durations
+}
+
+// getFlags reads a comma-separated list of name=bool pairs, e.g. "search=true,sse=false".
+// Names are case-insensitive and stored in lower case.
+func (e *envReader) getFlags(key string) map[string]bool {
+	flags := make(map[string]bool)
+	for _, pair := range parseStringSlice(os.Getenv(key)) {
+		name, value, _ := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if name == "" || err != nil {
+			e.invalid = append(e.invalid, fmt.Sprintf("%s entry %q is not of the form name=true or name=false", key, pair))
+			continue
+		}
+		flags[name] = enabled
+	}
+	return flags
+}
+
+// parseDuration accepts Go durations ("30m"), whole days ("7d") and bare seconds ("60")
+func parseDuration(s string) (time.Duration, bool) {
 	if d, err := time.ParseDuration(s); err == nil {
-		return d
+		return d, true
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Duration(n) * 24 * time.Hour, true
+		}
 	}
-	// If it's just a number, assume seconds
 	if i, err := strconv.Atoi(s); err == nil {
-		return time.Duration(i) * time.Second
+		return time.Duration(i) * time.Second, true
 	}
-	return defaultValue
+	return 0, false
 }
 
 func parseStringSlice(s string) []string {
@@ -123,6 +401,15 @@ func parseStringSlice(s string) []string {
 	return result
 }
 
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) IsProduction() bool {
 	return c.Server.Environment == "production"
 }
@@ -131,14 +418,197 @@ func (c *Config) IsDevelopment() bool {
 	return c.Server.Environment == "development"
 }
 
+// FirestoreOptions returns the connection options for db.NewFirestoreDB
+func (c *Config) FirestoreOptions() db.ConnectOptions {
+	return db.ConnectOptions{
+		ProjectID:       c.Firebase.ProjectID,
+		CredentialsPath: c.Firebase.CredentialsPath,
+		CredentialsJSON: c.Firebase.CredentialsJSON,
+		EmulatorHost:    c.Firebase.EmulatorHost,
+	}
+}
+
+// ExportOptions returns the Cloud Storage options for exports.NewUploader
+func (c *Config) ExportOptions() exports.Options {
+	return exports.Options{
+		Bucket:          c.Export.Bucket,
+		CredentialsPath: c.Firebase.CredentialsPath,
+		CredentialsJSON: c.Firebase.CredentialsJSON,
+		URLTTL:          c.Export.URLTTL,
+	}
+}
+
+// AuditArchiveOptions returns the Cloud Storage options for the audit log archive
+func (c *Config) AuditArchiveOptions() exports.Options {
+	return exports.Options{
+		Bucket:          c.Retention.AuditArchiveBucket,
+		CredentialsPath: c.Firebase.CredentialsPath,
+		CredentialsJSON: c.Firebase.CredentialsJSON,
+		WriteOnly:       true,
+	}
+}
+
+// PasswordDenyList loads the configured password deny list, or returns nil when
+// neither a list file nor a breach dataset is configured
+func (c *Config) PasswordDenyList() (*auth.PasswordDenyList, error) {
+	if c.Password.DenyListFile == "" && c.Password.BreachedRangesDir == "" {
+		return nil, nil
+	}
+	return auth.LoadPasswordDenyList(c.Password.DenyListFile, c.Password.BreachedRangesDir)
+}
+
+// IdlePolicy returns the configured idle-session expiry
+func (c *Config) IdlePolicy() auth.IdlePolicy {
+	return auth.IdlePolicy{Timeout: c.JWT.IdleTimeout, RoleTimeout: c.JWT.RoleIdleTimeouts}
+}
+
+// TLSEnabled reports whether the server should serve HTTPS itself
+func (c *Config) TLSEnabled() bool {
+	return c.Server.TLSCertFile != "" || c.Server.TLSKeyFile != ""
+}
+
+// Validate logs warnings about the configuration and exits if it has problems
 func (c *Config) Validate() {
+	problems, warnings := c.Check()
+	for _, warning := range warnings {
+		log.Println("⚠️  " + warning)
+	}
+	if len(problems) > 0 {
+		log.Fatal(strings.Join(problems, "; "))
+	}
+}
+
+// Check returns the problems that keep the configuration from being used and warnings
+// about settings that work but are probably unintended
+func (c *Config) Check() (problems, warnings []string) {
 	if c.JWT.Secret == "dev-secret-key" && c.IsProduction() {
-		log.Fatal("JWT_SECRET must be set in production")
+		problems = append(problems, "JWT_SECRET must be set in production")
+	}
+	if c.JWT.TokenStore != "firestore" && c.JWT.TokenStore != "memory" {
+		problems = append(problems, fmt.Sprintf("Unsupported TOKEN_STORE: %s (use firestore or memory)", c.JWT.TokenStore))
+	}
+	if c.JWT.TokenStore == "memory" && c.IsProduction() {
+		problems = append(problems, "TOKEN_STORE=memory loses sessions on restart and is not shared between instances; use firestore in production")
+	}
+	if !containsString(c.JWT.Algorithms, "HS256") {
+		problems = append(problems, "JWT_ALLOWED_ALGORITHMS must include HS256, which is used to sign tokens")
+	}
+	for _, alg := range c.JWT.Algorithms {
+		if !containsString(auth.SupportedAlgorithms, alg) {
+			problems = append(problems, fmt.Sprintf("Unsupported algorithm in JWT_ALLOWED_ALGORITHMS: %s (use HS256, HS384 or HS512)", alg))
+		}
+	}
+	// Refresh tokens are what keep a session alive, so a very long lifetime makes sessions
+	// effectively permanent
+	if c.JWT.RefreshTokenExpiration > c.JWT.MaxRefreshExpiration {
+		message := fmt.Sprintf("REFRESH_TOKEN_EXPIRATION (%v) exceeds REFRESH_TOKEN_MAX_EXPIRATION (%v)", c.JWT.RefreshTokenExpiration, c.JWT.MaxRefreshExpiration)
+		if c.IsProduction() {
+			problems = append(problems, message)
+		} else {
+			warnings = append(warnings, message)
+		}
+	}
+	if c.JWT.MaxRefreshExpiration > RefreshExpirationCeiling {
+		message := fmt.Sprintf("REFRESH_TOKEN_MAX_EXPIRATION (%v) exceeds the ceiling of %v", c.JWT.MaxRefreshExpiration, RefreshExpirationCeiling)
+		if c.IsProduction() {
+			problems = append(problems, message)
+		} else {
+			warnings = append(warnings, message)
+		}
+	}
+	if c.JWT.Expiration > c.JWT.RefreshTokenExpiration {
+		warnings = append(warnings, fmt.Sprintf("JWT_EXPIRATION (%v) is longer than REFRESH_TOKEN_EXPIRATION (%v), so access tokens outlive the sessions they belong to", c.JWT.Expiration, c.JWT.RefreshTokenExpiration))
+	}
+	if c.JWT.Leeway < 0 || c.JWT.Leeway > 5*time.Minute {
+		problems = append(problems, "JWT_LEEWAY must be between 0 and 5m")
+	}
+	// Activity is only recorded once per interval, so shorter timeouts would expire active users
+	idleTimeouts := []time.Duration{c.JWT.IdleTimeout}
+	for _, timeout := range c.JWT.RoleIdleTimeouts {
+		idleTimeouts = append(idleTimeouts, timeout)
+	}
+	for _, timeout := range idleTimeouts {
+		if timeout < 0 || (timeout > 0 && timeout < 5*auth.ActivityWriteInterval) {
+			problems = append(problems, "SESSION_IDLE_TIMEOUT and SESSION_IDLE_TIMEOUT_BY_ROLE must be 0 or at least 5m")
+			break
+		}
+	}
+	if c.Logging.DebugRequests && c.IsProduction() {
+		warnings = append(warnings, "LOG_REQUESTS is enabled in production; disable it once debugging is done")
+	}
+	if c.TLSEnabled() {
+		// Never fall back to plain HTTP when TLS was requested
+		if c.Server.TLSCertFile == "" || c.Server.TLSKeyFile == "" {
+			problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must both be set to enable TLS")
+		}
+		if _, err := os.Stat(c.Server.TLSCertFile); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("TLS certificate file not found: %s", c.Server.TLSCertFile))
+		}
+		if _, err := os.Stat(c.Server.TLSKeyFile); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("TLS key file not found: %s", c.Server.TLSKeyFile))
+		}
+		if _, err := c.TLSConfig(); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if c.Server.TLSMinVersion == "1.3" && len(c.Server.TLSCipherSuites) > 0 {
+			warnings = append(warnings, "TLS_CIPHER_SUITES has no effect with TLS_MIN_VERSION=1.3; TLS 1.3 suites are not configurable")
+		}
+	}
+	if role := c.UserDefaults.Role; role != "" && (!role.IsValid() || role == models.RoleSuperAdmin) {
+		problems = append(problems, fmt.Sprintf("Unsupported DEFAULT_USER_ROLE: %s (use ADMIN, SUPERVISOR or GATE_OPERATOR)", role))
+	}
+	if _, err := c.ParsedTrustedProxies(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.Captcha.Enabled {
+		if c.Captcha.Secret == "" {
+			problems = append(problems, "CAPTCHA_SECRET must be set when CAPTCHA_ENABLED is true")
+		}
+		if c.Captcha.Provider != "hcaptcha" && c.Captcha.Provider != "turnstile" {
+			problems = append(problems, fmt.Sprintf("Unsupported CAPTCHA_PROVIDER: %s (use hcaptcha or turnstile)", c.Captcha.Provider))
+		}
+	}
+	// V4 signed URLs cannot outlive seven days
+	if c.Export.URLTTL <= 0 || c.Export.URLTTL > 7*24*time.Hour {
+		problems = append(problems, "EXPORT_URL_TTL must be positive and at most 7 days")
+	}
+	// Leave headroom below Firestore's 1 MiB document limit for the entry's other fields
+	if c.Sync.MaxPayloadBytes < 0 || c.Sync.MaxPayloadBytes > 900*1024 {
+		problems = append(problems, "MAX_ENTRY_PAYLOAD_BYTES must be between 0 and 921600")
+	}
+	// Audit logs never leave Firestore without a copy in cold storage
+	if c.Retention.AuditRetentionDays > 0 && c.Retention.AuditArchiveBucket == "" {
+		problems = append(problems, "AUDIT_ARCHIVE_BUCKET must be set when AUDIT_RETENTION_DAYS is set")
+	}
+	if c.Compression.MinSize < 0 {
+		problems = append(problems, "COMPRESSION_MIN_SIZE must not be negative")
+	}
+	if c.Compression.Level != gzip.HuffmanOnly && c.Compression.Level != gzip.DefaultCompression &&
+		(c.Compression.Level < gzip.BestSpeed || c.Compression.Level > gzip.BestCompression) {
+		problems = append(problems, "COMPRESSION_LEVEL must be -2, -1 or between 1 and 9")
+	}
+	if c.Sync.MaxClockAhead < 0 {
+		problems = append(problems, "MAX_CLIENT_CLOCK_AHEAD must not be negative")
+	}
+	if c.Pagination.DefaultPageSize < 1 || c.Pagination.MaxPageSize < c.Pagination.DefaultPageSize {
+		problems = append(problems, "DEFAULT_PAGE_SIZE must be positive and no larger than MAX_PAGE_SIZE")
 	}
 	if c.Firebase.ProjectID == "" {
-		log.Fatal("FIREBASE_PROJECT_ID must be set")
+		problems = append(problems, "FIREBASE_PROJECT_ID must be set")
 	}
-	if _, err := os.Stat(c.Firebase.CredentialsPath); os.IsNotExist(err) {
-		log.Fatalf("Firebase credentials file not found: %s", c.Firebase.CredentialsPath)
+	switch {
+	case c.Firebase.EmulatorHost != "":
+		if c.IsProduction() {
+			problems = append(problems, "FIRESTORE_EMULATOR_HOST must not be set in production")
+		}
+	case c.Firebase.CredentialsJSON != "":
+		if !json.Valid([]byte(c.Firebase.CredentialsJSON)) {
+			problems = append(problems, "FIREBASE_CREDENTIALS_JSON is not valid JSON")
+		}
+	default:
+		if _, err := os.Stat(c.Firebase.CredentialsPath); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("Firebase credentials file not found: %s", c.Firebase.CredentialsPath))
+		}
 	}
+	return problems, warnings
 }
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func hasMessage(messages []string, substr string) bool {
+	for _, message := range messages {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMaxRefreshExpirationCeiling(t *testing.T) {
+	t.Setenv("REFRESH_TOKEN_MAX_EXPIRATION", (RefreshExpirationCeiling + 24*time.Hour).String())
+
+	cfg := Load()
+	cfg.Server.Environment = "production"
+	problems, _ := cfg.Check()
+	if !hasMessage(problems, "REFRESH_TOKEN_MAX_EXPIRATION") {
+		t.Errorf("production problems = %v, want one about REFRESH_TOKEN_MAX_EXPIRATION", problems)
+	}
+
+	cfg.Server.Environment = "development"
+	problems, warnings := cfg.Check()
+	if hasMessage(problems, "REFRESH_TOKEN_MAX_EXPIRATION") {
+		t.Errorf("development problems = %v, want the ceiling only as a warning", problems)
+	}
+	if !hasMessage(warnings, "REFRESH_TOKEN_MAX_EXPIRATION") {
+		t.Errorf("development warnings = %v, want one about REFRESH_TOKEN_MAX_EXPIRATION", warnings)
+	}
+}
+
+func TestDefaultMaxRefreshExpirationWithinCeiling(t *testing.T) {
+	cfg := Load()
+	cfg.Server.Environment = "production"
+	problems, _ := cfg.Check()
+	if hasMessage(problems, "REFRESH_TOKEN_MAX_EXPIRATION") {
+		t.Errorf("default configuration has problems %v", problems)
+	}
+}
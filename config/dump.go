@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// Dump is the fully resolved configuration, defaults included, with the result of
+// validating it. Unlike Summary it covers every field, so fields holding secrets are
+// tagged secret:"true" and only reported as set or not.
+type Dump struct {
+	Config     map[string]interface{} `json:"config"`
+	InvalidEnv []string               `json:"invalid_env"` // Values that failed to parse and were replaced by defaults
+	Problems   []string               `json:"problems"`    // Would stop the server from starting
+	Warnings   []string               `json:"warnings"`
+}
+
+// Dump returns the resolved configuration for -print-config
+func (c *Config) Dump() Dump {
+	problems, warnings := c.Check()
+	return Dump{
+		Config:     dumpStruct(reflect.ValueOf(*c)),
+		InvalidEnv: nonNil(c.invalidEnv),
+		Problems:   nonNil(problems),
+		Warnings:   nonNil(warnings),
+	}
+}
+
+// dumpStruct converts the exported fields of a config struct to a map, writing durations
+// as e.g. "30m0s" rather than nanoseconds and redacting secrets
+func dumpStruct(v reflect.Value) map[string]interface{} {
+	fields := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true":
+			if value.IsZero() {
+				fields[field.Name] = ""
+			} else {
+				fields[field.Name] = "[REDACTED]"
+			}
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			fields[field.Name] = time.Duration(value.Int()).String()
+		case field.Type.Kind() == reflect.Struct:
+			fields[field.Name] = dumpStruct(value)
+		case field.Type.Kind() == reflect.Map && field.Type.Elem() == reflect.TypeOf(time.Duration(0)):
+			durations := make(map[string]string, value.Len())
+			for _, key := range value.MapKeys() {
+				durations[key.String()] = time.Duration(value.MapIndex(key).Int()).String()
+			}
+			fields[field.Name] = durations
+		default:
+			fields[field.Name] = value.Interface()
+		}
+	}
+	return fields
+}
+
+// nonNil returns values, or an empty slice so JSON shows [] rather than null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
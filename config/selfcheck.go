@@ -0,0 +1,125 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"gatekeeper/health"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SelfCheck checks that the configuration is coherent beyond what Validate enforces:
+// every environment value parsed, CORS origins are well formed, durations are sane and
+// credentials can be loaded. Checks that need live services are added by the caller.
+func (c *Config) SelfCheck() *health.Report {
+	report := health.NewReport()
+
+	if len(c.invalidEnv) > 0 {
+		report.Fail("environment", strings.Join(c.invalidEnv, "; "))
+	} else {
+		report.Pass("environment", "all values parsed")
+	}
+
+	c.checkOrigins(report, "cors.allowed_origins", c.CORS.AllowedOrigins, true)
+	c.checkOrigins(report, "cors.operational_origins", c.CORS.OperationalOrigins, false)
+	c.checkDurations(report)
+	c.checkCredentials(report)
+
+	for _, key := range []string{"ENTRY_RETENTION_MODE", "AUDIT_RETENTION_MODE"} {
+		switch mode := os.Getenv(key); mode {
+		case "", "delete", "archive":
+		default:
+			report.Fail("retention.mode", fmt.Sprintf("%s=%q is neither delete nor archive", key, mode))
+		}
+	}
+
+	return report
+}
+
+// checkOrigins requires each origin to be a bare scheme://host[:port], the only form a
+// browser ever sends, so a typo can't silently disable CORS for a frontend. Credentialed
+// origins may not be the * wildcard.
+func (c *Config) checkOrigins(report *health.Report, name string, origins []string, credentialed bool) {
+	var problems []string
+	for _, origin := range origins {
+		if origin == "*" {
+			if credentialed {
+				problems = append(problems, "wildcard origin is not allowed with credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			problems = append(problems, fmt.Sprintf("%q is not of the form https://host[:port]", origin))
+			continue
+		}
+		if u.Path == "/" {
+			problems = append(problems, fmt.Sprintf("%q must not end in a slash", origin))
+		}
+	}
+
+	if len(problems) > 0 {
+		report.Fail(name, strings.Join(problems, "; "))
+		return
+	}
+	report.Pass(name, fmt.Sprintf("%d origins", len(origins)))
+}
+
+func (c *Config) checkDurations(report *health.Report) {
+	var problems []string
+	if c.JWT.Expiration <= 0 {
+		problems = append(problems, "JWT_EXPIRATION must be positive")
+	}
+	if c.JWT.RefreshTokenExpiration <= 0 {
+		problems = append(problems, "REFRESH_TOKEN_EXPIRATION must be positive")
+	}
+	if c.RateLimit.Requests <= 0 || c.RateLimit.Window <= 0 {
+		problems = append(problems, "RATE_LIMIT_REQUESTS and RATE_LIMIT_WINDOW must be positive")
+	}
+	if c.RateLimit.AdminRequests < 0 {
+		problems = append(problems, "RATE_LIMIT_ADMIN_REQUESTS must not be negative")
+	}
+	if (c.Retention.EntryRetentionDays > 0 || c.Retention.AuditRetentionDays > 0) && c.Retention.PurgeInterval <= 0 {
+		problems = append(problems, "RETENTION_PURGE_INTERVAL must be positive when retention is enabled")
+	}
+	if c.Cache.CheckpointTTL < 0 {
+		problems = append(problems, "CHECKPOINT_CACHE_TTL must not be negative")
+	}
+	if c.Cache.UserTTL < 0 {
+		problems = append(problems, "USER_CACHE_TTL must not be negative")
+	}
+	if len(problems) > 0 {
+		report.Fail("durations", strings.Join(problems, "; "))
+		return
+	}
+
+	if c.JWT.RefreshTokenExpiration <= c.JWT.Expiration {
+		report.Warn("durations", "REFRESH_TOKEN_EXPIRATION is not longer than JWT_EXPIRATION, so refreshing never extends a session")
+		return
+	}
+	report.Pass("durations", fmt.Sprintf("access %v, refresh %v", c.JWT.Expiration, c.JWT.RefreshTokenExpiration))
+}
+
+func (c *Config) checkCredentials(report *health.Report) {
+	switch {
+	case c.Firebase.EmulatorHost != "":
+		report.Pass("credentials.firebase", "emulator at "+c.Firebase.EmulatorHost)
+	case c.Firebase.CredentialsJSON != "":
+		report.Pass("credentials.firebase", "FIREBASE_CREDENTIALS_JSON")
+	default:
+		if _, err := os.ReadFile(c.Firebase.CredentialsPath); err != nil {
+			report.Fail("credentials.firebase", fmt.Sprintf("cannot read %s: %v", c.Firebase.CredentialsPath, err))
+		} else {
+			report.Pass("credentials.firebase", c.Firebase.CredentialsPath)
+		}
+	}
+
+	if c.TLSEnabled() {
+		if _, err := tls.LoadX509KeyPair(c.Server.TLSCertFile, c.Server.TLSKeyFile); err != nil {
+			report.Fail("credentials.tls", fmt.Sprintf("cannot load certificate: %v", err))
+		} else {
+			report.Pass("credentials.tls", c.Server.TLSCertFile)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+// Summary is the non-secret part of the configuration, for support to confirm how an
+// instance is set up. Fields are copied one by one rather than redacted from Config, so
+// a secret added to Config later can't appear here by accident.
+type Summary struct {
+	Environment       string           `json:"environment"`
+	TLSEnabled        bool             `json:"tls_enabled"`
+	TLSMinVersion     string           `json:"tls_min_version,omitempty"`
+	FirebaseProjectID string           `json:"firebase_project_id"`
+	CredentialsSource string           `json:"credentials_source"` // emulator, FIREBASE_CREDENTIALS_JSON or file
+	Tokens            TokenSummary     `json:"tokens"`
+	RateLimit         RateLimitSummary `json:"rate_limit"`
+	CORS              CORSSummary      `json:"cors"`
+	TrustedProxies    []string         `json:"trusted_proxies"`
+	LockoutThreshold  int              `json:"lockout_threshold"`          // 0 when lockout is disabled
+	CaptchaProvider   string           `json:"captcha_provider,omitempty"` // Empty when CAPTCHA is disabled
+	EntryRetention    int              `json:"entry_retention_days"`
+	AuditRetention    int              `json:"audit_retention_days"`
+	StorageExport     bool             `json:"storage_export"`
+	Features          map[string]bool  `json:"features"`
+}
+
+// TokenSummary describes token lifetimes and validation, without the signing secret
+type TokenSummary struct {
+	AccessLifetime  string   `json:"access_lifetime"`
+	RefreshLifetime string   `json:"refresh_lifetime"`
+	IdleTimeout     string   `json:"idle_timeout"` // 0s when idle expiry is disabled
+	Leeway          string   `json:"leeway"`
+	Store           string   `json:"store"`
+	Algorithms      []string `json:"algorithms"`
+}
+
+// RateLimitSummary describes the per-IP rate limit and the per-admin one, if any
+type RateLimitSummary struct {
+	Requests      int      `json:"requests"`
+	Window        string   `json:"window"`
+	ExemptPaths   []string `json:"exempt_paths"`
+	AdminRequests int      `json:"admin_requests,omitempty"`
+}
+
+// CORSSummary lists the origins allowed to call the API
+type CORSSummary struct {
+	AllowedOrigins     []string `json:"allowed_origins"`
+	OperationalOrigins []string `json:"operational_origins"`
+	OperationalPaths   []string `json:"operational_paths"`
+}
+
+// Summary returns the non-secret configuration
+func (c *Config) Summary() Summary {
+	summary := Summary{
+		Environment:       c.Server.Environment,
+		TLSEnabled:        c.TLSEnabled(),
+		FirebaseProjectID: c.Firebase.ProjectID,
+		CredentialsSource: "file",
+		Tokens: TokenSummary{
+			AccessLifetime:  c.JWT.Expiration.String(),
+			RefreshLifetime: c.JWT.RefreshTokenExpiration.String(),
+			IdleTimeout:     c.JWT.IdleTimeout.String(),
+			Leeway:          c.JWT.Leeway.String(),
+			Store:           c.JWT.TokenStore,
+			Algorithms:      c.JWT.Algorithms,
+		},
+		RateLimit: RateLimitSummary{
+			Requests:      c.RateLimit.Requests,
+			Window:        c.RateLimit.Window.String(),
+			ExemptPaths:   c.RateLimit.ExemptPaths,
+			AdminRequests: c.RateLimit.AdminRequests,
+		},
+		CORS: CORSSummary{
+			AllowedOrigins:     c.CORS.AllowedOrigins,
+			OperationalOrigins: c.CORS.OperationalOrigins,
+			OperationalPaths:   c.CORS.OperationalPaths,
+		},
+		TrustedProxies:   c.Server.TrustedProxies,
+		LockoutThreshold: c.Lockout.Threshold,
+		EntryRetention:   c.Retention.EntryRetentionDays,
+		AuditRetention:   c.Retention.AuditRetentionDays,
+		StorageExport:    c.Export.Bucket != "",
+		Features:         c.Features.Flags,
+	}
+	if summary.TLSEnabled {
+		summary.TLSMinVersion = c.Server.TLSMinVersion
+	}
+	switch {
+	case c.Firebase.EmulatorHost != "":
+		summary.CredentialsSource = "emulator"
+	case c.Firebase.CredentialsJSON != "":
+		summary.CredentialsSource = "FIREBASE_CREDENTIALS_JSON"
+	}
+	if c.Captcha.Enabled {
+		summary.CaptchaProvider = c.Captcha.Provider
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsVersions maps TLS_MIN_VERSION values to protocol versions. Versions below 1.2 are
+// deliberately absent: they are deprecated and fail security audits.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// defaultCipherSuites are the TLS 1.2 suites used when TLS_CIPHER_SUITES is unset: forward
+// secret AEAD suites only, leaving out the CBC suites Go still enables by default
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// TLSConfig builds the server's TLS settings from TLS_MIN_VERSION and TLS_CIPHER_SUITES.
+// Unknown versions and suites, and suites Go classes as insecure, are errors.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	minVersion, ok := tlsVersions[c.Server.TLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported TLS_MIN_VERSION: %s (use 1.2 or 1.3)", c.Server.TLSMinVersion)
+	}
+
+	suites := defaultCipherSuites
+	if len(c.Server.TLSCipherSuites) > 0 {
+		suites = make([]uint16, 0, len(c.Server.TLSCipherSuites))
+		for _, name := range c.Server.TLSCipherSuites {
+			id, err := cipherSuiteID(name)
+			if err != nil {
+				return nil, err
+			}
+			suites = append(suites, id)
+		}
+	}
+
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: suites,
+	}, nil
+}
+
+// cipherSuiteID resolves a cipher suite by its Go name, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func cipherSuiteID(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			return 0, fmt.Errorf("Insecure cipher suite in TLS_CIPHER_SUITES: %s", name)
+		}
+	}
+	return 0, fmt.Errorf("Unknown cipher suite in TLS_CIPHER_SUITES: %s", name)
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"gatekeeper/models"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// BackfillFields lists the entry fields BackfillEntries can fill in, with the value each
+// gets when missing
+var BackfillFields = map[string]string{
+	"status":     "ACTIVE",
+	"updated_at": "the entry's created_at",
+	"sequence":   "the next sequence number of the entry's checkpoint",
+}
+
+// BackfillPage reports one page of a backfill
+type BackfillPage struct {
+	Scanned int    // Entries read
+	Updated int    // Entries that lacked the field and were filled in
+	Next    string // Cursor to resume from; empty once every entry has been scanned
+}
+
+// BackfillEntries scans up to limit entries in record ID order after startAfter and fills
+// in field on those that lack it. Entries of one checkpoint are updated in one
+// transaction that re-checks the field, so re-running a page, or running it alongside
+// live writes, never overwrites a value that is already set.
+func (db *FirestoreDB) BackfillEntries(field, startAfter string, limit int) (*BackfillPage, error) {
+	if _, ok := BackfillFields[field]; !ok {
+		return nil, fmt.Errorf("unknown backfill field %q", field)
+	}
+	if limit <= 0 || limit > MaxEntriesPerTransaction {
+		limit = MaxEntriesPerTransaction
+	}
+
+	query := db.scopedQuery("entries").OrderBy(firestore.DocumentID, firestore.Asc)
+	if startAfter != "" {
+		query = query.StartAfter(startAfter)
+	}
+	docs, err := query.Limit(limit).Documents(db.ctx).GetAll()
+	if err != nil {
+		return nil, queryError("failed to scan entries", err, nil)
+	}
+
+	page := &BackfillPage{Scanned: len(docs)}
+	if len(docs) == limit {
+		page.Next = docs[len(docs)-1].Ref.ID
+	}
+
+	var order []string
+	byCheckpoint := make(map[string][]*firestore.DocumentRef)
+	for _, doc := range docs {
+		if !fieldMissing(doc.Data(), field) {
+			continue
+		}
+		checkpointID, _ := doc.Data()["checkpoint_id"].(string)
+		if _, seen := byCheckpoint[checkpointID]; !seen {
+			order = append(order, checkpointID)
+		}
+		byCheckpoint[checkpointID] = append(byCheckpoint[checkpointID], doc.Ref)
+	}
+
+	for _, checkpointID := range order {
+		updated, err := db.backfillCheckpoint(field, checkpointID, byCheckpoint[checkpointID])
+		page.Updated += updated
+		if err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// backfillCheckpoint fills in field on the given entries of one checkpoint in a transaction
+func (db *FirestoreDB) backfillCheckpoint(field, checkpointID string, refs []*firestore.DocumentRef) (int, error) {
+	counterRef := db.client.Collection("checkpoint_sequences").Doc(checkpointID)
+
+	var updated int
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		updated = 0
+		readRefs := refs
+		if field == "sequence" {
+			readRefs = append(slices.Clone(refs), counterRef)
+		}
+		docs, err := tx.GetAll(readRefs)
+		if err != nil {
+			return err
+		}
+
+		var counter models.CheckpointSequence
+		if field == "sequence" {
+			if counterDoc := docs[len(refs)]; counterDoc.Exists() {
+				if err := counterDoc.DataTo(&counter); err != nil {
+					return fmt.Errorf("failed to parse sequence counter: %w", err)
+				}
+			}
+			counter.CheckpointID = checkpointID
+		}
+
+		for _, doc := range docs[:len(refs)] {
+			// The entry may have been deleted or filled in since the scan
+			if !doc.Exists() || !fieldMissing(doc.Data(), field) {
+				continue
+			}
+			var value interface{}
+			switch field {
+			case "status":
+				value = models.StatusActive
+			case "updated_at":
+				createdAt, _ := doc.Data()["created_at"].(time.Time)
+				if createdAt.IsZero() {
+					continue
+				}
+				value = createdAt
+			case "sequence":
+				counter.LastSequence++
+				value = counter.LastSequence
+			}
+			if err := tx.Update(doc.Ref, []firestore.Update{{Path: field, Value: value}}); err != nil {
+				return err
+			}
+			updated++
+		}
+
+		if field == "sequence" && updated > 0 {
+			return tx.Set(counterRef, counter)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill %s on entries of checkpoint %s: %w", field, checkpointID, err)
+	}
+	return updated, nil
+}
+
+// fieldMissing reports whether a stored entry lacks field. Zero values count as missing,
+// since that is what older entries decode to.
+func fieldMissing(data map[string]interface{}, field string) bool {
+	switch value := data[field].(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case int64:
+		return value == 0
+	case time.Time:
+		return value.IsZero()
+	}
+	return false
+}
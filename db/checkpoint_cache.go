@@ -0,0 +1,103 @@
+package db
+
+import (
+	"gatekeeper/models"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointCacheTTL is how long checkpoint reads are served from memory
+const DefaultCheckpointCacheTTL = 60 * time.Second
+
+// checkpointCache holds every checkpoint across organizations. Checkpoints change
+// rarely but are read on hot paths, so reads are served from memory for ttl and
+// local writes invalidate the cache. Writes made by other instances become visible
+// once the ttl passes.
+type checkpointCache struct {
+	mu          sync.RWMutex
+	ttl         time.Duration // 0 disables caching
+	loadedAt    time.Time
+	checkpoints []models.Checkpoint
+	generation  uint64 // Bumped by every invalidation
+}
+
+// get returns the cached checkpoints, or false when the cache is disabled or stale
+func (c *checkpointCache) get() ([]models.Checkpoint, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.ttl <= 0 || c.checkpoints == nil || time.Since(c.loadedAt) > c.ttl {
+		return nil, false
+	}
+	return c.checkpoints, true
+}
+
+// currentGeneration returns the generation to pass to set for checkpoints loaded from
+// now on
+func (c *checkpointCache) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// set caches checkpoints loaded while the cache was at generation. A load that an
+// invalidation overtook may predate the write behind it, so it is not cached.
+func (c *checkpointCache) set(checkpoints []models.Checkpoint, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if checkpoints == nil {
+		checkpoints = []models.Checkpoint{}
+	}
+	c.checkpoints = checkpoints
+	c.loadedAt = time.Now()
+}
+
+func (c *checkpointCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkpoints = nil
+	c.generation++
+}
+
+func (c *checkpointCache) enabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ttl > 0
+}
+
+// SetCheckpointCacheTTL sets how long checkpoint reads are cached; 0 disables the cache
+func (db *FirestoreDB) SetCheckpointCacheTTL(ttl time.Duration) {
+	db.checkpoints.mu.Lock()
+	defer db.checkpoints.mu.Unlock()
+
+	db.checkpoints.ttl = ttl
+	db.checkpoints.checkpoints = nil
+	db.checkpoints.generation++
+}
+
+// RefreshCheckpoints drops the cached checkpoints so the next read goes to Firestore
+func (db *FirestoreDB) RefreshCheckpoints() {
+	db.checkpoints.invalidate()
+}
+
+// cachedCheckpoints returns every checkpoint, loading them when the cache is stale
+func (db *FirestoreDB) cachedCheckpoints() ([]models.Checkpoint, error) {
+	if checkpoints, ok := db.checkpoints.get(); ok {
+		return checkpoints, nil
+	}
+
+	generation := db.checkpoints.currentGeneration()
+	checkpoints, err := db.loadCheckpoints(db.client.Collection("checkpoints").Query)
+	if err != nil {
+		return nil, err
+	}
+	db.checkpoints.set(checkpoints, generation)
+	return checkpoints, nil
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"gatekeeper/models"
+	"testing"
+	"time"
+)
+
+func TestCheckpointCacheSetAfterInvalidateIsDropped(t *testing.T) {
+	cache := &checkpointCache{ttl: time.Minute}
+
+	// A load starts, a write invalidates the cache, then the stale load finishes
+	generation := cache.currentGeneration()
+	cache.invalidate()
+	cache.set([]models.Checkpoint{{CheckpointID: "CP-1", Name: "Stale"}}, generation)
+
+	if _, ok := cache.get(); ok {
+		t.Error("checkpoints loaded before an invalidation were cached")
+	}
+}
+
+func TestCheckpointCacheSetWithoutInvalidate(t *testing.T) {
+	cache := &checkpointCache{ttl: time.Minute}
+
+	cache.set([]models.Checkpoint{{CheckpointID: "CP-1"}}, cache.currentGeneration())
+
+	checkpoints, ok := cache.get()
+	if !ok || len(checkpoints) != 1 || checkpoints[0].CheckpointID != "CP-1" {
+		t.Errorf("get() = %v, %t; want the cached checkpoint", checkpoints, ok)
+	}
+}
@@ -2,28 +2,61 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"gatekeeper/auth"
 	"gatekeeper/models"
 	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	firebase "firebase.google.com/go"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // FirestoreDB wraps the Firestore client
 type FirestoreDB struct {
 	client *firestore.Client
 	ctx    context.Context
+	orgID  string // When set, queries and lookups are restricted to this organization
+
+	checkpoints *checkpointCache // Shared by every org view of the same client
+	users       *userCache       // Likewise
+}
+
+// ConnectOptions selects the Firestore project and how to authenticate against it
+type ConnectOptions struct {
+	ProjectID       string
+	CredentialsPath string
+	CredentialsJSON string // Raw service account JSON; takes precedence over CredentialsPath
+	EmulatorHost    string // host:port of a Firestore emulator; credentials are skipped when set
 }
 
 // NewFirestoreDB initializes a new Firestore client
-func NewFirestoreDB(ctx context.Context, projectID, credentialsPath string) (*FirestoreDB, error) {
-	opt := option.WithCredentialsFile(credentialsPath)
-	
-	config := &firebase.Config{ProjectID: projectID}
+func NewFirestoreDB(ctx context.Context, opts ConnectOptions) (*FirestoreDB, error) {
+	opt := option.WithCredentialsFile(opts.CredentialsPath)
+	if opts.CredentialsJSON != "" {
+		opt = option.WithCredentialsJSON([]byte(opts.CredentialsJSON))
+	}
+	if opts.EmulatorHost != "" {
+		// The Firestore client dials the emulator whenever this variable is set
+		if err := os.Setenv("FIRESTORE_EMULATOR_HOST", opts.EmulatorHost); err != nil {
+			return nil, fmt.Errorf("error configuring Firestore emulator: %w", err)
+		}
+		opt = option.WithoutAuthentication()
+	}
+
+	config := &firebase.Config{ProjectID: opts.ProjectID}
 	app, err := firebase.NewApp(ctx, config, opt)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing Firebase app: %w", err)
@@ -34,33 +67,465 @@ func NewFirestoreDB(ctx context.Context, projectID, credentialsPath string) (*Fi
 		return nil, fmt.Errorf("error initializing Firestore client: %w", err)
 	}
 
-	log.Printf("✅ Connected to Firestore project: %s", projectID)
+	if opts.EmulatorHost != "" {
+		log.Printf("✅ Connected to Firestore emulator at %s (project: %s)", opts.EmulatorHost, opts.ProjectID)
+	} else {
+		log.Printf("✅ Connected to Firestore project: %s", opts.ProjectID)
+	}
 
 	return &FirestoreDB{
-		client: client,
-		ctx:    ctx,
+		client:      client,
+		ctx:         ctx,
+		checkpoints: &checkpointCache{ttl: DefaultCheckpointCacheTTL},
+		users:       &userCache{ttl: DefaultUserCacheTTL, users: make(map[string]cachedUser)},
 	}, nil
 }
 
+// Ping checks that Firestore is reachable and the credentials are accepted by reading
+// at most one checkpoint
+func (db *FirestoreDB) Ping(ctx context.Context) error {
+	if _, err := db.client.Collection("checkpoints").Limit(1).Documents(ctx).GetAll(); err != nil {
+		return fmt.Errorf("failed to reach Firestore: %w", err)
+	}
+	return nil
+}
+
 // Close closes the Firestore client
 func (db *FirestoreDB) Close() error {
 	return db.client.Close()
 }
 
+// ForOrg returns a view of the database scoped to a single organization.
+// An empty orgID yields an unscoped view (single-tenant deployments and super admins).
+func (db *FirestoreDB) ForOrg(orgID string) *FirestoreDB {
+	return &FirestoreDB{
+		client:      db.client,
+		ctx:         db.ctx,
+		orgID:       orgID,
+		checkpoints: db.checkpoints,
+		users:       db.users,
+	}
+}
+
+// WithContext returns a view of the database whose operations run under ctx, keeping
+// the organization scope. Pass a request's context so work stops when the client
+// disconnects; the operation then fails with an error wrapping ctx.Err().
+func (db *FirestoreDB) WithContext(ctx context.Context) *FirestoreDB {
+	return &FirestoreDB{
+		client:      db.client,
+		ctx:         ctx,
+		orgID:       db.orgID,
+		checkpoints: db.checkpoints,
+		users:       db.users,
+	}
+}
+
+// scopedQuery returns a query over the collection restricted to the view's organization
+func (db *FirestoreDB) scopedQuery(collection string) firestore.Query {
+	query := db.client.Collection(collection).Query
+	if db.orgID != "" {
+		query = query.Where("org_id", "==", db.orgID)
+	}
+	return query
+}
+
+// scopedFields returns the equality filters scopedQuery adds, for composite index lookups
+func (db *FirestoreDB) scopedFields() []string {
+	if db.orgID != "" {
+		return []string{"org_id"}
+	}
+	return nil
+}
+
+// inScope reports whether a document belonging to orgID is visible in this view
+func (db *FirestoreDB) inScope(orgID string) bool {
+	return db.orgID == "" || db.orgID == orgID
+}
+
 // --- Entry Operations ---
 
-// CreateEntry creates a new entry in Firestore
+// CreateEntry creates a new entry in Firestore, overwriting any entry with the same
+// record ID. A new entry takes the next sequence number of its checkpoint; an overwritten
+// one keeps the number it was first given unless it moves to another checkpoint, where
+// it is numbered like a new entry.
 func (db *FirestoreDB) CreateEntry(entry *models.Entry) error {
-	_, err := db.client.Collection("entries").Doc(entry.RecordID).Set(db.ctx, entry)
+	entry.NormalizeTimestamps()
+	ref := db.client.Collection("entries").Doc(entry.RecordID)
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var existing models.Entry
+			if err := doc.DataTo(&existing); err != nil {
+				return fmt.Errorf("failed to parse entry: %w", err)
+			}
+			// Review state belongs to supervisors; a re-push must not clear it
+			entry.Reviewed = existing.Reviewed
+			entry.ReviewedBy = existing.ReviewedBy
+			entry.ReviewedAt = existing.ReviewedAt
+			entry.FlagReason = existing.FlagReason
+			if existing.CheckpointID == entry.CheckpointID {
+				entry.Sequence = existing.Sequence
+				return tx.Set(ref, entry)
+			}
+		}
+
+		if err := db.assignSequence(tx, entry); err != nil {
+			return err
+		}
+		return tx.Set(ref, entry)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create entry: %w", err)
+	}
+	return nil
+}
+
+// assignSequence gives a new entry the next sequence number of its checkpoint within the
+// transaction, so numbers have no gaps or duplicates. One counter document per checkpoint
+// sustains about one entry per second, well above a gate's pace; sharding it would lose
+// the gap-free ordering that makes the numbers useful.
+func (db *FirestoreDB) assignSequence(tx *firestore.Transaction, entry *models.Entry) error {
+	ref := db.client.Collection("checkpoint_sequences").Doc(entry.CheckpointID)
+	doc, err := tx.Get(ref)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	var counter models.CheckpointSequence
+	if err == nil {
+		if err := doc.DataTo(&counter); err != nil {
+			return fmt.Errorf("failed to parse sequence counter: %w", err)
+		}
+	}
+	counter.CheckpointID = entry.CheckpointID
+	counter.LastSequence++
+	entry.Sequence = counter.LastSequence
+	return tx.Set(ref, counter)
+}
+
+// ErrEntryExists is returned by InsertEntry when the record ID is already taken
+var ErrEntryExists = errors.New("entry already exists")
+
+// ErrNotFound is returned, wrapped, when a requested document does not exist or lies
+// outside the caller's organization. Check for it with errors.Is; any other error is a
+// real failure.
+var ErrNotFound = errors.New("not found")
+
+// ErrUsernameTaken is returned by CreateUser when the username or user ID is already in use
+var ErrUsernameTaken = errors.New("username already exists")
+
+// ErrWriteConflict is returned, wrapped, by WriteCheckpointEntries and ReplaceEntry when
+// concurrent writes to the same documents kept the transaction from committing
+var ErrWriteConflict = errors.New("write conflict")
+
+// ErrCheckpointIDTaken is returned by UpsertCheckpoint when another organization already
+// uses the checkpoint ID
+var ErrCheckpointIDTaken = errors.New("checkpoint ID is used by another organization")
+
+// ErrCheckpointExists is returned by CreateCheckpoint when the checkpoint ID is already taken
+var ErrCheckpointExists = errors.New("checkpoint already exists")
+
+// ErrLastAdmin is returned by ChangeRole when demoting the user would leave their
+// organization without an admin
+var ErrLastAdmin = errors.New("cannot change the role of the last admin")
+
+// ErrUserNotFound and ErrNotOperator are returned by SetCheckpointAssignment for invalid user IDs
+var (
+	ErrUserNotFound       = fmt.Errorf("user %w", ErrNotFound)
+	ErrNotOperator        = errors.New("user is not a gate operator")
+	ErrSyncCursorNotFound = fmt.Errorf("sync cursor %w", ErrNotFound)
+)
+
+// InsertEntry creates a new entry with the next sequence number of its checkpoint,
+// failing with ErrEntryExists instead of overwriting an existing document with the same
+// record ID
+func (db *FirestoreDB) InsertEntry(entry *models.Entry) error {
+	entry.NormalizeTimestamps()
+	ref := db.client.Collection("entries").Doc(entry.RecordID)
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(ref); err == nil {
+			return ErrEntryExists
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if err := db.assignSequence(tx, entry); err != nil {
+			return err
+		}
+		return tx.Create(ref, entry)
+	})
+	if errors.Is(err, ErrEntryExists) {
+		return ErrEntryExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create entry: %w", err)
 	}
 	return nil
 }
 
-// GetEntry retrieves an entry by ID
+// MaxEntriesPerTransaction is the most entries WriteCheckpointEntries accepts: one write
+// each, plus the checkpoint's sequence counter, within Firestore's 500-write limit
+const MaxEntriesPerTransaction = 499
+
+// WriteCheckpointEntries stores entries of one checkpoint in a single transaction, so
+// either all of them land or none do. Entries are written as by CreateEntry, except that
+// a record ID already used by another user's entry is a collision between clients: that
+// entry is stored under a record ID derived from its own and the logging user's, instead
+// of overwriting the other one, so pushing it again updates the same copy. An
+// overwrite keeps the stored status when role may not make the status change. On success
+// each entry's RecordID, Sequence and Status hold what was stored. Returns
+// ErrWriteConflict when contention outlasted the transaction's retries.
+func (db *FirestoreDB) WriteCheckpointEntries(entries []*models.Entry, role models.UserRole) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if len(entries) > MaxEntriesPerTransaction {
+		return fmt.Errorf("failed to write entries: %d entries exceed the limit of %d per transaction", len(entries), MaxEntriesPerTransaction)
+	}
+	checkpointID := entries[0].CheckpointID
+	refs := make([]*firestore.DocumentRef, len(entries))
+	for i, entry := range entries {
+		if entry.CheckpointID != checkpointID {
+			return fmt.Errorf("failed to write entries: entries span checkpoints %s and %s", checkpointID, entry.CheckpointID)
+		}
+		entry.NormalizeTimestamps()
+		refs[i] = db.client.Collection("entries").Doc(entry.RecordID)
+	}
+
+	// Pushed entries are stamped with the server's time, so delta pulls keyed on
+	// updated_at can't miss one whose device clock lags
+	now := models.Now()
+
+	// The transaction may run more than once, so it works on copies
+	var written []models.Entry
+	counterRef := db.client.Collection("checkpoint_sequences").Doc(checkpointID)
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Firestore requires every read of a transaction to precede its writes
+		docs, err := tx.GetAll(append(refs, counterRef))
+		if err != nil {
+			return err
+		}
+		var counter models.CheckpointSequence
+		if counterDoc := docs[len(refs)]; counterDoc.Exists() {
+			if err := counterDoc.DataTo(&counter); err != nil {
+				return fmt.Errorf("failed to parse sequence counter: %w", err)
+			}
+		}
+		counter.CheckpointID = checkpointID
+
+		written = make([]models.Entry, len(entries))
+		stored, err := parseStoredEntries(docs[:len(refs)])
+		if err != nil {
+			return err
+		}
+		targets := slices.Clone(refs)
+		var rekeyed []int // Index of each entry whose record ID another user's entry holds
+		var rekeyedRefs []*firestore.DocumentRef
+		for i, entry := range entries {
+			written[i] = *entry
+			written[i].UpdatedAt = now
+			if stored[i] != nil && stored[i].LoggingUserID != entry.LoggingUserID {
+				written[i].RecordID = rekeyedRecordID(entry.RecordID, entry.LoggingUserID)
+				targets[i] = db.client.Collection("entries").Doc(written[i].RecordID)
+				rekeyed = append(rekeyed, i)
+				rekeyedRefs = append(rekeyedRefs, targets[i])
+			}
+		}
+		if len(rekeyed) > 0 {
+			// An earlier push may already have stored the re-keyed copy
+			rekeyedDocs, err := tx.GetAll(rekeyedRefs)
+			if err != nil {
+				return err
+			}
+			rekeyedStored, err := parseStoredEntries(rekeyedDocs)
+			if err != nil {
+				return err
+			}
+			for j, i := range rekeyed {
+				stored[i] = rekeyedStored[j]
+			}
+		}
+
+		for i, entry := range entries {
+			if existing := stored[i]; existing != nil {
+				if existing.LoggingUserID != entry.LoggingUserID {
+					return fmt.Errorf("record ID %s is held by another user's entry", written[i].RecordID)
+				}
+				if existing.CheckpointID == checkpointID {
+					written[i].Sequence = existing.Sequence
+				} else {
+					// Numbers are per checkpoint; the old one may already be taken here
+					counter.LastSequence++
+					written[i].Sequence = counter.LastSequence
+				}
+				written[i].Reviewed = existing.Reviewed
+				written[i].ReviewedBy = existing.ReviewedBy
+				written[i].ReviewedAt = existing.ReviewedAt
+				written[i].FlagReason = existing.FlagReason
+				if models.CheckStatusTransition(existing.Status, entry.Status, role) != nil {
+					written[i].Status = existing.Status
+				}
+				if err := tx.Set(targets[i], &written[i]); err != nil {
+					return err
+				}
+				continue
+			}
+
+			counter.LastSequence++
+			written[i].Sequence = counter.LastSequence
+			if err := tx.Create(targets[i], &written[i]); err != nil {
+				return err
+			}
+		}
+		return tx.Set(counterRef, counter)
+	})
+	if status.Code(err) == codes.Aborted {
+		return fmt.Errorf("failed to write entries of checkpoint %s: %w: %v", checkpointID, ErrWriteConflict, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write entries of checkpoint %s: %w", checkpointID, err)
+	}
+
+	for i := range entries {
+		*entries[i] = written[i]
+	}
+	return nil
+}
+
+// parseStoredEntries parses the entry snapshots read by a transaction; missing documents
+// are nil
+func parseStoredEntries(docs []*firestore.DocumentSnapshot) ([]*models.Entry, error) {
+	entries := make([]*models.Entry, len(docs))
+	for i, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var entry models.Entry
+		if err := doc.DataTo(&entry); err != nil {
+			return nil, fmt.Errorf("failed to parse entry: %w", err)
+		}
+		entries[i] = &entry
+	}
+	return entries, nil
+}
+
+// rekeyedRecordID is the record ID an entry is stored under when another user's entry
+// already holds its own. It is derived from both, so every push of the entry maps to the
+// same document.
+func rekeyedRecordID(recordID, loggingUserID string) string {
+	sum := sha256.Sum256([]byte(recordID + "\x00" + loggingUserID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SetEntryStatus changes an entry's status in a transaction after check approves the
+// entry as currently stored, so concurrent changes can't both pass the same check. The
+// error check returns is passed through wrapped. Returns the updated entry.
+func (db *FirestoreDB) SetEntryStatus(recordID string, to models.EntryStatus, at time.Time, check func(entry *models.Entry) error) (*models.Entry, error) {
+	ref := db.client.Collection("entries").Doc(recordID)
+	at = at.UTC()
+	var entry models.Entry
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&entry); err != nil {
+			return fmt.Errorf("failed to parse entry: %w", err)
+		}
+		if !db.inScope(entry.OrgID) {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+		}
+		if err := check(&entry); err != nil {
+			return err
+		}
+
+		entry.Status, entry.UpdatedAt = to, at
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: to},
+			{Path: "updated_at", Value: at},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set entry status: %w", err)
+	}
+	return &entry, nil
+}
+
+// ReplaceEntry replaces a stored entry in a transaction with the entry resolve returns
+// for it, so the replacement is chosen against the copy it overwrites. resolve may
+// return nil to keep the stored entry. The owner, checkpoint, sequence, creation time
+// and review state are kept from the stored entry, a status change must be a valid
+// transition for role, and updated_at is set to at. The error resolve returns is passed
+// through wrapped. Returns the entry as stored afterwards.
+func (db *FirestoreDB) ReplaceEntry(recordID string, role models.UserRole, at time.Time, resolve func(stored *models.Entry) (*models.Entry, error)) (*models.Entry, error) {
+	ref := db.client.Collection("entries").Doc(recordID)
+	var result models.Entry
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+		}
+		if err != nil {
+			return err
+		}
+		var stored models.Entry
+		if err := doc.DataTo(&stored); err != nil {
+			return fmt.Errorf("failed to parse entry: %w", err)
+		}
+		if !db.inScope(stored.OrgID) {
+			return fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+		}
+
+		storedCopy := stored
+		replacement, err := resolve(&storedCopy)
+		if err != nil {
+			return err
+		}
+		if replacement == nil {
+			result = stored
+			return nil
+		}
+		if err := models.CheckStatusTransition(stored.Status, replacement.Status, role); err != nil {
+			return err
+		}
+
+		result = *replacement
+		result.RecordID = stored.RecordID
+		result.CheckpointID = stored.CheckpointID
+		result.LoggingUserID = stored.LoggingUserID
+		result.OriginalUserID = stored.OriginalUserID
+		result.OrgID = stored.OrgID
+		result.Sequence = stored.Sequence
+		result.CreatedAt = stored.CreatedAt
+		result.Reviewed = stored.Reviewed
+		result.ReviewedBy = stored.ReviewedBy
+		result.ReviewedAt = stored.ReviewedAt
+		result.FlagReason = stored.FlagReason
+		result.UpdatedAt = at
+		result.NormalizeTimestamps()
+		return tx.Set(ref, &result)
+	})
+	if status.Code(err) == codes.Aborted {
+		return nil, fmt.Errorf("failed to replace entry %s: %w: %v", recordID, ErrWriteConflict, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace entry: %w", err)
+	}
+	return &result, nil
+}
+
+// GetEntry retrieves an entry by ID, returning ErrNotFound if it doesn't exist
 func (db *FirestoreDB) GetEntry(recordID string) (*models.Entry, error) {
 	doc, err := db.client.Collection("entries").Doc(recordID).Get(db.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entry: %w", err)
 	}
@@ -70,12 +535,16 @@ func (db *FirestoreDB) GetEntry(recordID string) (*models.Entry, error) {
 		return nil, fmt.Errorf("failed to parse entry: %w", err)
 	}
 
+	if !db.inScope(entry.OrgID) {
+		return nil, fmt.Errorf("entry %w: %s", ErrNotFound, recordID)
+	}
+
 	return &entry, nil
 }
 
-// GetAllEntries retrieves all entries
+// GetAllEntries retrieves all entries in record ID order
 func (db *FirestoreDB) GetAllEntries() ([]models.Entry, error) {
-	iter := db.client.Collection("entries").Documents(db.ctx)
+	iter := db.scopedQuery("entries").OrderBy(firestore.DocumentID, firestore.Asc).Documents(db.ctx)
 	defer iter.Stop()
 
 	var entries []models.Entry
@@ -85,7 +554,7 @@ func (db *FirestoreDB) GetAllEntries() ([]models.Entry, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate entries: %w", err)
+			return nil, queryError("failed to iterate entries", err, nil)
 		}
 
 		var entry models.Entry
@@ -99,10 +568,12 @@ func (db *FirestoreDB) GetAllEntries() ([]models.Entry, error) {
 	return entries, nil
 }
 
-// GetEntriesByUser retrieves entries for a specific user
+// GetEntriesByUser retrieves every entry of a user in record ID order. Endpoints should
+// page with ListEntries instead; this is for internal callers that need the full set.
 func (db *FirestoreDB) GetEntriesByUser(userID string) ([]models.Entry, error) {
-	iter := db.client.Collection("entries").
+	iter := db.scopedQuery("entries").
 		Where("logging_user_id", "==", userID).
+		OrderBy(firestore.DocumentID, firestore.Asc).
 		Documents(db.ctx)
 	defer iter.Stop()
 
@@ -113,7 +584,7 @@ func (db *FirestoreDB) GetEntriesByUser(userID string) ([]models.Entry, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate entries: %w", err)
+			return nil, queryError("failed to iterate entries", err, nil)
 		}
 
 		var entry models.Entry
@@ -127,10 +598,13 @@ func (db *FirestoreDB) GetEntriesByUser(userID string) ([]models.Entry, error) {
 	return entries, nil
 }
 
-// GetEntriesByCheckpoint retrieves entries for a specific checkpoint
+// GetEntriesByCheckpoint retrieves every entry of a checkpoint in record ID order.
+// Endpoints should page with ListEntries instead; this is for internal callers that need
+// the full set.
 func (db *FirestoreDB) GetEntriesByCheckpoint(checkpointID string) ([]models.Entry, error) {
-	iter := db.client.Collection("entries").
+	iter := db.scopedQuery("entries").
 		Where("checkpoint_id", "==", checkpointID).
+		OrderBy(firestore.DocumentID, firestore.Asc).
 		Documents(db.ctx)
 	defer iter.Stop()
 
@@ -141,7 +615,7 @@ func (db *FirestoreDB) GetEntriesByCheckpoint(checkpointID string) ([]models.Ent
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate entries: %w", err)
+			return nil, queryError("failed to iterate entries", err, nil)
 		}
 
 		var entry models.Entry
@@ -155,12 +629,16 @@ func (db *FirestoreDB) GetEntriesByCheckpoint(checkpointID string) ([]models.Ent
 	return entries, nil
 }
 
-// GetEntriesSince retrieves entries created after a specific timestamp
+// GetEntriesSince retrieves entries written after a specific timestamp, least recently
+// updated first. Every write bumps updated_at, so this includes entries edited, reviewed
+// or tombstoned since, not only new ones.
 func (db *FirestoreDB) GetEntriesSince(since time.Time) ([]models.Entry, error) {
-	iter := db.client.Collection("entries").
-		Where("created_at", ">", since).
+	iter := db.scopedQuery("entries").
+		Where("updated_at", ">", since).
+		OrderBy("updated_at", firestore.Asc).
 		Documents(db.ctx)
 	defer iter.Stop()
+	index := lookupIndex("entries", db.scopedFields(), "updated_at", "ASCENDING")
 
 	var entries []models.Entry
 	for {
@@ -169,7 +647,7 @@ func (db *FirestoreDB) GetEntriesSince(since time.Time) ([]models.Entry, error)
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate entries: %w", err)
+			return nil, queryError("failed to iterate entries", err, index)
 		}
 
 		var entry models.Entry
@@ -183,180 +661,1290 @@ func (db *FirestoreDB) GetEntriesSince(since time.Time) ([]models.Entry, error)
 	return entries, nil
 }
 
-// --- User Operations ---
-
-// CreateUser creates a new user in Firestore
-func (db *FirestoreDB) CreateUser(user *models.User) error {
-	_, err := db.client.Collection("users").Doc(user.UserID).Set(db.ctx, user)
-	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
-	}
-	return nil
-}
-
-// GetUser retrieves a user by ID
-func (db *FirestoreDB) GetUser(userID string) (*models.User, error) {
-	doc, err := db.client.Collection("users").Doc(userID).Get(db.ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
-
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		return nil, fmt.Errorf("failed to parse user: %w", err)
-	}
-
-	return &user, nil
-}
-
-// GetUserByUsername retrieves a user by username
-func (db *FirestoreDB) GetUserByUsername(username string) (*models.User, error) {
-	iter := db.client.Collection("users").
-		Where("username", "==", username).
-		Limit(1).
+// GetEntriesCreatedBefore retrieves up to limit entries created before the cutoff, oldest first
+func (db *FirestoreDB) GetEntriesCreatedBefore(cutoff time.Time, limit int) ([]models.Entry, error) {
+	iter := db.scopedQuery("entries").
+		Where("created_at", "<", cutoff).
+		OrderBy("created_at", firestore.Asc).
+		Limit(limit).
 		Documents(db.ctx)
 	defer iter.Stop()
+	index := lookupIndex("entries", db.scopedFields(), "created_at", "ASCENDING")
 
-	doc, err := iter.Next()
-	if err == iterator.Done {
-		return nil, fmt.Errorf("user not found: %s", username)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
+	var entries []models.Entry
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, queryError("failed to iterate entries", err, index)
+		}
 
-	var user models.User
-	if err := doc.DataTo(&user); err != nil {
-		return nil, fmt.Errorf("failed to parse user: %w", err)
+		var entry models.Entry
+		if err := doc.DataTo(&entry); err != nil {
+			log.Printf("Warning: failed to parse entry %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		entries = append(entries, entry)
 	}
 
-	return &user, nil
+	return entries, nil
 }
 
-// GetAllUsers retrieves all users
-func (db *FirestoreDB) GetAllUsers() ([]models.User, error) {
-	iter := db.client.Collection("users").Documents(db.ctx)
+// GetAuditLogsBefore retrieves up to limit audit logs of every organization recorded
+// before the cutoff, oldest first
+func (db *FirestoreDB) GetAuditLogsBefore(cutoff time.Time, limit int) ([]models.AuditLog, error) {
+	// Timestamps are stored as RFC3339 UTC strings, which sort chronologically
+	iter := db.client.Collection("audit_logs").
+		Where("timestamp", "<", models.FormatTimestamp(cutoff)).
+		OrderBy("timestamp", firestore.Asc).
+		Limit(limit).
+		Documents(db.ctx)
 	defer iter.Stop()
 
-	var users []models.User
+	var auditLogs []models.AuditLog
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate users: %w", err)
+			return nil, queryError("failed to iterate audit logs", err, nil)
 		}
 
-		var user models.User
-		if err := doc.DataTo(&user); err != nil {
-			log.Printf("Warning: failed to parse user %s: %v", doc.Ref.ID, err)
+		var auditLog models.AuditLog
+		if err := doc.DataTo(&auditLog); err != nil {
+			log.Printf("Warning: failed to parse audit log %s: %v", doc.Ref.ID, err)
 			continue
 		}
-		users = append(users, user)
+		auditLog.LogID = doc.Ref.ID
+		auditLogs = append(auditLogs, auditLog)
 	}
 
-	return users, nil
+	return auditLogs, nil
 }
 
-// UpdateUser updates an existing user
-func (db *FirestoreDB) UpdateUser(user *models.User) error {
-	_, err := db.client.Collection("users").Doc(user.UserID).Set(db.ctx, user)
-	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+// ArchiveAuditLogs moves the given audit logs to the audit_logs_archive collection in a
+// single batched write. Callers must keep the batch within Firestore's 500-write limit
+// (two writes per log).
+func (db *FirestoreDB) ArchiveAuditLogs(auditLogs []models.AuditLog) error {
+	if len(auditLogs) == 0 {
+		return nil
+	}
+
+	batch := db.client.Batch()
+	for i := range auditLogs {
+		batch.Set(db.client.Collection("audit_logs_archive").Doc(auditLogs[i].LogID), &auditLogs[i])
+		batch.Delete(db.client.Collection("audit_logs").Doc(auditLogs[i].LogID))
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to archive audit logs: %w", err)
 	}
 	return nil
 }
 
-// DeleteUser deletes a user
-func (db *FirestoreDB) DeleteUser(userID string) error {
-	_, err := db.client.Collection("users").Doc(userID).Delete(db.ctx)
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+// DeleteAuditLogs deletes the given audit logs in a single batched write.
+// Callers must keep the batch within Firestore's 500-write limit.
+func (db *FirestoreDB) DeleteAuditLogs(logIDs []string) error {
+	if len(logIDs) == 0 {
+		return nil
+	}
+
+	batch := db.client.Batch()
+	for _, logID := range logIDs {
+		batch.Delete(db.client.Collection("audit_logs").Doc(logID))
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to delete audit logs: %w", err)
 	}
 	return nil
 }
 
-// --- Checkpoint Operations ---
+// DeleteEntries deletes the given entries in a single batched write.
+// Callers must keep the batch within Firestore's 500-write limit.
+func (db *FirestoreDB) DeleteEntries(recordIDs []string) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
 
-// CreateCheckpoint creates a new checkpoint in Firestore
-func (db *FirestoreDB) CreateCheckpoint(checkpoint *models.Checkpoint) error {
-	_, err := db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID).Set(db.ctx, checkpoint)
-	if err != nil {
-		return fmt.Errorf("failed to create checkpoint: %w", err)
+	batch := db.client.Batch()
+	for _, recordID := range recordIDs {
+		batch.Delete(db.client.Collection("entries").Doc(recordID))
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to delete entries: %w", err)
 	}
 	return nil
 }
 
-// GetCheckpoint retrieves a checkpoint by ID
-func (db *FirestoreDB) GetCheckpoint(checkpointID string) (*models.Checkpoint, error) {
-	doc, err := db.client.Collection("checkpoints").Doc(checkpointID).Get(db.ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+// ArchiveEntries moves the given entries to the entries_archive collection in a single
+// batched write, so each entry is either archived and removed or left untouched.
+// Callers must keep the batch within Firestore's 500-write limit (two writes per entry).
+func (db *FirestoreDB) ArchiveEntries(entries []models.Entry) error {
+	if len(entries) == 0 {
+		return nil
 	}
 
-	var checkpoint models.Checkpoint
-	if err := doc.DataTo(&checkpoint); err != nil {
-		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	batch := db.client.Batch()
+	for i := range entries {
+		batch.Set(db.client.Collection("entries_archive").Doc(entries[i].RecordID), &entries[i])
+		batch.Delete(db.client.Collection("entries").Doc(entries[i].RecordID))
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to archive entries: %w", err)
 	}
+	return nil
+}
 
-	return &checkpoint, nil
+// EntryFilter narrows an entry query for views, exports and stats. Zero values are ignored.
+// Tombstoned entries are excluded unless IncludeDeleted is set; sync reads, which must
+// carry tombstones to clients, use GetAllEntries and GetEntriesSince instead.
+type EntryFilter struct {
+	CheckpointID   string
+	LoggingUserID  string
+	EntryType      models.EntryType
+	From           time.Time // Inclusive lower bound on created_at
+	To             time.Time // Exclusive upper bound on created_at
+	IncludeDeleted bool
 }
 
-// GetAllCheckpoints retrieves all checkpoints
-func (db *FirestoreDB) GetAllCheckpoints() ([]models.Checkpoint, error) {
-	iter := db.client.Collection("checkpoints").Documents(db.ctx)
+// entryFilterQuery builds the query for a filter and the composite index it needs
+// once ordered (in the given direction) or ranged on created_at
+func (db *FirestoreDB) entryFilterQuery(filter EntryFilter, order string) (firestore.Query, *Index) {
+	query := db.scopedQuery("entries")
+	eqFields := db.scopedFields()
+	if filter.CheckpointID != "" {
+		query = query.Where("checkpoint_id", "==", filter.CheckpointID)
+		eqFields = append(eqFields, "checkpoint_id")
+	}
+	if filter.LoggingUserID != "" {
+		query = query.Where("logging_user_id", "==", filter.LoggingUserID)
+		eqFields = append(eqFields, "logging_user_id")
+	}
+	if filter.EntryType != "" {
+		query = query.Where("entry_type", "==", filter.EntryType)
+		eqFields = append(eqFields, "entry_type")
+	}
+	if !filter.IncludeDeleted {
+		query = query.Where("status", "==", models.StatusActive)
+		eqFields = append(eqFields, "status")
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at", ">=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at", "<", filter.To)
+	}
+	return query, lookupIndex("entries", eqFields, "created_at", order)
+}
+
+// CountEntries counts the entries matching the filter without reading them
+func (db *FirestoreDB) CountEntries(filter EntryFilter) (int, error) {
+	query, index := db.entryFilterQuery(filter, "ASCENDING")
+	return countQuery(db.ctx, query, "failed to count entries", index)
+}
+
+// GetEntriesByFilter returns entries matching the filter, oldest first
+func (db *FirestoreDB) GetEntriesByFilter(filter EntryFilter) ([]models.Entry, error) {
+	query, index := db.entryFilterQuery(filter, "ASCENDING")
+	iter := query.OrderBy("created_at", firestore.Asc).Documents(db.ctx)
 	defer iter.Stop()
 
-	var checkpoints []models.Checkpoint
+	var entries []models.Entry
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate checkpoints: %w", err)
+			return nil, queryError("failed to iterate entries", err, index)
 		}
 
-		var checkpoint models.Checkpoint
-		if err := doc.DataTo(&checkpoint); err != nil {
-			log.Printf("Warning: failed to parse checkpoint %s: %v", doc.Ref.ID, err)
+		var entry models.Entry
+		if err := doc.DataTo(&entry); err != nil {
+			log.Printf("Warning: failed to parse entry %s: %v", doc.Ref.ID, err)
 			continue
 		}
-		checkpoints = append(checkpoints, checkpoint)
+		entries = append(entries, entry)
 	}
 
-	return checkpoints, nil
+	return entries, nil
 }
 
-// UpdateCheckpoint updates an existing checkpoint
-func (db *FirestoreDB) UpdateCheckpoint(checkpoint *models.Checkpoint) error {
-	_, err := db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID).Set(db.ctx, checkpoint)
-	if err != nil {
-		return fmt.Errorf("failed to update checkpoint: %w", err)
-	}
+// ListEntries returns one page of the entries matching the filter, newest first,
+// starting after the entry with record ID startAfter. next is the record ID to resume
+// after, or empty on the last page.
+func (db *FirestoreDB) ListEntries(filter EntryFilter, limit int, startAfter string) ([]models.Entry, string, error) {
+	query, index := db.entryFilterQuery(filter, "DESCENDING")
+	query = query.OrderBy("created_at", firestore.Desc)
+	if startAfter != "" {
+		doc, err := db.client.Collection("entries").Doc(startAfter).Get(db.ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, "", ErrInvalidCursor
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to resolve entry cursor: %w", err)
+		}
+		query = query.StartAfter(doc)
+	}
+
+	// Fetch one extra entry to learn whether another page follows
+	docs, err := query.Limit(limit + 1).Documents(db.ctx).GetAll()
+	if err != nil {
+		return nil, "", queryError("failed to list entries", err, index)
+	}
+
+	next := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		next = docs[limit-1].Ref.ID
+	}
+
+	entries := make([]models.Entry, 0, len(docs))
+	for _, doc := range docs {
+		var entry models.Entry
+		if err := doc.DataTo(&entry); err != nil {
+			log.Printf("Warning: failed to parse entry %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, next, nil
+}
+
+// TombstoneEntries marks the given entries as DELETED in a single batched write, bumping
+// updated_at so clients pick up the deletion on their next pull.
+// Callers must keep the batch within Firestore's 500-write limit.
+func (db *FirestoreDB) TombstoneEntries(recordIDs []string) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
+
+	now := models.Now()
+	batch := db.client.Batch()
+	for _, recordID := range recordIDs {
+		batch.Update(db.client.Collection("entries").Doc(recordID), []firestore.Update{
+			{Path: "status", Value: models.StatusDeleted},
+			{Path: "updated_at", Value: now},
+		})
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to tombstone entries: %w", err)
+	}
+	return nil
+}
+
+// EntryReview is the review state a supervisor sets on entries
+type EntryReview struct {
+	Reviewed   bool
+	ReviewedBy string
+	ReviewedAt time.Time
+	FlagReason string
+}
+
+// ReviewEntries sets the review state of the given entries in a single batched write and
+// bumps updated_at so the change reaches clients. Callers must keep the batch within
+// Firestore's 500-write limit.
+func (db *FirestoreDB) ReviewEntries(recordIDs []string, review EntryReview) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
+	review.ReviewedAt = review.ReviewedAt.UTC()
+
+	batch := db.client.Batch()
+	for _, recordID := range recordIDs {
+		batch.Update(db.client.Collection("entries").Doc(recordID), []firestore.Update{
+			{Path: "reviewed", Value: review.Reviewed},
+			{Path: "reviewed_by", Value: review.ReviewedBy},
+			{Path: "reviewed_at", Value: review.ReviewedAt},
+			{Path: "flag_reason", Value: review.FlagReason},
+			{Path: "updated_at", Value: review.ReviewedAt},
+		})
+	}
+	if _, err := batch.Commit(db.ctx); err != nil {
+		return fmt.Errorf("failed to review entries: %w", err)
+	}
+	return nil
+}
+
+// reassignBatchSize is the number of entries updated per atomic batch (Firestore allows 500 writes)
+const reassignBatchSize = 500
+
+// ReassignEntries moves every entry logged by fromUserID to toUserID in atomic batches,
+// recording the first owner in original_user_id. Each batch either fully applies or not
+// at all, and re-running after a failure picks up only the entries not yet moved.
+func (db *FirestoreDB) ReassignEntries(fromUserID, toUserID string) (int, error) {
+	reassigned := 0
+	for {
+		iter := db.scopedQuery("entries").
+			Where("logging_user_id", "==", fromUserID).
+			Limit(reassignBatchSize).
+			Documents(db.ctx)
+		docs, err := iter.GetAll()
+		if err != nil {
+			return reassigned, queryError("failed to iterate entries", err, nil)
+		}
+		if len(docs) == 0 {
+			return reassigned, nil
+		}
+
+		now := models.Now()
+		batch := db.client.Batch()
+		for _, doc := range docs {
+			var entry models.Entry
+			if err := doc.DataTo(&entry); err != nil {
+				return reassigned, fmt.Errorf("failed to parse entry %s: %w", doc.Ref.ID, err)
+			}
+
+			originalUserID := entry.OriginalUserID
+			if originalUserID == "" {
+				originalUserID = fromUserID
+			}
+			batch.Update(doc.Ref, []firestore.Update{
+				{Path: "logging_user_id", Value: toUserID},
+				{Path: "original_user_id", Value: originalUserID},
+				{Path: "updated_at", Value: now},
+			})
+		}
+
+		if _, err := batch.Commit(db.ctx); err != nil {
+			return reassigned, fmt.Errorf("failed to reassign entries: %w", err)
+		}
+		reassigned += len(docs)
+	}
+}
+
+// --- User Operations ---
+
+// CreateUser creates a new user in Firestore. The username is claimed in the usernames
+// collection in the same transaction, so of two concurrent creates with one username only
+// the first succeeds; the other fails with ErrUsernameTaken.
+func (db *FirestoreDB) CreateUser(user *models.User) error {
+	user.NormalizeTimestamps()
+	userRef := db.client.Collection("users").Doc(user.UserID)
+	claimRef := db.client.Collection("usernames").Doc(usernameDocID(user.Username))
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.GetAll([]*firestore.DocumentRef{claimRef, userRef})
+		if err != nil {
+			return err
+		}
+		if docs[0].Exists() || docs[1].Exists() {
+			return ErrUsernameTaken
+		}
+
+		// Users created before usernames were claimed have no claim document
+		existing, err := tx.Documents(db.client.Collection("users").Where("username", "==", user.Username).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return ErrUsernameTaken
+		}
+
+		claim := models.UsernameClaim{Username: user.Username, UserID: user.UserID, OrgID: user.OrgID}
+		if err := tx.Create(claimRef, claim); err != nil {
+			return err
+		}
+		return tx.Create(userRef, user)
+	})
+	if errors.Is(err, ErrUsernameTaken) {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// usernameDocID returns the ID of the usernames document claiming username. The prefix
+// and escaping make every username a valid document ID, including "." and ones with "/".
+func usernameDocID(username string) string {
+	return "u:" + url.PathEscape(username)
+}
+
+// GetUser retrieves a user by ID, returning ErrUserNotFound if it doesn't exist
+func (db *FirestoreDB) GetUser(userID string) (*models.User, error) {
+	doc, err := db.client.Collection("users").Doc(userID).Get(db.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	return db.scopedUser(&user, userID)
+}
+
+// scopedUser returns user if it belongs to the view's organization, and otherwise
+// ErrUserNotFound for key, so lookups never reveal other organizations' users
+func (db *FirestoreDB) scopedUser(user *models.User, key string) (*models.User, error) {
+	if !db.inScope(user.OrgID) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, key)
+	}
+	return user, nil
+}
+
+// GetUserByUsername retrieves a user by username, returning ErrUserNotFound if none
+// matches in the view's organization. Usernames are unique across organizations.
+func (db *FirestoreDB) GetUserByUsername(username string) (*models.User, error) {
+	iter := db.client.Collection("users").
+		Where("username", "==", username).
+		Limit(1).
+		Documents(db.ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	if err != nil {
+		return nil, queryError("failed to get user", err, nil)
+	}
+
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return nil, fmt.Errorf("failed to parse user: %w", err)
+	}
+
+	return db.scopedUser(&user, username)
+}
+
+// GetAllUsers retrieves all users in user ID order
+func (db *FirestoreDB) GetAllUsers() ([]models.User, error) {
+	iter := db.scopedQuery("users").OrderBy(firestore.DocumentID, firestore.Asc).Documents(db.ctx)
+	defer iter.Stop()
+
+	var users []models.User
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, queryError("failed to iterate users", err, nil)
+		}
+
+		var user models.User
+		if err := doc.DataTo(&user); err != nil {
+			log.Printf("Warning: failed to parse user %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// ListUsers returns up to limit users in user ID order, starting after the user with ID
+// startAfter (empty for the first page). The returned next is the startAfter of the
+// following page, or empty on the last page.
+func (db *FirestoreDB) ListUsers(limit int, startAfter string) ([]models.User, string, error) {
+	query := db.scopedQuery("users").OrderBy(firestore.DocumentID, firestore.Asc)
+	if startAfter != "" {
+		query = query.StartAfter(startAfter)
+	}
+
+	// Fetch one extra user to learn whether another page follows
+	docs, err := query.Limit(limit + 1).Documents(db.ctx).GetAll()
+	if err != nil {
+		return nil, "", queryError("failed to list users", err, nil)
+	}
+
+	next := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		next = docs[limit-1].Ref.ID
+	}
+
+	users := make([]models.User, 0, len(docs))
+	for _, doc := range docs {
+		var user models.User
+		if err := doc.DataTo(&user); err != nil {
+			log.Printf("Warning: failed to parse user %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, next, nil
+}
+
+// maxInQueryValues is the most values Firestore accepts in a single 'in' filter
+const maxInQueryValues = 30
+
+// GetUsersByIDs retrieves the users with the given IDs using batched 'in' queries, in
+// user ID order. IDs that don't exist or are outside the view's organization are left out.
+func (db *FirestoreDB) GetUsersByIDs(userIDs []string) ([]models.User, error) {
+	var users []models.User
+	for start := 0; start < len(userIDs); start += maxInQueryValues {
+		end := min(start+maxInQueryValues, len(userIDs))
+		docs, err := db.scopedQuery("users").
+			Where("user_id", "in", userIDs[start:end]).
+			Documents(db.ctx).
+			GetAll()
+		if err != nil {
+			return nil, queryError("failed to look up users", err, nil)
+		}
+
+		for _, doc := range docs {
+			var user models.User
+			if err := doc.DataTo(&user); err != nil {
+				log.Printf("Warning: failed to parse user %s: %v", doc.Ref.ID, err)
+				continue
+			}
+			users = append(users, user)
+		}
+	}
+	// Each batch is ordered on its own
+	slices.SortFunc(users, func(a, b models.User) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return users, nil
+}
+
+// UserFilter narrows a user query. Zero values are ignored.
+type UserFilter struct {
+	Role          models.UserRole
+	SupervisorID  string
+	LoggedInSince time.Time // Only users whose last login is at or after this time
+}
+
+// CountUsers counts the users matching the filter without reading them
+func (db *FirestoreDB) CountUsers(filter UserFilter) (int, error) {
+	query := db.scopedQuery("users")
+	if filter.Role != "" {
+		query = query.Where("role", "==", filter.Role)
+	}
+	if filter.SupervisorID != "" {
+		query = query.Where("supervisor_id", "==", filter.SupervisorID)
+	}
+	if filter.LoggedInSince.IsZero() {
+		// Equality-only filters are served by single-field indexes
+		return countQuery(db.ctx, query, "failed to count users", nil)
+	}
+
+	query = query.Where("last_login", ">=", filter.LoggedInSince)
+	var eqFields []string
+	if db.orgID != "" {
+		eqFields = append(eqFields, "org_id")
+	}
+	if filter.Role != "" {
+		eqFields = append(eqFields, "role")
+	}
+	if filter.SupervisorID != "" {
+		eqFields = append(eqFields, "supervisor_id")
+	}
+	return countQuery(db.ctx, query, "failed to count users", lookupIndex("users", eqFields, "last_login", "ASCENDING"))
+}
+
+// UpdateUser updates an existing user
+func (db *FirestoreDB) UpdateUser(user *models.User) error {
+	defer db.users.invalidate(user.UserID)
+	user.NormalizeTimestamps()
+	_, err := db.client.Collection("users").Doc(user.UserID).Set(db.ctx, user)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// UserFieldsUpdate is a partial update of a user's admin-managed fields. Nil and empty
+// fields are left unchanged.
+type UserFieldsUpdate struct {
+	AllowedCheckpoints []string
+	SupervisorID       string
+	Permissions        []models.Permission
+}
+
+// UpdateUserFields writes only the fields set in update, so an admin edit cannot clobber
+// fields changed concurrently elsewhere, such as password_changed_at
+func (db *FirestoreDB) UpdateUserFields(userID string, update UserFieldsUpdate) error {
+	var updates []firestore.Update
+	if update.AllowedCheckpoints != nil {
+		updates = append(updates, firestore.Update{Path: "allowed_checkpoints", Value: update.AllowedCheckpoints})
+	}
+	if update.SupervisorID != "" {
+		updates = append(updates, firestore.Update{Path: "supervisor_id", Value: update.SupervisorID})
+	}
+	if update.Permissions != nil {
+		updates = append(updates, firestore.Update{Path: "permissions", Value: update.Permissions})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	defer db.users.invalidate(userID)
+	_, err := db.client.Collection("users").Doc(userID).Update(db.ctx, updates)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// AddManagedOperator adds operatorID to a supervisor's managed operators with a
+// targeted array write, so concurrent changes to the list or the rest of the document
+// are not overwritten. Adding an operator already in the list does nothing.
+func (db *FirestoreDB) AddManagedOperator(supervisorID, operatorID string) error {
+	defer db.users.invalidate(supervisorID)
+	_, err := db.client.Collection("users").Doc(supervisorID).Update(db.ctx, []firestore.Update{
+		{Path: "managed_operators", Value: firestore.ArrayUnion(operatorID)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add managed operator: %w", err)
+	}
+	return nil
+}
+
+// RemoveManagedOperator removes operatorID from a supervisor's managed operators with a
+// targeted array write
+func (db *FirestoreDB) RemoveManagedOperator(supervisorID, operatorID string) error {
+	defer db.users.invalidate(supervisorID)
+	_, err := db.client.Collection("users").Doc(supervisorID).Update(db.ctx, []firestore.Update{
+		{Path: "managed_operators", Value: firestore.ArrayRemove(operatorID)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove managed operator: %w", err)
+	}
+	return nil
+}
+
+// RecordLogin stamps last_login and last_activity_at with a targeted field write, so a
+// login racing a password reset cannot restore the old password_changed_at
+func (db *FirestoreDB) RecordLogin(userID string, at time.Time) error {
+	at = at.UTC()
+	_, err := db.client.Collection("users").Doc(userID).Update(db.ctx, []firestore.Update{
+		{Path: "last_login", Value: at},
+		{Path: "last_activity_at", Value: at},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	db.users.update(userID, func(user *models.User) {
+		user.LastLogin, user.LastActivityAt = at, at
+	})
+	return nil
+}
+
+// SetAllowedCheckpoints replaces a user's allowed checkpoints with a targeted field write
+func (db *FirestoreDB) SetAllowedCheckpoints(userID string, checkpoints []string) error {
+	defer db.users.invalidate(userID)
+	_, err := db.client.Collection("users").Doc(userID).Update(db.ctx, []firestore.Update{
+		{Path: "allowed_checkpoints", Value: checkpoints},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update allowed checkpoints: %w", err)
+	}
+	return nil
+}
+
+// TouchLastSync records that the user just synced. It updates only last_sync_at, so it
+// cannot clobber a concurrent edit of the user's other fields.
+func (db *FirestoreDB) TouchLastSync(userID string, at time.Time) error {
+	at = at.UTC()
+	_, err := db.client.Collection("users").Doc(userID).Update(db.ctx, []firestore.Update{
+		{Path: "last_sync_at", Value: at},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update last sync time: %w", err)
+	}
+	db.users.update(userID, func(user *models.User) { user.LastSyncAt = at })
+	return nil
+}
+
+// TouchLastActivity records the user's latest authenticated request. Like TouchLastSync
+// it updates a single field.
+func (db *FirestoreDB) TouchLastActivity(userID string, at time.Time) error {
+	at = at.UTC()
+	_, err := db.client.Collection("users").Doc(userID).Update(db.ctx, []firestore.Update{
+		{Path: "last_activity_at", Value: at},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update last activity time: %w", err)
+	}
+	db.users.update(userID, func(user *models.User) { user.LastActivityAt = at })
+	return nil
+}
+
+// DeleteUser deletes a user
+func (db *FirestoreDB) DeleteUser(userID string) error {
+	defer db.users.invalidate(userID)
+	userRef := db.client.Collection("users").Doc(userID)
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var user models.User
+		if err := doc.DataTo(&user); err != nil {
+			return fmt.Errorf("failed to parse user: %w", err)
+		}
+
+		// Free the username for reuse; users created before claims have none to delete
+		if err := tx.Delete(db.client.Collection("usernames").Doc(usernameDocID(user.Username))); err != nil {
+			return err
+		}
+		return tx.Delete(userRef)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// SetCheckpointAssignment adds (assign) or removes (unassign) a checkpoint in the
+// allowed_checkpoints of each operator, all in one transaction. Unknown, out-of-scope
+// or non-operator user IDs abort the whole change. It returns the updated operators.
+func (db *FirestoreDB) SetCheckpointAssignment(checkpointID string, userIDs []string, assign bool) ([]models.User, error) {
+	defer db.users.invalidate(userIDs...)
+	refs := make([]*firestore.DocumentRef, len(userIDs))
+	for i, userID := range userIDs {
+		refs[i] = db.client.Collection("users").Doc(userID)
+	}
+
+	var updated []models.User
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		updated = make([]models.User, 0, len(refs))
+
+		docs, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+
+		for i, doc := range docs {
+			if !doc.Exists() {
+				return fmt.Errorf("%w: %s", ErrUserNotFound, userIDs[i])
+			}
+			var user models.User
+			if err := doc.DataTo(&user); err != nil {
+				return fmt.Errorf("failed to parse user %s: %w", userIDs[i], err)
+			}
+			if !db.inScope(user.OrgID) {
+				return fmt.Errorf("%w: %s", ErrUserNotFound, userIDs[i])
+			}
+			if user.Role != models.RoleGateOperator {
+				return fmt.Errorf("%w: %s", ErrNotOperator, userIDs[i])
+			}
+
+			checkpoints := []string{}
+			for _, id := range user.AllowedCheckpoints {
+				if id != checkpointID {
+					checkpoints = append(checkpoints, id)
+				}
+			}
+			if assign {
+				checkpoints = append(checkpoints, checkpointID)
+			}
+			user.AllowedCheckpoints = checkpoints
+
+			if err := tx.Update(refs[i], []firestore.Update{
+				{Path: "allowed_checkpoints", Value: checkpoints},
+			}); err != nil {
+				return err
+			}
+			updated = append(updated, user)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrNotOperator) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update checkpoint assignment: %w", err)
+	}
+	return updated, nil
+}
+
+// ClearSupervisor removes an operator's supervisor and drops the operator from that
+// supervisor's managed_operators in one transaction. It returns the updated operator.
+func (db *FirestoreDB) ClearSupervisor(userID string) (*models.User, error) {
+	// Supervisors and operators are updated too, so evict everyone
+	defer db.users.invalidate()
+	userRef := db.client.Collection("users").Doc(userID)
+
+	var user models.User
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&user); err != nil {
+			return fmt.Errorf("failed to parse user %s: %w", userID, err)
+		}
+		if !db.inScope(user.OrgID) {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+		if user.SupervisorID == "" {
+			return nil
+		}
+
+		// Read the supervisor before any write, as transactions require
+		supervisorRef := db.client.Collection("users").Doc(user.SupervisorID)
+		supervisorDoc, err := tx.Get(supervisorRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if err := tx.Update(userRef, []firestore.Update{
+			{Path: "supervisor_id", Value: firestore.Delete},
+		}); err != nil {
+			return err
+		}
+
+		// The supervisor may already have been deleted
+		if supervisorDoc != nil && supervisorDoc.Exists() {
+			var supervisor models.User
+			if err := supervisorDoc.DataTo(&supervisor); err != nil {
+				return fmt.Errorf("failed to parse supervisor %s: %w", user.SupervisorID, err)
+			}
+			managed := []string{}
+			for _, opID := range supervisor.ManagedOperators {
+				if opID != userID {
+					managed = append(managed, opID)
+				}
+			}
+			if err := tx.Update(supervisorRef, []firestore.Update{
+				{Path: "managed_operators", Value: managed},
+			}); err != nil {
+				return err
+			}
+		}
+
+		user.SupervisorID = ""
+		return nil
+	})
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear supervisor: %w", err)
+	}
+	return &user, nil
+}
+
+// RoleChange reports what ChangeRole reconciled besides the role itself
+type RoleChange struct {
+	User              *models.User
+	OldRole           models.UserRole
+	DetachedOperators []string // Operators whose supervisor_id pointed at a demoted supervisor
+	FormerSupervisor  string   // Supervisor a newly promoted supervisor was detached from
+}
+
+// ChangeRole sets a user's role and reconciles the supervisor hierarchy in one
+// transaction. Demoting a supervisor clears their managed_operators and detaches each
+// operator; promoting to supervisor detaches the user from their own supervisor. The
+// last admin of an organization, or the last super admin, cannot be demoted
+// (ErrLastAdmin).
+func (db *FirestoreDB) ChangeRole(userID string, role models.UserRole) (*RoleChange, error) {
+	// Supervisors and operators are updated too, so evict everyone
+	defer db.users.invalidate()
+	userRef := db.client.Collection("users").Doc(userID)
+
+	var change *RoleChange
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		change = &RoleChange{}
+
+		doc, err := tx.Get(userRef)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+		if err != nil {
+			return err
+		}
+		var user models.User
+		if err := doc.DataTo(&user); err != nil {
+			return fmt.Errorf("failed to parse user %s: %w", userID, err)
+		}
+		if !db.inScope(user.OrgID) {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+		change.User = &user
+		change.OldRole = user.Role
+		if user.Role == role {
+			return nil
+		}
+
+		if user.IsAdmin() {
+			// org_id is omitted for single-tenant users, so it is matched here rather
+			// than in the query. Admins are few, so reading all of them is cheap.
+			admins, err := tx.Documents(db.client.Collection("users").Where("role", "==", user.Role)).GetAll()
+			if err != nil {
+				return err
+			}
+			others := 0
+			for _, adminDoc := range admins {
+				var admin models.User
+				if err := adminDoc.DataTo(&admin); err != nil {
+					return fmt.Errorf("failed to parse user %s: %w", adminDoc.Ref.ID, err)
+				}
+				if adminDoc.Ref.ID != userID && admin.OrgID == user.OrgID {
+					others++
+				}
+			}
+			if others == 0 {
+				return ErrLastAdmin
+			}
+		}
+
+		demoted := user.Role == models.RoleSupervisor
+		promoted := role == models.RoleSupervisor && user.SupervisorID != ""
+
+		// Read everything the cascade touches before any write, as transactions require
+		var operatorDocs []*firestore.DocumentSnapshot
+		if demoted && len(user.ManagedOperators) > 0 {
+			refs := make([]*firestore.DocumentRef, len(user.ManagedOperators))
+			for i, opID := range user.ManagedOperators {
+				refs[i] = db.client.Collection("users").Doc(opID)
+			}
+			if operatorDocs, err = tx.GetAll(refs); err != nil {
+				return err
+			}
+		}
+		var supervisorDoc *firestore.DocumentSnapshot
+		if promoted {
+			supervisorDoc, err = tx.Get(db.client.Collection("users").Doc(user.SupervisorID))
+			if err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+		}
+
+		updates := []firestore.Update{{Path: "role", Value: role}}
+		if demoted {
+			for _, opDoc := range operatorDocs {
+				if !opDoc.Exists() {
+					continue
+				}
+				var operator models.User
+				if err := opDoc.DataTo(&operator); err != nil {
+					return fmt.Errorf("failed to parse operator %s: %w", opDoc.Ref.ID, err)
+				}
+				// Only detach operators that still point at this supervisor
+				if operator.SupervisorID != userID {
+					continue
+				}
+				if err := tx.Update(opDoc.Ref, []firestore.Update{
+					{Path: "supervisor_id", Value: firestore.Delete},
+				}); err != nil {
+					return err
+				}
+				change.DetachedOperators = append(change.DetachedOperators, opDoc.Ref.ID)
+			}
+			updates = append(updates, firestore.Update{Path: "managed_operators", Value: firestore.Delete})
+			user.ManagedOperators = nil
+		}
+		if promoted {
+			// The supervisor may already have been deleted
+			if supervisorDoc != nil && supervisorDoc.Exists() {
+				var supervisor models.User
+				if err := supervisorDoc.DataTo(&supervisor); err != nil {
+					return fmt.Errorf("failed to parse supervisor %s: %w", user.SupervisorID, err)
+				}
+				managed := []string{}
+				for _, opID := range supervisor.ManagedOperators {
+					if opID != userID {
+						managed = append(managed, opID)
+					}
+				}
+				if err := tx.Update(supervisorDoc.Ref, []firestore.Update{
+					{Path: "managed_operators", Value: managed},
+				}); err != nil {
+					return err
+				}
+			}
+			updates = append(updates, firestore.Update{Path: "supervisor_id", Value: firestore.Delete})
+			change.FormerSupervisor = user.SupervisorID
+			user.SupervisorID = ""
+		}
+
+		if err := tx.Update(userRef, updates); err != nil {
+			return err
+		}
+		user.Role = role
+		return nil
+	})
+	if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrLastAdmin) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change role: %w", err)
+	}
+	return change, nil
+}
+
+// --- Checkpoint Operations ---
+
+// CreateCheckpoint creates a new checkpoint in Firestore, failing with ErrCheckpointExists
+// instead of overwriting a checkpoint with the same ID in any organization
+func (db *FirestoreDB) CreateCheckpoint(checkpoint *models.Checkpoint) error {
+	_, err := db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID).Create(db.ctx, checkpoint)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrCheckpointExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	db.checkpoints.invalidate()
+	return nil
+}
+
+// MaxCreateCheckpoints is the most checkpoints CreateCheckpoints writes at once; Firestore
+// transactions are limited to 500 writes
+const MaxCreateCheckpoints = 500
+
+// CreateCheckpoints creates the checkpoints whose IDs are free in every organization. The
+// check and the writes run in one transaction, so a checkpoint created concurrently is
+// never overwritten, and either all free checkpoints are written or none are. It returns
+// the IDs that were already taken; those checkpoints are not written.
+func (db *FirestoreDB) CreateCheckpoints(checkpoints []models.Checkpoint) (map[string]bool, error) {
+	if len(checkpoints) > MaxCreateCheckpoints {
+		return nil, fmt.Errorf("cannot create more than %d checkpoints at once", MaxCreateCheckpoints)
+	}
+
+	refs := make([]*firestore.DocumentRef, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		refs[i] = db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID)
+	}
+	var taken map[string]bool
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+		taken = make(map[string]bool)
+		for _, doc := range docs {
+			if doc.Exists() {
+				taken[doc.Ref.ID] = true
+			}
+		}
+		for i := range checkpoints {
+			if taken[checkpoints[i].CheckpointID] {
+				continue
+			}
+			if err := tx.Create(refs[i], checkpoints[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoints: %w", err)
+	}
+	if len(taken) < len(checkpoints) {
+		db.checkpoints.invalidate()
+	}
+	return taken, nil
+}
+
+// ExistingCheckpointIDs reports which of the IDs already exist in any organization,
+// reading Firestore directly rather than the checkpoint cache
+func (db *FirestoreDB) ExistingCheckpointIDs(checkpointIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(checkpointIDs) == 0 {
+		return existing, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, len(checkpointIDs))
+	for i, id := range checkpointIDs {
+		refs[i] = db.client.Collection("checkpoints").Doc(id)
+	}
+
+	docs, err := db.client.GetAll(db.ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up checkpoints: %w", err)
+	}
+
+	for _, doc := range docs {
+		if doc.Exists() {
+			existing[doc.Ref.ID] = true
+		}
+	}
+	return existing, nil
+}
+
+// GetCheckpoint retrieves a checkpoint by ID, from the checkpoint cache when possible.
+// It returns ErrNotFound if the checkpoint doesn't exist.
+func (db *FirestoreDB) GetCheckpoint(checkpointID string) (*models.Checkpoint, error) {
+	if checkpoints, ok := db.checkpoints.get(); ok {
+		for _, checkpoint := range checkpoints {
+			if checkpoint.CheckpointID == checkpointID && db.inScope(checkpoint.OrgID) {
+				return &checkpoint, nil
+			}
+		}
+		// Fall through: the checkpoint may have been created by another instance
+	}
+
+	doc, err := db.client.Collection("checkpoints").Doc(checkpointID).Get(db.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("checkpoint %w: %s", ErrNotFound, checkpointID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	var checkpoint models.Checkpoint
+	if err := doc.DataTo(&checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	if !db.inScope(checkpoint.OrgID) {
+		return nil, fmt.Errorf("checkpoint %w: %s", ErrNotFound, checkpointID)
+	}
+
+	return &checkpoint, nil
+}
+
+// GetAllCheckpoints retrieves all checkpoints in checkpoint ID order, from the checkpoint
+// cache when enabled
+func (db *FirestoreDB) GetAllCheckpoints() ([]models.Checkpoint, error) {
+	if !db.checkpoints.enabled() {
+		return db.loadCheckpoints(db.scopedQuery("checkpoints"))
+	}
+
+	all, err := db.cachedCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := []models.Checkpoint{}
+	for _, checkpoint := range all {
+		if db.inScope(checkpoint.OrgID) {
+			checkpoints = append(checkpoints, checkpoint)
+		}
+	}
+	return checkpoints, nil
+}
+
+// loadCheckpoints reads the checkpoints matched by query from Firestore in checkpoint ID
+// order, which the cache keeps
+func (db *FirestoreDB) loadCheckpoints(query firestore.Query) ([]models.Checkpoint, error) {
+	iter := query.OrderBy(firestore.DocumentID, firestore.Asc).Documents(db.ctx)
+	defer iter.Stop()
+
+	var checkpoints []models.Checkpoint
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, queryError("failed to iterate checkpoints", err, nil)
+		}
+
+		var checkpoint models.Checkpoint
+		if err := doc.DataTo(&checkpoint); err != nil {
+			log.Printf("Warning: failed to parse checkpoint %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, nil
+}
+
+// UpdateCheckpoint updates an existing checkpoint
+func (db *FirestoreDB) UpdateCheckpoint(checkpoint *models.Checkpoint) error {
+	_, err := db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID).Set(db.ctx, checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to update checkpoint: %w", err)
+	}
+	db.checkpoints.invalidate()
 	return nil
 }
 
+// Outcomes of UpsertCheckpoint
+const (
+	UpsertCreated   = "created"
+	UpsertUpdated   = "updated"
+	UpsertUnchanged = "unchanged"
+)
+
+// UpsertCheckpoint creates the checkpoint when its ID is free and otherwise replaces its
+// name, location and allowed entry types, deciding in a transaction so concurrent calls
+// can't both create it. A stored checkpoint that already matches is not written. It
+// returns the outcome and the checkpoint as stored.
+func (db *FirestoreDB) UpsertCheckpoint(checkpoint *models.Checkpoint) (string, *models.Checkpoint, error) {
+	ref := db.client.Collection("checkpoints").Doc(checkpoint.CheckpointID)
+	var outcome string
+	var result models.Checkpoint
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			outcome, result = UpsertCreated, *checkpoint
+			return tx.Create(ref, &result)
+		}
+		if err != nil {
+			return err
+		}
+		if err := doc.DataTo(&result); err != nil {
+			return fmt.Errorf("failed to parse checkpoint: %w", err)
+		}
+		// Checkpoint IDs are document IDs, so they are unique across organizations. An
+		// unscoped caller may not take over another organization's checkpoint either.
+		if result.OrgID != checkpoint.OrgID {
+			return ErrCheckpointIDTaken
+		}
+
+		if result.Name == checkpoint.Name && result.Location == checkpoint.Location &&
+			slices.Equal(result.AllowedEntryTypes, checkpoint.AllowedEntryTypes) {
+			outcome = UpsertUnchanged
+			return nil
+		}
+		outcome = UpsertUpdated
+		result.Name = checkpoint.Name
+		result.Location = checkpoint.Location
+		result.AllowedEntryTypes = checkpoint.AllowedEntryTypes
+		return tx.Set(ref, &result)
+	})
+	if errors.Is(err, ErrCheckpointIDTaken) {
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to upsert checkpoint: %w", err)
+	}
+	if outcome != UpsertUnchanged {
+		db.checkpoints.invalidate()
+	}
+	return outcome, &result, nil
+}
+
 // DeleteCheckpoint deletes a checkpoint
 func (db *FirestoreDB) DeleteCheckpoint(checkpointID string) error {
 	_, err := db.client.Collection("checkpoints").Doc(checkpointID).Delete(db.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to delete checkpoint: %w", err)
 	}
+	db.checkpoints.invalidate()
 	return nil
 }
 
 // --- Password Operations ---
 
-// StorePasswordHash stores a password hash for a user
+// StorePasswordHash stores a password hash for a user and stamps password_changed_at on
+// the user document, which revokes every token issued before the change.
+// The user document must already exist.
 func (db *FirestoreDB) StorePasswordHash(userID, passwordHash string) error {
-	_, err := db.client.Collection("passwords").Doc(userID).Set(db.ctx, map[string]interface{}{
+	defer db.users.invalidate(userID)
+	now := models.Now()
+	batch := db.client.Batch()
+	batch.Set(db.client.Collection("passwords").Doc(userID), map[string]interface{}{
 		"user_id":       userID,
 		"password_hash": passwordHash,
-		"updated_at":    time.Now(),
+		"updated_at":    now,
 	})
-	if err != nil {
+	batch.Update(db.client.Collection("users").Doc(userID), []firestore.Update{
+		{Path: "password_changed_at", Value: now},
+	})
+	if _, err := batch.Commit(db.ctx); err != nil {
 		return fmt.Errorf("failed to store password hash: %w", err)
 	}
 	return nil
@@ -376,3 +1964,303 @@ func (db *FirestoreDB) GetPasswordHash(userID string) (string, error) {
 
 	return "", fmt.Errorf("password hash not found for user: %s", userID)
 }
+
+// --- Refresh Session Operations ---
+// Together these implement auth.TokenStore.
+// Configure a Firestore TTL policy on refresh_sessions.expires_at to clean up expired sessions.
+
+// SaveRefreshSession stores a newly issued refresh session
+func (db *FirestoreDB) SaveRefreshSession(session *models.RefreshSession) error {
+	session.IssuedAt, session.ExpiresAt = session.IssuedAt.UTC(), session.ExpiresAt.UTC()
+	_, err := db.client.Collection("refresh_sessions").Doc(session.SessionID).Set(db.ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to save refresh session: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshSession retrieves a refresh session, returning auth.ErrSessionNotFound if it doesn't exist
+func (db *FirestoreDB) GetRefreshSession(sessionID string) (*models.RefreshSession, error) {
+	doc, err := db.client.Collection("refresh_sessions").Doc(sessionID).Get(db.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, auth.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh session: %w", err)
+	}
+
+	var session models.RefreshSession
+	if err := doc.DataTo(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse refresh session: %w", err)
+	}
+	return &session, nil
+}
+
+// RevokeRefreshSession revokes a single refresh session
+func (db *FirestoreDB) RevokeRefreshSession(sessionID string) error {
+	_, err := db.client.Collection("refresh_sessions").Doc(sessionID).Update(db.ctx, []firestore.Update{
+		{Path: "revoked", Value: true},
+	})
+	if status.Code(err) == codes.NotFound {
+		return auth.ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh session: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserRefreshSessions revokes every active refresh session of a user
+func (db *FirestoreDB) RevokeUserRefreshSessions(userID string) error {
+	defer db.users.invalidate(userID)
+	for {
+		docs, err := db.client.Collection("refresh_sessions").
+			Where("user_id", "==", userID).
+			Where("revoked", "==", false).
+			Limit(reassignBatchSize).
+			Documents(db.ctx).
+			GetAll()
+		if err != nil {
+			return queryError("failed to iterate refresh sessions", err, nil)
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		batch := db.client.Batch()
+		for _, doc := range docs {
+			batch.Update(doc.Ref, []firestore.Update{{Path: "revoked", Value: true}})
+		}
+		if _, err := batch.Commit(db.ctx); err != nil {
+			return fmt.Errorf("failed to revoke refresh sessions: %w", err)
+		}
+	}
+}
+
+// --- Audit Log Operations ---
+
+// AuditLogFilter narrows an audit log query. Zero values are ignored.
+type AuditLogFilter struct {
+	UserID string
+	Action models.AuditAction
+	From   time.Time
+	To     time.Time
+}
+
+// auditLogQuery builds the query for a filter, newest first, and the composite index it needs
+func (db *FirestoreDB) auditLogQuery(filter AuditLogFilter) (firestore.Query, *Index) {
+	query := db.scopedQuery("audit_logs")
+	eqFields := db.scopedFields()
+
+	if filter.UserID != "" {
+		query = query.Where("user_id", "==", filter.UserID)
+		eqFields = append(eqFields, "user_id")
+	}
+	if filter.Action != "" {
+		query = query.Where("action", "==", filter.Action)
+		eqFields = append(eqFields, "action")
+	}
+	// Timestamps are stored as RFC3339 UTC strings, which sort chronologically
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp", ">=", models.FormatTimestamp(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp", "<=", models.FormatTimestamp(filter.To))
+	}
+
+	return query.OrderBy("timestamp", firestore.Desc), lookupIndex("audit_logs", eqFields, "timestamp", "DESCENDING")
+}
+
+// StreamAuditLogs calls fn for every audit log matching the filter, newest first,
+// without loading the whole result set into memory. Iteration stops at the first error from fn.
+func (db *FirestoreDB) StreamAuditLogs(filter AuditLogFilter, fn func(*models.AuditLog) error) error {
+	query, index := db.auditLogQuery(filter)
+	iter := query.Documents(db.ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return queryError("failed to iterate audit logs", err, index)
+		}
+
+		var auditLog models.AuditLog
+		if err := doc.DataTo(&auditLog); err != nil {
+			log.Printf("Warning: failed to parse audit log %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if err := fn(&auditLog); err != nil {
+			return err
+		}
+	}
+}
+
+// ErrInvalidCursor is returned when a page cursor does not refer to a known document
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListAuditLogs returns up to limit audit logs matching the filter, newest first,
+// starting after the log with ID startAfter (empty for the first page). The returned
+// next is the startAfter of the following page, or empty on the last page.
+func (db *FirestoreDB) ListAuditLogs(filter AuditLogFilter, limit int, startAfter string) ([]models.AuditLog, string, error) {
+	query, index := db.auditLogQuery(filter)
+	if startAfter != "" {
+		doc, err := db.client.Collection("audit_logs").Doc(startAfter).Get(db.ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, "", ErrInvalidCursor
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to resolve audit log cursor: %w", err)
+		}
+		query = query.StartAfter(doc)
+	}
+
+	// Fetch one extra log to learn whether another page follows
+	docs, err := query.Limit(limit + 1).Documents(db.ctx).GetAll()
+	if err != nil {
+		return nil, "", queryError("failed to list audit logs", err, index)
+	}
+
+	next := ""
+	if len(docs) > limit {
+		docs = docs[:limit]
+		next = docs[limit-1].Ref.ID
+	}
+
+	auditLogs := make([]models.AuditLog, 0, len(docs))
+	for _, doc := range docs {
+		var auditLog models.AuditLog
+		if err := doc.DataTo(&auditLog); err != nil {
+			log.Printf("Warning: failed to parse audit log %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+	return auditLogs, next, nil
+}
+
+// CountAuditLogs counts the audit logs matching the filter without reading them
+func (db *FirestoreDB) CountAuditLogs(filter AuditLogFilter) (int, error) {
+	query, index := db.auditLogQuery(filter)
+	return countQuery(db.ctx, query, "failed to count audit logs", index)
+}
+
+// countQuery counts a query's matches with an aggregation query, which is billed per
+// batch of index entries instead of per document
+func countQuery(ctx context.Context, query firestore.Query, op string, index *Index) (int, error) {
+	result, err := query.NewAggregationQuery().WithCount("total").Get(ctx)
+	if err != nil {
+		return 0, queryError(op, err, index)
+	}
+
+	total, ok := result["total"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("%s: missing count in aggregation result", op)
+	}
+	return int(total.GetIntegerValue()), nil
+}
+
+// GetAuditLogsForRecord returns the audit logs of events that changed the entry, oldest
+// first. Entries touch few events, so they are sorted here instead of needing an index.
+func (db *FirestoreDB) GetAuditLogsForRecord(recordID string) ([]models.AuditLog, error) {
+	docs, err := db.scopedQuery("audit_logs").Where("record_ids", "array-contains", recordID).Documents(db.ctx).GetAll()
+	if err != nil {
+		return nil, queryError("failed to get audit logs of entry", err, nil)
+	}
+
+	auditLogs := make([]models.AuditLog, 0, len(docs))
+	for _, doc := range docs {
+		var auditLog models.AuditLog
+		if err := doc.DataTo(&auditLog); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log %s: %w", doc.Ref.ID, err)
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+	// RFC3339 UTC timestamps sort chronologically as strings
+	slices.SortStableFunc(auditLogs, func(a, b models.AuditLog) int {
+		return strings.Compare(a.Timestamp, b.Timestamp)
+	})
+	return auditLogs, nil
+}
+
+// CreateAuditLog stores an audit log entry, generating its ID if absent
+func (db *FirestoreDB) CreateAuditLog(auditLog *models.AuditLog) error {
+	// Still record unknown actions so no event is lost, but flag them so they get registered
+	if !auditLog.Action.IsValid() {
+		log.Printf("Warning: audit log with unregistered action %q; add it to models.AuditAction", auditLog.Action)
+	}
+	// Audit timestamps are strings filtered by range, which only works if all are in UTC
+	if t, err := time.Parse(time.RFC3339, auditLog.Timestamp); err == nil {
+		auditLog.Timestamp = models.FormatTimestamp(t)
+	}
+
+	ref := db.client.Collection("audit_logs").NewDoc()
+	if auditLog.LogID == "" {
+		auditLog.LogID = ref.ID
+	} else {
+		ref = db.client.Collection("audit_logs").Doc(auditLog.LogID)
+	}
+
+	_, err := ref.Set(db.ctx, auditLog)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// syncCursorRef returns the document holding a device's sync cursor
+func (db *FirestoreDB) syncCursorRef(userID, deviceID string) *firestore.DocumentRef {
+	return db.client.Collection("sync_cursors").Doc(userID + ":" + deviceID)
+}
+
+// GetSyncCursor retrieves a device's sync cursor, returning ErrSyncCursorNotFound if the
+// device has never acknowledged a pull
+func (db *FirestoreDB) GetSyncCursor(userID, deviceID string) (*models.SyncCursor, error) {
+	doc, err := db.syncCursorRef(userID, deviceID).Get(db.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrSyncCursorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync cursor: %w", err)
+	}
+
+	var cursor models.SyncCursor
+	if err := doc.DataTo(&cursor); err != nil {
+		return nil, fmt.Errorf("failed to parse sync cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// AckSyncCursor advances a device's sync cursor to through. A late or repeated
+// acknowledgment of an older pull never moves the cursor back. It returns the stored cursor.
+func (db *FirestoreDB) AckSyncCursor(userID, deviceID string, through time.Time) (*models.SyncCursor, error) {
+	ref := db.syncCursorRef(userID, deviceID)
+	through = through.UTC()
+
+	var cursor models.SyncCursor
+	err := db.client.RunTransaction(db.ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cursor = models.SyncCursor{UserID: userID, DeviceID: deviceID}
+
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&cursor); err != nil {
+				return fmt.Errorf("failed to parse sync cursor: %w", err)
+			}
+		}
+
+		if through.After(cursor.AckedThrough) {
+			cursor.AckedThrough = through
+		}
+		cursor.AckedAt = models.Now()
+		return tx.Set(ref, cursor)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge sync: %w", err)
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"errors"
+	"gatekeeper/models"
+	"testing"
+)
+
+func TestRekeyedRecordIDIsStable(t *testing.T) {
+	first := rekeyedRecordID("rec-1", "user-1")
+	if again := rekeyedRecordID("rec-1", "user-1"); again != first {
+		t.Errorf("re-keying the same entry gave %s and %s", first, again)
+	}
+	if first == "rec-1" {
+		t.Error("re-keyed record ID equals the original")
+	}
+	if other := rekeyedRecordID("rec-1", "user-2"); other == first {
+		t.Error("entries of different users were re-keyed to the same record ID")
+	}
+}
+
+func TestUserLookupsStayInOrg(t *testing.T) {
+	east := &models.User{UserID: "user-1", Username: "east_op", OrgID: "org-1"}
+	west := &models.User{UserID: "user-2", Username: "west_op", OrgID: "org-2"}
+
+	view := &FirestoreDB{orgID: "org-1"}
+	if user, err := view.scopedUser(east, east.Username); err != nil || user != east {
+		t.Errorf("lookup of a user in the view's org = %v, %v", user, err)
+	}
+	if _, err := view.scopedUser(west, west.Username); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("lookup of another org's user error = %v, want ErrUserNotFound", err)
+	}
+
+	unscoped := &FirestoreDB{}
+	if _, err := unscoped.scopedUser(west, west.Username); err != nil {
+		t.Errorf("unscoped lookup error = %v, want none", err)
+	}
+}
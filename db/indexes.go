@@ -0,0 +1,166 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrIndexMissing is returned when a query needs a composite index that hasn't been created
+var ErrIndexMissing = errors.New("firestore composite index missing")
+
+// IndexField is a single field of a composite index, in firestore.indexes.json format
+type IndexField struct {
+	FieldPath string `json:"fieldPath"`
+	Order     string `json:"order"` // ASCENDING or DESCENDING
+}
+
+// Index describes a composite index that one of our queries depends on
+type Index struct {
+	CollectionGroup string       `json:"collectionGroup"`
+	QueryScope      string       `json:"queryScope"`
+	Fields          []IndexField `json:"fields"`
+}
+
+func (i *Index) String() string {
+	fields := make([]string, 0, len(i.Fields))
+	for _, f := range i.Fields {
+		fields = append(fields, fmt.Sprintf("%s %s", f.FieldPath, f.Order))
+	}
+	return fmt.Sprintf("%s(%s)", i.CollectionGroup, strings.Join(fields, ", "))
+}
+
+// requiredIndexes lists every composite index used by queries in this package.
+// Register an index in init whenever a query combines equality filters with an
+// OrderBy or range filter on another field. Ordering by document ID after equality
+// filters, as the unpaginated list queries do, is served by single-field indexes.
+var requiredIndexes = []*Index{}
+
+func init() {
+	// Audit log queries filter on any combination of org, user and action, newest first
+	for _, eqFields := range [][]string{
+		{"org_id"}, {"user_id"}, {"action"},
+		{"org_id", "user_id"}, {"org_id", "action"}, {"user_id", "action"},
+		{"org_id", "user_id", "action"},
+	} {
+		registerIndex("audit_logs", eqFields, "timestamp", "DESCENDING")
+	}
+
+	// Admin stats count an organization's recently active users
+	registerIndex("users", []string{"org_id"}, "last_login", "ASCENDING")
+
+	// Delta sync within an organization
+	registerIndex("entries", []string{"org_id"}, "updated_at", "ASCENDING")
+
+	// Date-range reads and retention within an organization
+	registerIndex("entries", []string{"org_id"}, "created_at", "ASCENDING")
+
+	// Bulk delete by checkpoint and date range
+	registerIndex("entries", []string{"checkpoint_id"}, "created_at", "ASCENDING")
+	registerIndex("entries", []string{"org_id", "checkpoint_id"}, "created_at", "ASCENDING")
+
+	// Entry counts per operator over a date range
+	registerIndex("entries", []string{"logging_user_id"}, "created_at", "ASCENDING")
+	registerIndex("entries", []string{"org_id", "logging_user_id"}, "created_at", "ASCENDING")
+
+	// Operators viewing their own entries at one checkpoint
+	registerIndex("entries", []string{"checkpoint_id", "logging_user_id"}, "created_at", "ASCENDING")
+	registerIndex("entries", []string{"org_id", "checkpoint_id", "logging_user_id"}, "created_at", "ASCENDING")
+
+	// Supervisors paging through one gate's entries, newest first
+	registerIndex("entries", []string{"checkpoint_id"}, "created_at", "DESCENDING")
+	registerIndex("entries", []string{"org_id", "checkpoint_id"}, "created_at", "DESCENDING")
+
+	// Views, exports and stats hide tombstones with an equality filter on status, so each
+	// filtered query above also needs a variant ending in status
+	for _, eqFields := range [][]string{
+		{}, {"org_id"},
+		{"checkpoint_id"}, {"org_id", "checkpoint_id"},
+		{"logging_user_id"}, {"org_id", "logging_user_id"},
+		{"checkpoint_id", "logging_user_id"}, {"org_id", "checkpoint_id", "logging_user_id"},
+	} {
+		registerIndex("entries", append(eqFields, "status"), "created_at", "ASCENDING")
+	}
+	registerIndex("entries", []string{"checkpoint_id", "status"}, "created_at", "DESCENDING")
+	registerIndex("entries", []string{"org_id", "checkpoint_id", "status"}, "created_at", "DESCENDING")
+
+	// Operators paging through their own entries, newest first, optionally at one
+	// checkpoint, of one type and with tombstones
+	for _, eqFields := range [][]string{
+		{"logging_user_id"}, {"logging_user_id", "entry_type"},
+		{"checkpoint_id", "logging_user_id"}, {"checkpoint_id", "logging_user_id", "entry_type"},
+	} {
+		for _, scoped := range [][]string{nil, {"org_id"}} {
+			fields := append(append([]string{}, scoped...), eqFields...)
+			registerIndex("entries", fields, "created_at", "DESCENDING")
+			registerIndex("entries", append(fields, "status"), "created_at", "DESCENDING")
+
+			// The page total counts the same filter; the other filters already have ascending indexes
+			if slices.Contains(eqFields, "entry_type") {
+				registerIndex("entries", fields, "created_at", "ASCENDING")
+				registerIndex("entries", append(fields, "status"), "created_at", "ASCENDING")
+			}
+		}
+	}
+}
+
+// newIndex builds the composite index for equality filters on eqFields plus an order on orderField
+func newIndex(collection string, eqFields []string, orderField, order string) *Index {
+	fields := make([]IndexField, 0, len(eqFields)+1)
+	for _, field := range eqFields {
+		fields = append(fields, IndexField{FieldPath: field, Order: "ASCENDING"})
+	}
+	fields = append(fields, IndexField{FieldPath: orderField, Order: order})
+	return &Index{CollectionGroup: collection, QueryScope: "COLLECTION", Fields: fields}
+}
+
+// registerIndex adds a composite index to the registry
+func registerIndex(collection string, eqFields []string, orderField, order string) {
+	requiredIndexes = append(requiredIndexes, newIndex(collection, eqFields, orderField, order))
+}
+
+// lookupIndex returns the index a query needs, or nil when no composite index is required
+func lookupIndex(collection string, eqFields []string, orderField, order string) *Index {
+	if len(eqFields) == 0 {
+		return nil
+	}
+
+	wanted := newIndex(collection, eqFields, orderField, order).String()
+	for _, index := range requiredIndexes {
+		if index.String() == wanted {
+			return index
+		}
+	}
+
+	log.Printf("Warning: query on %s is not in the index registry", wanted)
+	return newIndex(collection, eqFields, orderField, order)
+}
+
+// IndexesJSON renders all required composite indexes as a firestore.indexes.json document
+func IndexesJSON() ([]byte, error) {
+	return json.MarshalIndent(map[string]interface{}{
+		"indexes":        requiredIndexes,
+		"fieldOverrides": []interface{}{},
+	}, "", "  ")
+}
+
+// queryError wraps a query failure. A FailedPrecondition caused by a missing index is
+// logged with the index the query needs and returned as ErrIndexMissing.
+func queryError(op string, err error, index *Index) error {
+	if status.Code(err) == codes.FailedPrecondition && strings.Contains(status.Convert(err).Message(), "index") {
+		needed := "unknown (see Firestore message)"
+		if index != nil {
+			needed = index.String()
+		}
+		log.Printf("❌ %s: Firestore index missing. Required index: %s. Run the server with -print-indexes to generate firestore.indexes.json. Firestore said: %s",
+			op, needed, status.Convert(err).Message())
+		return fmt.Errorf("%s: %w (%s)", op, ErrIndexMissing, needed)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
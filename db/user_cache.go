@@ -0,0 +1,134 @@
+package db
+
+import (
+	"fmt"
+	"gatekeeper/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultUserCacheTTL is how long users loaded for authentication are served from memory
+const DefaultUserCacheTTL = 5 * time.Second
+
+// userCache holds users loaded by GetCachedUser, which authentication calls on every
+// request. User writes made through this package evict the users they touch, so role
+// changes, password changes and deletions take effect on this instance at once; the
+// activity timestamps written on nearly every request are updated in place instead.
+// Writes made by other instances become visible once the ttl passes.
+type userCache struct {
+	mu         sync.RWMutex
+	ttl        time.Duration // 0 disables caching
+	users      map[string]cachedUser
+	generation uint64 // Bumped by every eviction
+}
+
+type cachedUser struct {
+	user     models.User
+	loadedAt time.Time
+}
+
+// get returns a copy of the cached user, or false when the cache is disabled or stale
+func (c *userCache) get(userID string) (*models.User, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, ok := c.users[userID]
+	if c.ttl <= 0 || !ok || time.Since(cached.loadedAt) > c.ttl {
+		return nil, false
+	}
+	return cloneUser(&cached.user), true
+}
+
+// currentGeneration returns the generation to pass to set for users loaded from now on
+func (c *userCache) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// set caches a user loaded while the cache was at generation. A load that an eviction
+// overtook may predate the write behind it, such as a password change, so it is not
+// cached.
+func (c *userCache) set(user *models.User, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 || generation != c.generation {
+		return
+	}
+	// Drop stale users so deleted accounts don't accumulate
+	for userID, cached := range c.users {
+		if time.Since(cached.loadedAt) > c.ttl {
+			delete(c.users, userID)
+		}
+	}
+	c.users[user.UserID] = cachedUser{user: *cloneUser(user), loadedAt: time.Now()}
+}
+
+// update applies change to the cached copy of a user, if there is one, without
+// resetting its age
+func (c *userCache) update(userID string, change func(user *models.User)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.users[userID]; ok {
+		change(&cached.user)
+		c.users[userID] = cached
+	}
+}
+
+// invalidate evicts the given users, or every user when none are given
+func (c *userCache) invalidate(userIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if len(userIDs) == 0 {
+		clear(c.users)
+		return
+	}
+	for _, userID := range userIDs {
+		delete(c.users, userID)
+	}
+}
+
+// cloneUser copies a user so callers can't modify the cached one through shared slices
+func cloneUser(user *models.User) *models.User {
+	clone := *user
+	clone.AllowedCheckpoints = slices.Clone(user.AllowedCheckpoints)
+	clone.ManagedOperators = slices.Clone(user.ManagedOperators)
+	clone.Permissions = slices.Clone(user.Permissions)
+	return &clone
+}
+
+// SetUserCacheTTL sets how long users loaded by GetCachedUser are cached; 0 disables the cache
+func (db *FirestoreDB) SetUserCacheTTL(ttl time.Duration) {
+	db.users.mu.Lock()
+	defer db.users.mu.Unlock()
+
+	db.users.ttl = ttl
+	clear(db.users.users)
+	db.users.generation++
+}
+
+// GetCachedUser is GetUser served from memory for up to the user cache TTL. It is meant
+// for authentication, which reads the user on every request; handlers that modify the
+// user should read it with GetUser.
+func (db *FirestoreDB) GetCachedUser(userID string) (*models.User, error) {
+	if user, ok := db.users.get(userID); ok {
+		if !db.inScope(user.OrgID) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+		return user, nil
+	}
+
+	generation := db.users.currentGeneration()
+	user, err := db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	db.users.set(user, generation)
+	return user, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"gatekeeper/models"
+	"testing"
+	"time"
+)
+
+func newTestUserCache() *userCache {
+	return &userCache{ttl: time.Minute, users: make(map[string]cachedUser)}
+}
+
+func TestUserCacheUpdateKeepsUserCached(t *testing.T) {
+	cache := newTestUserCache()
+	cache.set(&models.User{UserID: "user-1"}, cache.currentGeneration())
+
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	cache.update("user-1", func(user *models.User) { user.LastActivityAt = at })
+
+	user, ok := cache.get("user-1")
+	if !ok {
+		t.Fatal("updating a timestamp evicted the user")
+	}
+	if !user.LastActivityAt.Equal(at) {
+		t.Errorf("LastActivityAt = %s, want %s", user.LastActivityAt, at)
+	}
+}
+
+func TestUserCacheUpdateOfUncachedUser(t *testing.T) {
+	cache := newTestUserCache()
+	cache.update("user-1", func(user *models.User) { user.LastSyncAt = time.Now() })
+
+	if _, ok := cache.get("user-1"); ok {
+		t.Error("updating an uncached user cached it")
+	}
+}
+
+func TestUserCacheSetAfterEvictionIsDropped(t *testing.T) {
+	cache := newTestUserCache()
+
+	// A load starts, a password change evicts the user, then the stale load finishes
+	generation := cache.currentGeneration()
+	cache.invalidate("user-1")
+	cache.set(&models.User{UserID: "user-1"}, generation)
+
+	if _, ok := cache.get("user-1"); ok {
+		t.Error("a user loaded before an eviction was cached")
+	}
+}
@@ -0,0 +1,116 @@
+// Package exports uploads large exports to Cloud Storage so clients download them
+// from a signed URL instead of streaming them through the API.
+package exports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// DefaultURLTTL is how long a signed download URL stays valid
+const DefaultURLTTL = 15 * time.Minute
+
+// ErrCannotSign is returned when the credentials cannot produce signed URLs
+var ErrCannotSign = errors.New("credentials cannot sign URLs; use a service account key or grant iam.serviceAccounts.signBlob")
+
+// Options selects the bucket and the credentials used to write to and sign for it.
+// The credentials are the Firestore service account's.
+type Options struct {
+	Bucket          string
+	CredentialsPath string
+	CredentialsJSON string        // Takes precedence over CredentialsPath
+	URLTTL          time.Duration // Lifetime of signed download URLs; DefaultURLTTL when zero
+	WriteOnly       bool          // Skip the signing check for buckets that are only written to
+}
+
+// Uploader writes export files to a Cloud Storage bucket
+type Uploader struct {
+	client *storage.Client
+	bucket string
+	urlTTL time.Duration
+}
+
+// NewUploader creates a Cloud Storage client for the export bucket
+func NewUploader(ctx context.Context, opts Options) (*Uploader, error) {
+	opt := option.WithCredentialsFile(opts.CredentialsPath)
+	if opts.CredentialsJSON != "" {
+		opt = option.WithCredentialsJSON([]byte(opts.CredentialsJSON))
+	}
+
+	client, err := storage.NewClient(ctx, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing Cloud Storage client: %w", err)
+	}
+
+	urlTTL := opts.URLTTL
+	if urlTTL <= 0 {
+		urlTTL = DefaultURLTTL
+	}
+	u := &Uploader{
+		client: client,
+		bucket: opts.Bucket,
+		urlTTL: urlTTL,
+	}
+
+	if opts.WriteOnly {
+		return u, nil
+	}
+
+	// Fail at startup rather than on the first export if the credentials can't sign
+	if _, _, err := u.SignedURL("exports/.signing-check"); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	log.Printf("✅ Exports upload to bucket: %s (download links valid for %v)", opts.Bucket, urlTTL)
+	return u, nil
+}
+
+// Close closes the Cloud Storage client
+func (u *Uploader) Close() error {
+	return u.client.Close()
+}
+
+// Upload streams an object into the bucket. write produces the content; the object
+// only becomes visible if write and the final flush both succeed.
+func (u *Uploader) Upload(ctx context.Context, objectName, contentType string, write func(io.Writer) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := u.client.Bucket(u.bucket).Object(objectName).NewWriter(ctx)
+	w.ContentType = contentType
+
+	if err := write(w); err != nil {
+		// Cancelling before Close aborts the upload instead of committing a partial object
+		cancel()
+		w.Close()
+		return fmt.Errorf("failed to write export %s: %w", objectName, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload export %s: %w", objectName, err)
+	}
+	return nil
+}
+
+// SignedURL returns a time-limited V4 signed download URL for an uploaded object and
+// when it expires. It wraps ErrCannotSign when the credentials lack signing capability.
+func (u *Uploader) SignedURL(objectName string) (string, time.Time, error) {
+	expires := time.Now().Add(u.urlTTL)
+	url, err := u.client.Bucket(u.bucket).SignedURL(objectName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  http.MethodGet,
+		Expires: expires,
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: signing %s: %v", ErrCannotSign, objectName, err)
+	}
+	return url, expires, nil
+}
@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gatekeeper/auth"
 	"gatekeeper/db"
+	"gatekeeper/httputil"
 	"gatekeeper/middleware"
 	"gatekeeper/models"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
 type AdminHandler struct {
-	db *db.FirestoreDB
+	db       *db.FirestoreDB
+	lockouts LockoutSource
+	defaults UserDefaults
 }
 
 func NewAdminHandler(firestoreDB *db.FirestoreDB) *AdminHandler {
@@ -24,47 +32,107 @@ func NewAdminHandler(firestoreDB *db.FirestoreDB) *AdminHandler {
 
 // --- User Management ---
 
+// UserDefaults fills in fields a create-user request omits
+type UserDefaults struct {
+	Role               models.UserRole
+	AllowedCheckpoints []string // Applied to non-admin users only; admins see every checkpoint
+}
+
+// SetUserDefaults sets the role and checkpoints given to created users whose request
+// leaves them out. Values in the request always take precedence.
+func (h *AdminHandler) SetUserDefaults(defaults UserDefaults) {
+	h.defaults = defaults
+}
+
+// CreateUserRequest describes a new user. An omitted role or allowed_checkpoints is
+// taken from the configured user defaults; a value given here always wins, and an empty
+// allowed_checkpoints list means no checkpoints.
 type CreateUserRequest struct {
-	Username           string          `json:"username"`
-	Password           string          `json:"password"`
-	Role               models.UserRole `json:"role"`
-	AllowedCheckpoints []string        `json:"allowed_checkpoints"`
-	SupervisorID       string          `json:"supervisor_id,omitempty"`
+	Username           string              `json:"username"`
+	Password           string              `json:"password"`
+	Role               models.UserRole     `json:"role"`
+	AllowedCheckpoints []string            `json:"allowed_checkpoints"`
+	SupervisorID       string              `json:"supervisor_id,omitempty"`
+	OrgID              string              `json:"org_id,omitempty"` // Honored for super admins only
+	Permissions        []models.Permission `json:"permissions,omitempty"`
 }
 
 type UpdateUserRequest struct {
-	UserID             string          `json:"user_id"`
-	Role               models.UserRole `json:"role,omitempty"`
-	AllowedCheckpoints []string        `json:"allowed_checkpoints,omitempty"`
-	SupervisorID       string          `json:"supervisor_id,omitempty"`
+	UserID             string              `json:"user_id"`
+	Role               models.UserRole     `json:"role,omitempty"`
+	AllowedCheckpoints []string            `json:"allowed_checkpoints,omitempty"`
+	SupervisorID       string              `json:"supervisor_id,omitempty"`
+	Permissions        []models.Permission `json:"permissions,omitempty"` // Replaces the granted permissions; [] revokes them all
+}
+
+type SetUserCheckpointsRequest struct {
+	UserID             string   `json:"user_id"`
+	AllowedCheckpoints []string `json:"allowed_checkpoints"`
+}
+
+type UnassignSupervisorRequest struct {
+	UserID string `json:"user_id"`
 }
 
 type DeleteUserRequest struct {
 	UserID string `json:"user_id"`
 }
 
-// GetUsers returns all users
+// GetUsers returns a page of users in user ID order
 func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	users, err := h.db.GetAllUsers()
+	startAfter := ""
+	if params.Cursor != "" {
+		if startAfter, err = decodeKeyCursor(params.Cursor); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Only the requested page is read; the total comes from a count aggregation
+	store := scopedDB(h.db, adminUser)
+	users, next, err := store.ListUsers(params.Limit, startAfter)
 	if err != nil {
 		log.Printf("❌ Failed to get users: %v", err)
 		writeError(w, "Failed to retrieve users", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	total, err := store.CountUsers(db.UserFilter{})
+	if err != nil {
+		log.Printf("❌ Failed to count users: %v", err)
+		writeError(w, "Failed to retrieve users", http.StatusInternalServerError)
+		return
+	}
+
+	writePaginated(w, users, Pagination{
+		Total:      total,
+		Limit:      params.Limit,
+		Cursor:     params.Cursor,
+		NextCursor: encodeKeyCursor(next),
+	})
 }
 
 // CreateUser creates a new user
 func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -75,95 +143,296 @@ func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	problem, err := h.applyUserDefaults(scopedDB(h.db, adminUser), &req)
+	if err != nil {
+		log.Printf("❌ Failed to apply user defaults: %v", err)
+		writeError(w, "Failed to apply user defaults", http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		writeError(w, problem, http.StatusBadRequest)
 		return
 	}
 
 	// Validate input
-	if req.Username == "" || req.Password == "" {
-		writeError(w, "Username and password are required", http.StatusBadRequest)
+	if err := validateNewUser(req); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	// Validate password strength
-	if err := auth.ValidatePasswordStrength(req.Password); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+	if err := checkRoleGrant(adminUser, req.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	// Check if username already exists
-	existingUser, _ := h.db.GetUserByUsername(req.Username)
-	if existingUser != nil {
+	taken, err := h.usernameTaken(req.Username)
+	if err != nil {
+		log.Printf("❌ Failed to check username %s: %v", req.Username, err)
+		writeError(w, "Failed to check username", http.StatusInternalServerError)
+		return
+	}
+	if taken {
 		writeError(w, "Username already exists", http.StatusConflict)
 		return
 	}
 
-	// Generate user ID
-	userID := fmt.Sprintf("user-%s", req.Username)
+	user := newUserFromRequest(req, adminUser)
+	if err := storeNewUser(scopedDB(h.db, adminUser), user, req.Password); err != nil {
+		// The check above is only a fast path; creation itself claims the username atomically
+		if errors.Is(err, db.ErrUsernameTaken) {
+			writeError(w, "Username already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("❌ Failed to create user: %v", err)
+		writeError(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ User created by %s: %s (role: %s)", adminUser.Username, req.Username, req.Role)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionCreateUser, fmt.Sprintf("Admin '%s' created user '%s' with role '%s'", adminUser.Username, req.Username, req.Role))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// applyUserDefaults fills in the role and allowed checkpoints the request omits. Default
+// checkpoints must exist in the store's organization; when one doesn't, the returned
+// problem explains why the request can't rely on the defaults.
+func (h *AdminHandler) applyUserDefaults(store *db.FirestoreDB, req *CreateUserRequest) (string, error) {
+	if req.Role == "" {
+		req.Role = h.defaults.Role
+	}
+	if req.AllowedCheckpoints != nil || len(h.defaults.AllowedCheckpoints) == 0 {
+		return "", nil
+	}
+	if req.Role == models.RoleAdmin || req.Role == models.RoleSuperAdmin {
+		return "", nil
+	}
+
+	for _, checkpointID := range h.defaults.AllowedCheckpoints {
+		_, err := store.GetCheckpoint(checkpointID)
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Sprintf("Default checkpoint %s does not exist; set allowed_checkpoints explicitly", checkpointID), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	req.AllowedCheckpoints = slices.Clone(h.defaults.AllowedCheckpoints)
+	return "", nil
+}
+
+// validateNewUser checks the fields of a create-user request
+func validateNewUser(req CreateUserRequest) error {
+	if req.Username == "" || req.Password == "" {
+		return errors.New("Username and password are required")
+	}
+	if !req.Role.IsValid() {
+		return fmt.Errorf("invalid role: %q", req.Role)
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return err
+	}
+	return auth.ValidatePasswordStrength(req.Password)
+}
+
+// validatePermissions rejects permissions that are not known
+func validatePermissions(permissions []models.Permission) error {
+	for _, p := range permissions {
+		if !p.IsValid() {
+			return fmt.Errorf("invalid permission: %q", p)
+		}
+	}
+	return nil
+}
+
+// checkRoleGrant prevents organization admins from creating or promoting super admins
+func checkRoleGrant(adminUser *models.User, role models.UserRole) error {
+	if role == models.RoleSuperAdmin && adminUser.Role != models.RoleSuperAdmin {
+		return errors.New("Only super admins can grant the SUPER_ADMIN role")
+	}
+	return nil
+}
+
+// usernameTaken reports whether any user, in any organization, has the username
+func (h *AdminHandler) usernameTaken(username string) (bool, error) {
+	_, err := h.db.GetUserByUsername(username)
+	if errors.Is(err, db.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// newUserFromRequest builds the user document for a create-user request.
+// Users join the creating admin's organization; super admins may choose one.
+func newUserFromRequest(req CreateUserRequest, adminUser *models.User) *models.User {
+	orgID := adminUser.OrgID
+	if adminUser.Role == models.RoleSuperAdmin && req.OrgID != "" {
+		orgID = req.OrgID
+	}
 
-	// Create user
-	user := &models.User{
-		UserID:             userID,
+	return &models.User{
+		UserID:             fmt.Sprintf("user-%s", req.Username),
 		Username:           req.Username,
 		Role:               req.Role,
 		AllowedCheckpoints: req.AllowedCheckpoints,
 		SupervisorID:       req.SupervisorID,
-		LastLogin:          time.Now(),
+		LastLogin:          models.Now(),
+		OrgID:              orgID,
+		Permissions:        req.Permissions,
 	}
+}
 
-	if err := h.db.CreateUser(user); err != nil {
-		log.Printf("❌ Failed to create user: %v", err)
-		writeError(w, "Failed to create user", http.StatusInternalServerError)
-		return
+// storeNewUser writes the user, their password hash, and the supervisor linkage
+func storeNewUser(store *db.FirestoreDB, user *models.User, password string) error {
+	if err := store.CreateUser(user); err != nil {
+		return err
 	}
 
 	// Hash and store password
-	passwordHash, err := auth.HashPassword(req.Password)
+	passwordHash, err := auth.HashPassword(password)
 	if err != nil {
-		log.Printf("❌ Failed to hash password: %v", err)
-		writeError(w, "Failed to hash password", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	if err := h.db.StorePasswordHash(userID, passwordHash); err != nil {
-		log.Printf("❌ Failed to store password: %v", err)
-		writeError(w, "Failed to store password", http.StatusInternalServerError)
-		return
+	if err := store.StorePasswordHash(user.UserID, passwordHash); err != nil {
+		return err
 	}
 
 	// If this is a gate operator with a supervisor, update the supervisor's managed operators
-	if req.Role == models.RoleGateOperator && req.SupervisorID != "" {
-		supervisor, err := h.db.GetUser(req.SupervisorID)
-		if err == nil {
-			if supervisor.ManagedOperators == nil {
-				supervisor.ManagedOperators = []string{}
+	if user.Role == models.RoleGateOperator && user.SupervisorID != "" {
+		if _, err := store.GetUser(user.SupervisorID); err == nil {
+			if err := store.AddManagedOperator(user.SupervisorID, user.UserID); err != nil {
+				log.Printf("⚠️  Failed to add %s to the managed operators of supervisor %s: %v", user.UserID, user.SupervisorID, err)
 			}
-			// Add operator to supervisor's list if not already there
-			found := false
-			for _, opID := range supervisor.ManagedOperators {
-				if opID == userID {
-					found = true
-					break
+		}
+	}
+
+	return nil
+}
+
+type ImportUsersRequest struct {
+	Users []CreateUserRequest `json:"users"`
+}
+
+// ImportUserResult reports the outcome for a single row of a bulk import
+type ImportUserResult struct {
+	Username string `json:"username"`
+	UserID   string `json:"user_id,omitempty"`
+	Status   string `json:"status"` // "created" or "rejected"
+	Reason   string `json:"reason,omitempty"`
+}
+
+type ImportUsersResponse struct {
+	DryRun   bool               `json:"dry_run"`
+	Created  int                `json:"created"`
+	Rejected int                `json:"rejected"`
+	Results  []ImportUserResult `json:"results"`
+}
+
+// ImportUsers creates many users at once. With ?dry_run=true every row is validated
+// and reported exactly as in a real run, but nothing is written.
+func (h *AdminHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ImportUsersRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Users) == 0 {
+		writeError(w, "At least one user is required", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	dryRun := isDryRun(r)
+	response := ImportUsersResponse{
+		DryRun:  dryRun,
+		Results: make([]ImportUserResult, 0, len(req.Users)),
+	}
+	seen := make(map[string]bool)
+
+	for _, row := range req.Users {
+		result := ImportUserResult{Username: row.Username}
+
+		if problem, err := h.applyUserDefaults(store, &row); err != nil {
+			log.Printf("❌ Failed to apply user defaults for %s: %v", row.Username, err)
+			result.Reason = "Failed to apply user defaults"
+		} else if problem != "" {
+			result.Reason = problem
+		} else if err := validateNewUser(row); err != nil {
+			result.Reason = err.Error()
+		} else if err := checkRoleGrant(adminUser, row.Role); err != nil {
+			result.Reason = err.Error()
+		} else if seen[row.Username] {
+			result.Reason = "Duplicate username in import"
+		} else if taken, err := h.usernameTaken(row.Username); err != nil {
+			log.Printf("❌ Failed to check username %s: %v", row.Username, err)
+			result.Reason = "Failed to check username"
+		} else if taken {
+			result.Reason = "Username already exists"
+		}
+
+		if result.Reason != "" {
+			result.Status = "rejected"
+			response.Rejected++
+			response.Results = append(response.Results, result)
+			continue
+		}
+		seen[row.Username] = true
+
+		user := newUserFromRequest(row, adminUser)
+		if !dryRun {
+			if err := storeNewUser(store, user, row.Password); err != nil {
+				result.Status = "rejected"
+				result.Reason = "Failed to create user"
+				if errors.Is(err, db.ErrUsernameTaken) {
+					result.Reason = "Username already exists"
+				} else {
+					log.Printf("❌ Failed to import user %s: %v", row.Username, err)
 				}
-			}
-			if !found {
-				supervisor.ManagedOperators = append(supervisor.ManagedOperators, userID)
-				h.db.UpdateUser(supervisor)
+				response.Rejected++
+				response.Results = append(response.Results, result)
+				continue
 			}
 		}
+
+		result.UserID = user.UserID
+		result.Status = "created"
+		response.Created++
+		response.Results = append(response.Results, result)
 	}
 
-	log.Printf("✅ User created by %s: %s (role: %s)", adminUser.Username, req.Username, req.Role)
+	log.Printf("✅ User import by %s (dry run: %t): %d created, %d rejected", adminUser.Username, dryRun, response.Created, response.Rejected)
+	if !dryRun {
+		middleware.SetAuditEvent(r.Context(), models.AuditActionImportUsers, fmt.Sprintf("Admin '%s' imported %d users (%d rejected)", adminUser.Username, response.Created, response.Rejected))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(response)
 }
 
 // UpdateUser updates an existing user
 func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -174,8 +443,8 @@ func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -184,74 +453,250 @@ func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	store := scopedDB(h.db, adminUser)
+
 	// Get existing user
-	user, err := h.db.GetUser(req.UserID)
+	user, err := store.GetUser(req.UserID)
 	if err != nil {
-		writeError(w, "User not found", http.StatusNotFound)
+		writeLookupError(w, err, "User not found")
+		return
+	}
+
+	// Organization admins can neither modify super admins nor create new ones
+	if err := checkRoleGrant(adminUser, user.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := checkRoleGrant(adminUser, req.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if req.Role != "" && !req.Role.IsValid() {
+		writeError(w, "Invalid role", http.StatusBadRequest)
 		return
 	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SupervisorID != "" && (req.Role == models.RoleSupervisor || (req.Role == "" && user.Role == models.RoleSupervisor)) {
+		writeError(w, "Supervisors cannot be assigned a supervisor", http.StatusBadRequest)
+		return
+	}
+
+	// Role changes reconcile the supervisor hierarchy before any other field is written
+	action, cascade := models.AuditActionUpdateUser, ""
+	if req.Role != "" && req.Role != user.Role {
+		change, err := store.ChangeRole(user.UserID, req.Role)
+		if errors.Is(err, db.ErrLastAdmin) {
+			writeError(w, "Cannot change the role of the last admin", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Failed to change role of %s: %v", user.Username, err)
+			writeError(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		user = change.User
+		revokeSessions(store, user.UserID)
+		action = models.AuditActionUpdateRole
+		cascade = fmt.Sprintf("; role %s -> %s", change.OldRole, user.Role)
+		if len(change.DetachedOperators) > 0 {
+			cascade += fmt.Sprintf("; detached operators: %s", strings.Join(change.DetachedOperators, ", "))
+		}
+		if change.FormerSupervisor != "" {
+			cascade += fmt.Sprintf("; detached from supervisor %s", change.FormerSupervisor)
+		}
+	}
 
 	// Store old supervisor ID for cleanup
 	oldSupervisorID := user.SupervisorID
 
 	// Update fields
-	if req.Role != "" {
-		user.Role = req.Role
-	}
 	if req.AllowedCheckpoints != nil {
 		user.AllowedCheckpoints = req.AllowedCheckpoints
 	}
 	if req.SupervisorID != "" {
 		user.SupervisorID = req.SupervisorID
 	}
+	if req.Permissions != nil {
+		cascade += fmt.Sprintf("; permissions %v -> %v", user.Permissions, req.Permissions)
+		user.Permissions = req.Permissions
+	}
 
-	// Update user
-	if err := h.db.UpdateUser(user); err != nil {
+	// Update user; only the requested fields are written
+	if err := store.UpdateUserFields(user.UserID, db.UserFieldsUpdate{
+		AllowedCheckpoints: req.AllowedCheckpoints,
+		SupervisorID:       req.SupervisorID,
+		Permissions:        req.Permissions,
+	}); err != nil {
 		log.Printf("❌ Failed to update user: %v", err)
 		writeError(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
 
-	// Update supervisor relationships if supervisor changed
-	if oldSupervisorID != req.SupervisorID {
+	// Update supervisor relationships if supervisor changed. An empty supervisor_id
+	// means no change; use UnassignSupervisor to clear it.
+	if req.SupervisorID != "" && oldSupervisorID != req.SupervisorID {
 		// Remove from old supervisor's list
 		if oldSupervisorID != "" {
-			oldSupervisor, err := h.db.GetUser(oldSupervisorID)
-			if err == nil {
-				newList := []string{}
-				for _, opID := range oldSupervisor.ManagedOperators {
-					if opID != req.UserID {
-						newList = append(newList, opID)
-					}
+			if _, err := store.GetUser(oldSupervisorID); err == nil {
+				if err := store.RemoveManagedOperator(oldSupervisorID, req.UserID); err != nil {
+					log.Printf("⚠️  Failed to remove %s from the managed operators of supervisor %s: %v", req.UserID, oldSupervisorID, err)
 				}
-				oldSupervisor.ManagedOperators = newList
-				h.db.UpdateUser(oldSupervisor)
 			}
 		}
 
 		// Add to new supervisor's list
 		if req.SupervisorID != "" {
-			newSupervisor, err := h.db.GetUser(req.SupervisorID)
-			if err == nil {
-				if newSupervisor.ManagedOperators == nil {
-					newSupervisor.ManagedOperators = []string{}
-				}
-				found := false
-				for _, opID := range newSupervisor.ManagedOperators {
-					if opID == req.UserID {
-						found = true
-						break
-					}
-				}
-				if !found {
-					newSupervisor.ManagedOperators = append(newSupervisor.ManagedOperators, req.UserID)
-					h.db.UpdateUser(newSupervisor)
+			if _, err := store.GetUser(req.SupervisorID); err == nil {
+				if err := store.AddManagedOperator(req.SupervisorID, req.UserID); err != nil {
+					log.Printf("⚠️  Failed to add %s to the managed operators of supervisor %s: %v", req.UserID, req.SupervisorID, err)
 				}
 			}
 		}
 	}
 
 	log.Printf("✅ User updated by %s: %s", adminUser.Username, user.Username)
+	middleware.SetAuditEvent(r.Context(), action, fmt.Sprintf("Admin '%s' updated user '%s' (role: %s)%s", adminUser.Username, user.Username, user.Role, cascade))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SetUserCheckpoints replaces a user's allowed checkpoints after checking that every
+// checkpoint exists. Unlike UpdateUser it rejects the whole list if any ID is unknown.
+func (h *AdminHandler) SetUserCheckpoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetUserCheckpointsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" || req.AllowedCheckpoints == nil {
+		writeError(w, "User ID and allowed checkpoints are required", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+
+	user, err := store.GetUser(req.UserID)
+	if err != nil {
+		writeLookupError(w, err, "User not found")
+		return
+	}
+	if err := checkRoleGrant(adminUser, user.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Validate against Firestore, not a cache that may miss another instance's changes
+	h.db.RefreshCheckpoints()
+	checkpoints, err := store.GetAllCheckpoints()
+	if err != nil {
+		log.Printf("❌ Failed to get checkpoints: %v", err)
+		writeError(w, "Failed to validate checkpoints", http.StatusInternalServerError)
+		return
+	}
+	known := make(map[string]bool, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		known[checkpoint.CheckpointID] = true
+	}
+
+	validated := []string{}
+	unknown := []string{}
+	seen := make(map[string]bool, len(req.AllowedCheckpoints))
+	for _, id := range req.AllowedCheckpoints {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if known[id] {
+			validated = append(validated, id)
+		} else {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		writeErrorDetails(w, "Unknown checkpoints: "+strings.Join(unknown, ", "), unknown, http.StatusBadRequest)
+		return
+	}
+
+	if err := store.SetAllowedCheckpoints(user.UserID, validated); err != nil {
+		log.Printf("❌ Failed to set checkpoints of %s: %v", user.Username, err)
+		writeError(w, "Failed to update allowed checkpoints", http.StatusInternalServerError)
+		return
+	}
+	user.AllowedCheckpoints = validated
+
+	log.Printf("✅ Allowed checkpoints of %s set by %s: %v", user.Username, adminUser.Username, validated)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionSetUserCheckpoints, fmt.Sprintf("Admin '%s' set allowed checkpoints of '%s' to [%s]", adminUser.Username, user.Username, strings.Join(validated, ", ")))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UnassignSupervisor clears an operator's supervisor, which UpdateUser cannot do
+// because it treats an empty supervisor_id as no change
+func (h *AdminHandler) UnassignSupervisor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req UnassignSupervisorRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" {
+		writeError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+
+	existing, err := store.GetUser(req.UserID)
+	if err != nil {
+		writeLookupError(w, err, "User not found")
+		return
+	}
+	if err := checkRoleGrant(adminUser, existing.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	user, err := store.ClearSupervisor(req.UserID)
+	if errors.Is(err, db.ErrUserNotFound) {
+		writeError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to unassign supervisor of %s: %v", req.UserID, err)
+		writeError(w, "Failed to unassign supervisor", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Supervisor unassigned by %s: %s (was %s)", adminUser.Username, user.Username, existing.SupervisorID)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionUnassignSupervisor, fmt.Sprintf("Admin '%s' removed supervisor '%s' from user '%s'", adminUser.Username, existing.SupervisorID, user.Username))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
@@ -260,7 +705,7 @@ func (h *AdminHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // DeleteUser deletes a user
 func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -271,8 +716,8 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req DeleteUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -287,36 +732,41 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	store := scopedDB(h.db, adminUser)
+
 	// Get user to check supervisor relationships
-	user, err := h.db.GetUser(req.UserID)
+	user, err := store.GetUser(req.UserID)
 	if err != nil {
-		writeError(w, "User not found", http.StatusNotFound)
+		writeLookupError(w, err, "User not found")
+		return
+	}
+
+	if err := checkRoleGrant(adminUser, user.Role); err != nil {
+		writeError(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
 	// Remove from supervisor's managed operators list
 	if user.SupervisorID != "" {
-		supervisor, err := h.db.GetUser(user.SupervisorID)
-		if err == nil {
-			newList := []string{}
-			for _, opID := range supervisor.ManagedOperators {
-				if opID != req.UserID {
-					newList = append(newList, opID)
-				}
+		if _, err := store.GetUser(user.SupervisorID); err == nil {
+			if err := store.RemoveManagedOperator(user.SupervisorID, req.UserID); err != nil {
+				log.Printf("❌ Failed to remove %s from the managed operators of supervisor %s: %v", req.UserID, user.SupervisorID, err)
+				writeError(w, "Failed to delete user", http.StatusInternalServerError)
+				return
 			}
-			supervisor.ManagedOperators = newList
-			h.db.UpdateUser(supervisor)
 		}
 	}
 
 	// Delete user
-	if err := h.db.DeleteUser(req.UserID); err != nil {
+	if err := store.DeleteUser(req.UserID); err != nil {
 		log.Printf("❌ Failed to delete user: %v", err)
 		writeError(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
+	revokeSessions(store, req.UserID)
 
 	log.Printf("✅ User deleted by %s: %s", adminUser.Username, user.Username)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionDeleteUser, fmt.Sprintf("Admin '%s' deleted user '%s'", adminUser.Username, user.Username))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -327,33 +777,76 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 // --- Checkpoint Management ---
 
 type CreateCheckpointRequest struct {
-	CheckpointID string `json:"checkpoint_id"`
-	Name         string `json:"name"`
-	Location     string `json:"location"`
+	CheckpointID      string             `json:"checkpoint_id"`
+	Name              string             `json:"name"`
+	Location          string             `json:"location"`
+	AllowedEntryTypes []models.EntryType `json:"allowed_entry_types"` // Empty allows every type
+}
+
+// validateAllowedEntryTypes checks the entry types a checkpoint is restricted to
+func validateAllowedEntryTypes(types []models.EntryType) error {
+	for _, t := range types {
+		if !t.IsValid() {
+			return fmt.Errorf("Invalid entry type %q in allowed_entry_types", t)
+		}
+	}
+	return nil
 }
 
 // GetCheckpoints returns all checkpoints
 func (h *AdminHandler) GetCheckpoints(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
-	checkpoints, err := h.db.GetAllCheckpoints()
+	params, err := parsePageParams(r)
 	if err != nil {
-		log.Printf("❌ Failed to get checkpoints: %v", err)
-		writeError(w, "Failed to retrieve checkpoints", http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(checkpoints)
-}
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	// ?refresh=true bypasses the checkpoint cache, e.g. after editing checkpoints directly in Firestore
+	refresh, err := httputil.ParseBoolParam(r, "refresh", false)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if refresh {
+		h.db.RefreshCheckpoints()
+	}
+
+	checkpoints, err := scopedDB(h.db, user).GetAllCheckpoints()
+	if err != nil {
+		log.Printf("❌ Failed to get checkpoints: %v", err)
+		writeError(w, "Failed to retrieve checkpoints", http.StatusInternalServerError)
+		return
+	}
+
+	page, pagination, err := paginate(checkpoints, params)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Checkpoints rarely change, so let pollers revalidate cheaply
+	if etag, err := contentETag(PaginatedResponse{Data: page, Pagination: pagination}); err == nil && checkNotModified(w, r, etag) {
+		return
+	}
+
+	writePaginated(w, page, pagination)
+}
 
 // CreateCheckpoint creates a new checkpoint
 func (h *AdminHandler) CreateCheckpoint(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -364,8 +857,8 @@ func (h *AdminHandler) CreateCheckpoint(w http.ResponseWriter, r *http.Request)
 	}
 
 	var req CreateCheckpointRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -373,21 +866,557 @@ func (h *AdminHandler) CreateCheckpoint(w http.ResponseWriter, r *http.Request)
 		writeError(w, "Checkpoint ID and name are required", http.StatusBadRequest)
 		return
 	}
+	if err := validateAllowedEntryTypes(req.AllowedEntryTypes); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	checkpoint := &models.Checkpoint{
-		CheckpointID: req.CheckpointID,
-		Name:         req.Name,
-		Location:     req.Location,
+		CheckpointID:      req.CheckpointID,
+		Name:              req.Name,
+		Location:          req.Location,
+		OrgID:             adminUser.OrgID,
+		AllowedEntryTypes: req.AllowedEntryTypes,
 	}
 
-	if err := h.db.CreateCheckpoint(checkpoint); err != nil {
+	err := h.db.CreateCheckpoint(checkpoint)
+	if errors.Is(err, db.ErrCheckpointExists) {
+		writeError(w, "Checkpoint ID already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
 		log.Printf("❌ Failed to create checkpoint: %v", err)
 		writeError(w, "Failed to create checkpoint", http.StatusInternalServerError)
 		return
 	}
 
 	log.Printf("✅ Checkpoint created by %s: %s", adminUser.Username, req.Name)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionCreateCheckpoint, fmt.Sprintf("Admin '%s' created checkpoint '%s'", adminUser.Username, req.CheckpointID))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(checkpoint)
 }
+
+// UpsertCheckpointResponse reports what UpsertCheckpoint did
+type UpsertCheckpointResponse struct {
+	Status     string             `json:"status"` // "created", "updated" or "unchanged"
+	Checkpoint *models.Checkpoint `json:"checkpoint"`
+}
+
+// UpsertCheckpoint creates a checkpoint or, when its ID already exists in the admin's
+// organization, replaces its name, location and allowed entry types. Provisioning
+// scripts can re-run it safely: a checkpoint that already matches is left unchanged.
+func (h *AdminHandler) UpsertCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateCheckpointRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.CheckpointID == "" || req.Name == "" {
+		writeError(w, "Checkpoint ID and name are required", http.StatusBadRequest)
+		return
+	}
+	if err := validateAllowedEntryTypes(req.AllowedEntryTypes); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outcome, checkpoint, err := scopedDB(h.db, adminUser).UpsertCheckpoint(&models.Checkpoint{
+		CheckpointID:      req.CheckpointID,
+		Name:              req.Name,
+		Location:          req.Location,
+		OrgID:             adminUser.OrgID,
+		AllowedEntryTypes: req.AllowedEntryTypes,
+	})
+	if errors.Is(err, db.ErrCheckpointIDTaken) {
+		writeError(w, "Checkpoint ID already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to upsert checkpoint %s: %v", req.CheckpointID, err)
+		writeError(w, "Failed to save checkpoint", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Checkpoint upserted by %s: %s (%s)", adminUser.Username, req.CheckpointID, outcome)
+	switch outcome {
+	case db.UpsertCreated:
+		middleware.SetAuditEvent(r.Context(), models.AuditActionCreateCheckpoint, fmt.Sprintf("Admin '%s' created checkpoint '%s' by upsert", adminUser.Username, req.CheckpointID))
+	case db.UpsertUpdated:
+		middleware.SetAuditEvent(r.Context(), models.AuditActionUpdateCheckpoint, fmt.Sprintf("Admin '%s' updated checkpoint '%s' by upsert", adminUser.Username, req.CheckpointID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UpsertCheckpointResponse{Status: outcome, Checkpoint: checkpoint})
+}
+
+// maxImportCheckpoints caps the rows of one checkpoint import, which are created in a
+// single transaction
+const maxImportCheckpoints = db.MaxCreateCheckpoints
+
+// maxImportBodyBytes caps the size of a checkpoint import request body
+const maxImportBodyBytes = 1 << 20
+
+type ImportCheckpointsRequest struct {
+	Checkpoints []CreateCheckpointRequest `json:"checkpoints"`
+}
+
+// ImportCheckpointResult reports the outcome for a single row of a checkpoint import
+type ImportCheckpointResult struct {
+	CheckpointID string `json:"checkpoint_id"`
+	Status       string `json:"status"` // "created" or "rejected"
+	Reason       string `json:"reason,omitempty"`
+}
+
+type ImportCheckpointsResponse struct {
+	DryRun   bool                     `json:"dry_run"`
+	Created  int                      `json:"created"`
+	Rejected int                      `json:"rejected"`
+	Results  []ImportCheckpointResult `json:"results"`
+}
+
+// ImportCheckpoints creates many checkpoints at once, e.g. from a site planning
+// spreadsheet. Rows with missing fields or an ID already used in the import or in
+// Firestore are rejected individually; the accepted rows are written together or not at
+// all. With ?dry_run=true nothing is written.
+func (h *AdminHandler) ImportCheckpoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	var req ImportCheckpointsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			writeError(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Checkpoints) == 0 {
+		writeError(w, "At least one checkpoint is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Checkpoints) > maxImportCheckpoints {
+		writeError(w, fmt.Sprintf("At most %d checkpoints can be imported per request", maxImportCheckpoints), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]string, 0, len(req.Checkpoints))
+	for _, row := range req.Checkpoints {
+		if row.CheckpointID != "" {
+			ids = append(ids, row.CheckpointID)
+		}
+	}
+	// Checkpoint IDs are document IDs, so they must be unique across organizations
+	existing, err := h.db.ExistingCheckpointIDs(ids)
+	if err != nil {
+		log.Printf("❌ Failed to check existing checkpoints: %v", err)
+		writeError(w, "Failed to import checkpoints", http.StatusInternalServerError)
+		return
+	}
+
+	dryRun := isDryRun(r)
+	response := ImportCheckpointsResponse{
+		DryRun:  dryRun,
+		Results: make([]ImportCheckpointResult, len(req.Checkpoints)),
+	}
+	seen := make(map[string]bool)
+	var toCreate []models.Checkpoint
+	var rows []int // Index into Results of each checkpoint in toCreate
+
+	for i, row := range req.Checkpoints {
+		result := ImportCheckpointResult{CheckpointID: row.CheckpointID, Status: "created"}
+
+		if row.CheckpointID == "" || row.Name == "" {
+			result.Reason = "Checkpoint ID and name are required"
+		} else if err := validateAllowedEntryTypes(row.AllowedEntryTypes); err != nil {
+			result.Reason = err.Error()
+		} else if seen[row.CheckpointID] {
+			result.Reason = "Duplicate checkpoint ID in import"
+		} else if existing[row.CheckpointID] {
+			result.Reason = "Checkpoint ID already exists"
+		}
+		seen[row.CheckpointID] = true
+
+		if result.Reason != "" {
+			result.Status = "rejected"
+		} else {
+			toCreate = append(toCreate, models.Checkpoint{
+				CheckpointID:      row.CheckpointID,
+				Name:              row.Name,
+				Location:          row.Location,
+				OrgID:             adminUser.OrgID,
+				AllowedEntryTypes: row.AllowedEntryTypes,
+			})
+			rows = append(rows, i)
+		}
+		response.Results[i] = result
+	}
+
+	if !dryRun && len(toCreate) > 0 {
+		// IDs taken since the check above are reported like ones that existed before
+		taken, err := h.db.CreateCheckpoints(toCreate)
+		if err != nil {
+			log.Printf("❌ Checkpoint import by %s failed: %v", adminUser.Username, err)
+		}
+		for j, i := range rows {
+			switch {
+			case err != nil:
+				response.Results[i].Status = "rejected"
+				response.Results[i].Reason = "Failed to create checkpoint"
+			case taken[toCreate[j].CheckpointID]:
+				response.Results[i].Status = "rejected"
+				response.Results[i].Reason = "Checkpoint ID already exists"
+			}
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Status == "created" {
+			response.Created++
+		} else {
+			response.Rejected++
+		}
+	}
+
+	log.Printf("✅ Checkpoint import by %s (dry run: %t): %d created, %d rejected", adminUser.Username, dryRun, response.Created, response.Rejected)
+	if !dryRun {
+		middleware.SetAuditEvent(r.Context(), models.AuditActionImportCheckpoints, fmt.Sprintf("Admin '%s' imported %d checkpoints (%d rejected)", adminUser.Username, response.Created, response.Rejected))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxAssignOperators caps the operators changed by one assignment; Firestore transactions
+// are limited to 500 writes
+const maxAssignOperators = 500
+
+type CheckpointAssignmentRequest struct {
+	CheckpointID string   `json:"checkpoint_id"`
+	OperatorIDs  []string `json:"operator_ids"`
+}
+
+// AssignCheckpoint adds a checkpoint to the allowed checkpoints of several operators
+func (h *AdminHandler) AssignCheckpoint(w http.ResponseWriter, r *http.Request) {
+	h.setCheckpointAssignment(w, r, true)
+}
+
+// UnassignCheckpoint removes a checkpoint from the allowed checkpoints of several operators
+func (h *AdminHandler) UnassignCheckpoint(w http.ResponseWriter, r *http.Request) {
+	h.setCheckpointAssignment(w, r, false)
+}
+
+func (h *AdminHandler) setCheckpointAssignment(w http.ResponseWriter, r *http.Request, assign bool) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CheckpointAssignmentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.CheckpointID == "" || len(req.OperatorIDs) == 0 {
+		writeError(w, "Checkpoint ID and operator IDs are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.OperatorIDs) > maxAssignOperators {
+		writeError(w, fmt.Sprintf("At most %d operators can be changed per request", maxAssignOperators), http.StatusBadRequest)
+		return
+	}
+
+	// Drop duplicates so each operator is written once in the transaction
+	seen := make(map[string]bool, len(req.OperatorIDs))
+	operatorIDs := make([]string, 0, len(req.OperatorIDs))
+	for _, id := range req.OperatorIDs {
+		if !seen[id] {
+			seen[id] = true
+			operatorIDs = append(operatorIDs, id)
+		}
+	}
+
+	store := scopedDB(h.db, adminUser)
+
+	if _, err := store.GetCheckpoint(req.CheckpointID); err != nil {
+		writeLookupError(w, err, "Checkpoint not found")
+		return
+	}
+
+	operators, err := store.SetCheckpointAssignment(req.CheckpointID, operatorIDs, assign)
+	if errors.Is(err, db.ErrUserNotFound) || errors.Is(err, db.ErrNotOperator) {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to update assignment of checkpoint %s: %v", req.CheckpointID, err)
+		writeError(w, "Failed to update checkpoint assignment", http.StatusInternalServerError)
+		return
+	}
+
+	action, verb := models.AuditActionAssignCheckpoint, "assigned"
+	if !assign {
+		action, verb = models.AuditActionUnassignCheckpoint, "unassigned"
+	}
+	log.Printf("✅ Checkpoint %s %s by %s for %d operators", req.CheckpointID, verb, adminUser.Username, len(operators))
+	middleware.SetAuditEvent(r.Context(), action, fmt.Sprintf("Admin '%s' %s checkpoint '%s' for operators %s", adminUser.Username, verb, req.CheckpointID, strings.Join(operatorIDs, ", ")))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(operators)
+}
+
+// --- Entry Management ---
+
+type ReassignEntriesRequest struct {
+	FromUserID string `json:"from_user_id"`
+	ToUserID   string `json:"to_user_id"`
+}
+
+// ReassignEntries moves all entries logged by one operator to another
+func (h *AdminHandler) ReassignEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReassignEntriesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.FromUserID == "" || req.ToUserID == "" {
+		writeError(w, "From and to user IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.FromUserID == req.ToUserID {
+		writeError(w, "From and to user IDs must differ", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+
+	// The source user may already be deleted, but the target must exist
+	if _, err := store.GetUser(req.ToUserID); err != nil {
+		writeLookupError(w, err, "Target user not found")
+		return
+	}
+
+	reassigned, err := store.ReassignEntries(req.FromUserID, req.ToUserID)
+	if err != nil {
+		log.Printf("❌ Failed to reassign entries from %s to %s after %d entries: %v", req.FromUserID, req.ToUserID, reassigned, err)
+		writeError(w, "Failed to reassign entries; retry to complete the remaining entries", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Entries reassigned by %s: %d from %s to %s", adminUser.Username, reassigned, req.FromUserID, req.ToUserID)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionReassignEntries, fmt.Sprintf("Admin '%s' reassigned %d entries from '%s' to '%s'", adminUser.Username, reassigned, req.FromUserID, req.ToUserID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Entries reassigned successfully",
+		"reassigned": reassigned,
+	})
+}
+
+// maxBulkDeleteIDs caps the number of record IDs accepted by a single bulk delete
+const maxBulkDeleteIDs = 500
+
+// tombstoneBatchSize is the number of entries tombstoned per batched write
+const tombstoneBatchSize = 500
+
+type DeleteEntriesRequest struct {
+	RecordIDs    []string  `json:"record_ids,omitempty"`
+	CheckpointID string    `json:"checkpoint_id,omitempty"`
+	From         time.Time `json:"from,omitempty"` // RFC3339, inclusive
+	To           time.Time `json:"to,omitempty"`   // RFC3339, exclusive
+	ConfirmToken string    `json:"confirm_token,omitempty"`
+}
+
+func (req DeleteEntriesRequest) hasFilter() bool {
+	return req.CheckpointID != "" || !req.From.IsZero() || !req.To.IsZero()
+}
+
+// deleteConfirmToken fingerprints a filter-based delete: the filter plus the exact set of
+// matched entries. A dry run hands it out, and the real delete must present it, so the
+// admin confirms precisely the entries they previewed.
+func deleteConfirmToken(req DeleteEntriesRequest, entries []models.Entry) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d", req.CheckpointID, req.From.UnixNano(), req.To.UnixNano())
+	for _, entry := range entries {
+		fmt.Fprintf(h, "|%s", entry.RecordID)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// DeleteEntries tombstones entries by record ID or by checkpoint and date range.
+// Filter-based deletes must first be previewed with ?dry_run=true and then repeated
+// with the returned confirm_token.
+func (h *AdminHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeleteEntriesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.RecordIDs) > 0 && req.hasFilter() {
+		writeError(w, "Provide either record_ids or a filter, not both", http.StatusBadRequest)
+		return
+	}
+	if len(req.RecordIDs) == 0 && !req.hasFilter() {
+		writeError(w, "Provide record_ids or a checkpoint_id/from/to filter", http.StatusBadRequest)
+		return
+	}
+	if len(req.RecordIDs) > maxBulkDeleteIDs {
+		writeError(w, fmt.Sprintf("At most %d record IDs can be deleted per request", maxBulkDeleteIDs), http.StatusBadRequest)
+		return
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		writeError(w, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	dryRun := isDryRun(r)
+
+	// Resolve the request to the active entries it targets
+	var matched []models.Entry
+	if len(req.RecordIDs) > 0 {
+		for _, recordID := range req.RecordIDs {
+			entry, err := store.GetEntry(recordID)
+			if errors.Is(err, db.ErrNotFound) {
+				continue // Unknown or outside the admin's organization
+			}
+			if err != nil {
+				log.Printf("❌ Failed to find entries to delete: %v", err)
+				writeError(w, "Failed to find entries", http.StatusInternalServerError)
+				return
+			}
+			if entry.Status != models.StatusDeleted {
+				matched = append(matched, *entry)
+			}
+		}
+	} else {
+		entries, err := store.GetEntriesByFilter(db.EntryFilter{
+			CheckpointID: req.CheckpointID,
+			From:         req.From,
+			To:           req.To,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to find entries to delete: %v", err)
+			writeError(w, "Failed to find entries", http.StatusInternalServerError)
+			return
+		}
+		for _, entry := range entries {
+			if entry.Status != models.StatusDeleted {
+				matched = append(matched, entry)
+			}
+		}
+	}
+
+	response := map[string]interface{}{
+		"dry_run": dryRun,
+		"matched": len(matched),
+	}
+
+	if req.hasFilter() {
+		token := deleteConfirmToken(req, matched)
+		if dryRun {
+			response["confirm_token"] = token
+		} else if req.ConfirmToken != token {
+			writeError(w, "Filter-based deletes require the confirm_token from a dry run (?dry_run=true); the matched entries may have changed since", http.StatusPreconditionFailed)
+			return
+		}
+	}
+
+	if dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	deleted := 0
+	for start := 0; start < len(matched); start += tombstoneBatchSize {
+		end := start + tombstoneBatchSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+
+		recordIDs := make([]string, 0, end-start)
+		for _, entry := range matched[start:end] {
+			recordIDs = append(recordIDs, entry.RecordID)
+		}
+		if err := store.TombstoneEntries(recordIDs); err != nil {
+			log.Printf("❌ Failed to delete entries after %d of %d: %v", deleted, len(matched), err)
+			middleware.SetAuditEvent(r.Context(), models.AuditActionDeleteEntries, fmt.Sprintf("Admin '%s' bulk-deleted %d of %d entries before failing", adminUser.Username, deleted, len(matched)))
+			writeError(w, "Failed to delete entries; retry to complete the remaining entries", http.StatusInternalServerError)
+			return
+		}
+		deleted += len(recordIDs)
+	}
+
+	log.Printf("✅ Entries bulk-deleted by %s: %d", adminUser.Username, deleted)
+	if req.hasFilter() {
+		middleware.SetAuditEvent(r.Context(), models.AuditActionDeleteEntries, fmt.Sprintf("Admin '%s' bulk-deleted %d entries (checkpoint '%s', from %s, to %s)",
+			adminUser.Username, deleted, req.CheckpointID, req.From.Format(time.RFC3339), req.To.Format(time.RFC3339)))
+	} else {
+		recordIDs := make([]string, 0, len(matched))
+		for _, entry := range matched {
+			recordIDs = append(recordIDs, entry.RecordID)
+		}
+		middleware.SetAuditEvent(r.Context(), models.AuditActionDeleteEntries, fmt.Sprintf("Admin '%s' bulk-deleted %d entries: %s", adminUser.Username, deleted, strings.Join(recordIDs, ", ")))
+		middleware.SetAuditRecords(r.Context(), recordIDs)
+	}
+
+	response["deleted"] = deleted
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/httputil"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditHandler struct {
+	db *db.FirestoreDB
+}
+
+func NewAuditHandler(firestoreDB *db.FirestoreDB) *AuditHandler {
+	return &AuditHandler{
+		db: firestoreDB,
+	}
+}
+
+// parseAuditFilter reads the user_id, action, from and to query parameters
+func parseAuditFilter(r *http.Request) (db.AuditLogFilter, error) {
+	query := r.URL.Query()
+	filter := db.AuditLogFilter{
+		UserID: query.Get("user_id"),
+	}
+
+	var err error
+	if filter.Action, err = httputil.ParseEnumParam(r, "action", "", models.AuditActions()...); err != nil {
+		return filter, err
+	}
+	if filter.From, err = httputil.ParseTimeParam(r, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = httputil.ParseTimeParam(r, "to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// GetAuditLogs returns audit logs filtered by user, action and date range
+func (h *AuditHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	startAfter := ""
+	if params.Cursor != "" {
+		if startAfter, err = decodeKeyCursor(params.Cursor); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Audit logs grow without bound, so page in Firestore rather than in memory
+	store := scopedDB(h.db, user)
+	page, next, err := store.ListAuditLogs(filter, params.Limit, startAfter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, "Invalid 'cursor' parameter", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeAuditQueryError(w, err)
+		return
+	}
+
+	total, err := store.CountAuditLogs(filter)
+	if err != nil {
+		writeAuditQueryError(w, err)
+		return
+	}
+
+	writePaginated(w, page, Pagination{
+		Total:      total,
+		Limit:      params.Limit,
+		Cursor:     params.Cursor,
+		NextCursor: encodeKeyCursor(next),
+	})
+}
+
+// writeAuditQueryError reports a failed audit query, naming a missing index explicitly
+// so operators know to deploy it rather than retry
+func writeAuditQueryError(w http.ResponseWriter, err error) {
+	log.Printf("❌ Failed to get audit logs: %v", err)
+	if errors.Is(err, db.ErrIndexMissing) {
+		writeError(w, "Audit log query needs a Firestore index that has not been created; see server logs", http.StatusServiceUnavailable)
+		return
+	}
+	writeError(w, "Failed to retrieve audit logs", http.StatusInternalServerError)
+}
+
+// ExportAuditLogs streams audit logs as a CSV (default) or JSON download
+func (h *AuditHandler) ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format, err := httputil.ParseEnumParam(r, "format", "csv", "csv", "json")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc, err := httputil.ParseLocationParam(r, "tz")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	filename := fmt.Sprintf("gatekeeper_audit_%s.%s", timestamp, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	store := scopedDB(h.db, user)
+	count, err := writeAuditExport(w, format, loc, func(fn func(auditLog *models.AuditLog) error) error {
+		return store.StreamAuditLogs(filter, fn)
+	})
+	if err != nil {
+		log.Printf("❌ Audit export by %s failed after %d records: %v", user.Username, count, err)
+		// Headers are already sent once streaming starts; aborting the response is the
+		// only way to keep the client from taking a truncated export for a complete one
+		panic(http.ErrAbortHandler)
+	}
+
+	log.Printf("📊 Audit export (%s) by %s: %d records", format, user.Username, count)
+}
+
+// writeAuditExport writes the audit logs stream yields to w as a JSON array or CSV,
+// returning how many were written. On error the output is left unterminated.
+func writeAuditExport(w http.ResponseWriter, format string, loc *time.Location, stream func(fn func(auditLog *models.AuditLog) error) error) (int, error) {
+	count := 0
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		fmt.Fprint(w, "[")
+		err := stream(func(auditLog *models.AuditLog) error {
+			if count > 0 {
+				fmt.Fprint(w, ",")
+			}
+			count++
+			return encoder.Encode(auditLog)
+		})
+		if err != nil {
+			return count, err
+		}
+		fmt.Fprint(w, "]")
+		return count, nil
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	writer := csv.NewWriter(w)
+	header := []string{"Log ID", "Timestamp", "User ID", "Action", "Details", "Route", "Status Code", "Record IDs", "Org ID"}
+	if err := writer.Write(header); err != nil {
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	err := stream(func(auditLog *models.AuditLog) error {
+		count++
+		statusCode := ""
+		if auditLog.StatusCode != 0 {
+			statusCode = strconv.Itoa(auditLog.StatusCode)
+		}
+		logTime := auditLog.Timestamp
+		if t, err := time.Parse(time.RFC3339, auditLog.Timestamp); err == nil {
+			logTime = formatExportTime(t, loc)
+		}
+		return writer.Write([]string{
+			auditLog.LogID,
+			logTime,
+			auditLog.UserID,
+			string(auditLog.Action),
+			auditLog.Details,
+			auditLog.Route,
+			statusCode,
+			strings.Join(auditLog.RecordIDs, ", "),
+			auditLog.OrgID,
+		})
+	})
+	writer.Flush()
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Error()
+}
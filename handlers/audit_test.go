@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gatekeeper/models"
+)
+
+// failingAuditStream yields n audit logs, then fails as a dropped query would
+func failingAuditStream(n int) func(fn func(auditLog *models.AuditLog) error) error {
+	return func(fn func(auditLog *models.AuditLog) error) error {
+		for i := 0; i < n; i++ {
+			if err := fn(&models.AuditLog{LogID: fmt.Sprintf("log-%d", i), Action: models.AuditActionCreateUser}); err != nil {
+				return err
+			}
+		}
+		return errors.New("stream interrupted")
+	}
+}
+
+func TestAuditExportFailureLeavesOutputUnterminated(t *testing.T) {
+	for _, format := range []string{"json", "csv"} {
+		rec := httptest.NewRecorder()
+		count, err := writeAuditExport(rec, format, time.UTC, failingAuditStream(2))
+		if err == nil {
+			t.Fatalf("%s: expected the stream error", format)
+		}
+		if count != 2 {
+			t.Errorf("%s: expected 2 records before the failure, got %d", format, count)
+		}
+		if format == "json" && strings.HasSuffix(strings.TrimSpace(rec.Body.String()), "]") {
+			t.Errorf("json: a failed export must not be closed as a complete array: %q", rec.Body.String())
+		}
+	}
+}
+
+func TestAuditExportClosesJSONArray(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := func(fn func(auditLog *models.AuditLog) error) error {
+		return fn(&models.AuditLog{LogID: "log-a", Action: models.AuditActionCreateUser})
+	}
+	if _, err := writeAuditExport(rec, "json", time.UTC, stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); !strings.HasPrefix(body, "[") || !strings.HasSuffix(body, "]") {
+		t.Errorf("expected a JSON array, got %q", body)
+	}
+}
+
+func TestAuditExportCSVIncludesRecordIDsAndOrg(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := func(fn func(auditLog *models.AuditLog) error) error {
+		return fn(&models.AuditLog{LogID: "log-a", Action: models.AuditActionCreateUser, OrgID: "org-a", RecordIDs: []string{"rec-1", "rec-2"}})
+	}
+	if _, err := writeAuditExport(rec, "csv", time.UTC, stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected a header and one row, got %d rows", len(rows))
+	}
+	header, row := rows[0], rows[1]
+	if got := header[len(header)-2:]; got[0] != "Record IDs" || got[1] != "Org ID" {
+		t.Errorf("unexpected trailing columns %v", got)
+	}
+	if got := row[len(row)-2:]; got[0] != "rec-1, rec-2" || got[1] != "org-a" {
+		t.Errorf("unexpected trailing values %v", got)
+	}
+}
@@ -2,47 +2,119 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gatekeeper/auth"
 	"gatekeeper/db"
+	"gatekeeper/httputil"
+	"gatekeeper/middleware"
 	"gatekeeper/models"
 	"log"
 	"net/http"
+	"net/netip"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
-	db         *db.FirestoreDB
-	jwtManager *auth.JWTManager
+	db               *db.FirestoreDB
+	jwtManager       *auth.JWTManager
+	tokens           auth.TokenStore
+	attempts         *auth.LoginAttemptTracker
+	captcha          auth.CaptchaVerifier
+	captchaThreshold int
+	lockoutThreshold int
+	idle             auth.IdlePolicy
+	trustedProxies   []netip.Prefix
 }
 
-func NewAuthHandler(firestoreDB *db.FirestoreDB, jwtManager *auth.JWTManager) *AuthHandler {
+func NewAuthHandler(firestoreDB *db.FirestoreDB, jwtManager *auth.JWTManager, tokens auth.TokenStore) *AuthHandler {
 	return &AuthHandler{
 		db:         firestoreDB,
 		jwtManager: jwtManager,
+		tokens:     tokens,
+		attempts:   auth.NewLoginAttemptTracker(15 * time.Minute),
 	}
 }
 
+// EnableCaptcha requires a verified captcha_token once a username or IP has
+// accumulated threshold failed logins. Without it, login behavior is unchanged.
+func (h *AuthHandler) EnableCaptcha(verifier auth.CaptchaVerifier, threshold int) {
+	h.captcha = verifier
+	h.captchaThreshold = threshold
+}
+
+// EnableLockout refuses logins for a username once it has accumulated threshold
+// failed logins, until the attempt window passes without further failures
+func (h *AuthHandler) EnableLockout(threshold int) {
+	h.lockoutThreshold = threshold
+}
+
+// SetIdlePolicy makes refresh tokens unusable once the user has been inactive for longer
+// than the policy allows, matching what AuthMiddleware enforces on access tokens
+func (h *AuthHandler) SetIdlePolicy(policy auth.IdlePolicy) {
+	h.idle = policy
+}
+
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
+// LoginResponse carries the issued tokens and, unless the client asked for
+// ?minimal=true, the profile of the user who logged in
 type LoginResponse struct {
 	Token        string       `json:"token"`
 	RefreshToken string       `json:"refresh_token"`
-	User         *models.User `json:"user"`
+	User         *UserProfile `json:"user,omitempty"`
 }
 
-// Login handles user authentication
+// UserProfile is the user as returned on login. Fields are copied explicitly so that
+// new User fields (password or credential metadata in particular) are never exposed
+// by accident; add a field here only when clients need it.
+type UserProfile struct {
+	UserID             string          `json:"user_id"`
+	Username           string          `json:"username"`
+	Role               models.UserRole `json:"role"`
+	AllowedCheckpoints []string        `json:"allowed_checkpoints"`
+	SupervisorID       string          `json:"supervisor_id,omitempty"`
+	ManagedOperators   []string        `json:"managed_operators,omitempty"`
+	LastLogin          time.Time       `json:"last_login"`
+	OrgID              string          `json:"org_id,omitempty"`
+}
+
+func newUserProfile(user *models.User) *UserProfile {
+	return &UserProfile{
+		UserID:             user.UserID,
+		Username:           user.Username,
+		Role:               user.Role,
+		AllowedCheckpoints: user.AllowedCheckpoints,
+		SupervisorID:       user.SupervisorID,
+		ManagedOperators:   user.ManagedOperators,
+		LastLogin:          user.LastLogin,
+		OrgID:              user.OrgID,
+	}
+}
+
+// Login handles user authentication. The response includes the user profile by
+// default; ?minimal=true returns only the tokens.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	minimal, err := httputil.ParseBoolParam(r, "minimal", false)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -52,10 +124,44 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := httputil.RealIP(r, h.trustedProxies)
+
+	// Refuse locked accounts before spending a password check on them
+	if lockedUntil, locked := h.lockedUntil(req.Username); locked {
+		log.Printf("Login refused for user %s: locked until %s", req.Username, lockedUntil.Format(time.RFC3339))
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(time.Until(lockedUntil).Seconds())+1))
+		writeError(w, "Too many failed login attempts. Try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// Require a CAPTCHA after repeated failures for this username or IP
+	if h.captchaRequired(req.Username, ip) {
+		if req.CaptchaToken == "" {
+			writeCaptchaRequired(w, "CAPTCHA verification required")
+			return
+		}
+		valid, err := h.captcha.Verify(r.Context(), req.CaptchaToken, ip)
+		if err != nil {
+			log.Printf("❌ CAPTCHA verification error for user %s: %v", req.Username, err)
+			writeError(w, "Failed to verify CAPTCHA", http.StatusServiceUnavailable)
+			return
+		}
+		if !valid {
+			writeCaptchaRequired(w, "Invalid CAPTCHA token")
+			return
+		}
+	}
+
 	// Get user by username
 	user, err := h.db.GetUserByUsername(req.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Printf("❌ Login lookup failed for user %s: %v", req.Username, err)
+		writeError(w, "Login is temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		log.Printf("Login failed for user %s: user not found", req.Username)
+		h.recordLoginFailure(req.Username, ip)
 		writeError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
@@ -64,6 +170,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	passwordHash, err := h.db.GetPasswordHash(user.UserID)
 	if err != nil {
 		log.Printf("Login failed for user %s: password hash not found", req.Username)
+		h.recordLoginFailure(req.Username, ip)
 		writeError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
@@ -71,13 +178,18 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Verify password
 	if err := auth.CheckPassword(req.Password, passwordHash); err != nil {
 		log.Printf("Login failed for user %s: invalid password", req.Username)
+		h.recordLoginFailure(req.Username, ip)
 		writeError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
-	// Update last login
-	user.LastLogin = time.Now()
-	if err := h.db.UpdateUser(user); err != nil {
+	h.attempts.Reset("user:" + req.Username)
+	h.attempts.Reset("ip:" + ip)
+
+	// Update last login; logging in also counts as activity for idle expiry
+	user.LastLogin = models.Now()
+	user.LastActivityAt = user.LastLogin
+	if err := h.db.RecordLogin(user.UserID, user.LastLogin); err != nil {
 		log.Printf("Warning: failed to update last login for user %s: %v", req.Username, err)
 	}
 
@@ -89,7 +201,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := h.jwtManager.GenerateRefreshToken(user)
+	session := &models.RefreshSession{
+		SessionID: uuid.NewString(),
+		UserID:    user.UserID,
+		IssuedAt:  models.Now(),
+		ExpiresAt: models.Now().Add(h.jwtManager.RefreshTokenExpiration()),
+	}
+	if err := h.tokens.SaveRefreshSession(session); err != nil {
+		log.Printf("Failed to save refresh session for user %s: %v", req.Username, err)
+		writeError(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken, err := h.jwtManager.GenerateRefreshToken(user, session.SessionID)
 	if err != nil {
 		log.Printf("Failed to generate refresh token for user %s: %v", req.Username, err)
 		writeError(w, "Failed to generate refresh token", http.StatusInternalServerError)
@@ -100,10 +224,60 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(LoginResponse{
+	response := LoginResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
-		User:         user,
+	}
+	if !minimal {
+		response.User = newUserProfile(user)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// captchaRequired reports whether the username or IP has reached the CAPTCHA threshold
+func (h *AuthHandler) captchaRequired(username, ip string) bool {
+	if h.captcha == nil {
+		return false
+	}
+	return h.attempts.Failures("user:"+username) >= h.captchaThreshold ||
+		h.attempts.Failures("ip:"+ip) >= h.captchaThreshold
+}
+
+// lockedUntil reports whether the username is locked out and when the lock expires
+func (h *AuthHandler) lockedUntil(username string) (time.Time, bool) {
+	if h.lockoutThreshold <= 0 || h.attempts.Failures("user:"+username) < h.lockoutThreshold {
+		return time.Time{}, false
+	}
+	return h.attempts.LastFailure("user:" + username).Add(h.attempts.Window()), true
+}
+
+// LockedUsernames returns the usernames currently locked out on this instance
+func (h *AuthHandler) LockedUsernames() []string {
+	if h.lockoutThreshold <= 0 {
+		return nil
+	}
+	return h.attempts.KeysAtLeast("user:", h.lockoutThreshold)
+}
+
+// recordLoginFailure counts a failed login against both the username and the IP
+func (h *AuthHandler) recordLoginFailure(username, ip string) {
+	h.attempts.RecordFailure("user:" + username)
+	h.attempts.RecordFailure("ip:" + ip)
+}
+
+// SetTrustedProxies sets the proxies whose forwarding headers identify the client for
+// IP-based CAPTCHA and failed-login tracking
+func (h *AuthHandler) SetTrustedProxies(proxies []netip.Prefix) {
+	h.trustedProxies = proxies
+}
+
+// writeCaptchaRequired writes a 401 that tells the client to present a CAPTCHA
+func writeCaptchaRequired(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":            message,
+		"captcha_required": true,
 	})
 }
 
@@ -118,30 +292,49 @@ type RefreshTokenResponse struct {
 // RefreshToken handles token refresh
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
 	var req RefreshTokenRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Validate refresh token
-	claims, err := h.jwtManager.ValidateToken(req.RefreshToken)
-	if err != nil {
+	// Validate refresh token; access tokens are rejected by type, and it must also be
+	// backed by a live session, which rejects revoked sessions
+	claims, err := h.jwtManager.ValidateRefreshToken(req.RefreshToken)
+	if err != nil || !auth.SessionActive(h.tokens, claims) {
 		writeError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
 		return
 	}
 
 	// Get user
 	user, err := h.db.GetUser(claims.UserID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Printf("❌ Failed to load user %s for refresh: %v", claims.UserID, err)
+		writeError(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
 	if err != nil {
 		writeError(w, "User not found", http.StatusUnauthorized)
 		return
 	}
 
+	// A password change revokes every refresh token issued before it
+	if claims.IssuedBeforePasswordChange(user) {
+		writeError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// Refreshing is not activity, so background token refreshes can't keep an idle
+	// session alive
+	if h.idle.Expired(user, time.Now()) {
+		writeError(w, "Session expired due to inactivity. Please log in again", http.StatusUnauthorized)
+		return
+	}
+
 	// Generate new access token
 	token, err := h.jwtManager.GenerateToken(user)
 	if err != nil {
@@ -157,6 +350,67 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// revokeSessions revokes every refresh session of a user after a password reset, role
+// change or deletion. A failure is logged rather than returned: the change has already
+// been stored, and refreshes re-check the stored user, so a session left behind can't
+// outlive it.
+func revokeSessions(store *db.FirestoreDB, userID string) {
+	if err := store.RevokeUserRefreshSessions(userID); err != nil {
+		log.Printf("⚠️  Failed to revoke refresh sessions of %s: %v", userID, err)
+	}
+}
+
+// TokenVerification describes a valid access token. Session and password-change
+// claims are left out; clients only need to know who the token is for and when it expires.
+type TokenVerification struct {
+	Valid     bool            `json:"valid"`
+	UserID    string          `json:"user_id"`
+	Username  string          `json:"username"`
+	Role      models.UserRole `json:"role"`
+	OrgID     string          `json:"org_id,omitempty"`
+	IssuedAt  time.Time       `json:"issued_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	ExpiresIn int64           `json:"expires_in"` // Seconds of validity left
+}
+
+// VerifyToken reports whether the presented token is still accepted, so clients can
+// refresh ahead of expiry. Validation is done by AuthMiddleware; this has no side effects.
+func (h *AuthHandler) VerifyToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+	claims, ok := middleware.GetClaimsFromContext(r.Context())
+	if !ok {
+		writeError(w, "Token claims not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	verification := TokenVerification{
+		Valid:    true,
+		UserID:   user.UserID,
+		Username: user.Username,
+		Role:     user.Role,
+		OrgID:    user.OrgID,
+	}
+	if claims.IssuedAt != nil {
+		verification.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		verification.ExpiresAt = claims.ExpiresAt.Time
+		verification.ExpiresIn = max(int64(time.Until(claims.ExpiresAt.Time).Seconds()), 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
 func writeError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -164,3 +418,90 @@ func writeError(w http.ResponseWriter, message string, status int) {
 		"error": message,
 	})
 }
+
+// writeErrorDetails writes an error response with a list of specific problems alongside the summary
+func writeErrorDetails(w http.ResponseWriter, message string, details []string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   message,
+		"details": details,
+	})
+}
+
+// writeValidationError writes a 400 for err, listing every violation when it is a password policy error
+func writeValidationError(w http.ResponseWriter, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		writeErrorDetails(w, err.Error(), policyErr.Violations, http.StatusBadRequest)
+		return
+	}
+	writeError(w, err.Error(), http.StatusBadRequest)
+}
+
+// LockoutStatus describes a user's failed-login state
+type LockoutStatus struct {
+	UserID         string     `json:"user_id"`
+	Username       string     `json:"username"`
+	FailedAttempts int        `json:"failed_attempts"`
+	Locked         bool       `json:"locked"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+}
+
+type UnlockUserRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// Lockout shows a user's failed-login state (GET ?user_id=) or clears it (POST {user_id})
+func (h *AuthHandler) Lockout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if r.Method == http.MethodPost {
+		var req UnlockUserRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		userID = req.UserID
+	}
+	if userID == "" {
+		writeError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := scopedDB(h.db, adminUser).GetUser(userID)
+	if err != nil {
+		writeLookupError(w, err, "User not found")
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		failures := h.attempts.Failures("user:" + user.Username)
+		h.attempts.Reset("user:" + user.Username)
+		log.Printf("🔓 User %s unlocked by %s (%d failed attempts cleared)", user.Username, adminUser.Username, failures)
+		middleware.SetAuditEvent(r.Context(), models.AuditActionUnlockUser, fmt.Sprintf("Admin '%s' unlocked user '%s' (%d failed attempts cleared)", adminUser.Username, user.Username, failures))
+	}
+
+	status := LockoutStatus{
+		UserID:         user.UserID,
+		Username:       user.Username,
+		FailedAttempts: h.attempts.Failures("user:" + user.Username),
+	}
+	if lockedUntil, locked := h.lockedUntil(user.Username); locked {
+		status.Locked = true
+		status.LockedUntil = &lockedUntil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gatekeeper/middleware"
+	"net/http"
+)
+
+type CORSDebugHandler struct {
+	allowedOrigins []string
+	opsOrigins     []string
+	opsPaths       []string
+}
+
+// NewCORSDebugHandler takes the same arguments as middleware.CORSMiddleware
+func NewCORSDebugHandler(allowedOrigins, opsOrigins, opsPaths []string) *CORSDebugHandler {
+	return &CORSDebugHandler{
+		allowedOrigins: allowedOrigins,
+		opsOrigins:     opsOrigins,
+		opsPaths:       opsPaths,
+	}
+}
+
+// CORS reports the effective CORS policy for ?path= (default /api/) and whether ?origin=
+// would be allowed, so frontend developers can check their origin without server logs
+func (h *CORSDebugHandler) CORS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		path = "/api/"
+	}
+
+	explanation := middleware.ExplainCORS(h.allowedOrigins, h.opsOrigins, h.opsPaths, r.URL.Query().Get("origin"), path)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(explanation)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gatekeeper/config"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+type DiagnosticsHandler struct {
+	config    config.Summary
+	startedAt time.Time
+}
+
+func NewDiagnosticsHandler(summary config.Summary) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		config:    summary,
+		startedAt: time.Now(),
+	}
+}
+
+// Dependency is a module the server binary was built with
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"` // Module path and version it was replaced with
+}
+
+// DiagnosticsResponse describes how this instance was built and configured
+type DiagnosticsResponse struct {
+	GoVersion    string         `json:"go_version"`
+	Revision     string         `json:"revision,omitempty"` // VCS commit the binary was built from, when known
+	StartedAt    time.Time      `json:"started_at"`
+	Uptime       string         `json:"uptime"`
+	Config       config.Summary `json:"config"`
+	Dependencies []Dependency   `json:"dependencies"`
+}
+
+// Diagnostics reports the build, dependency versions and non-secret configuration of
+// this instance, so support can check its setup without shell access
+func (h *DiagnosticsHandler) Diagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	response := DiagnosticsResponse{
+		StartedAt:    h.startedAt,
+		Uptime:       time.Since(h.startedAt).Round(time.Second).String(),
+		Config:       h.config,
+		Dependencies: []Dependency{},
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		response.GoVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				response.Revision = setting.Value
+			}
+		}
+		for _, dep := range info.Deps {
+			dependency := Dependency{Path: dep.Path, Version: dep.Version}
+			if dep.Replace != nil {
+				dependency.Replace = dep.Replace.Path + " " + dep.Replace.Version
+			}
+			response.Dependencies = append(response.Dependencies, dependency)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
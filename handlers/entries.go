@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gatekeeper/db"
+	"gatekeeper/httputil"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateEntryRequest is the payload for online single-entry creation.
+// Server-controlled fields (owner, status, timestamps) are never taken from the client.
+type CreateEntryRequest struct {
+	RecordID             string                 `json:"record_id,omitempty"` // Generated when absent
+	CheckpointID         string                 `json:"checkpoint_id"`
+	EntryType            models.EntryType       `json:"entry_type"`
+	ClientTS             time.Time              `json:"client_ts,omitempty"` // Defaults to the server time
+	Payload              map[string]interface{} `json:"payload"`
+	PayloadSchemaVersion int                    `json:"payload_schema_version,omitempty"` // Omit for clients that predate versioning
+}
+
+// CreateEntry creates a single entry for always-online clients, without the sync envelope
+func (h *SyncHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateEntryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.CheckpointID == "" {
+		writeError(w, "Checkpoint ID is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Payload) == 0 {
+		writeError(w, "Payload is required", http.StatusBadRequest)
+		return
+	}
+
+	now := models.Now()
+	entry := models.Entry{
+		RecordID:             req.RecordID,
+		CheckpointID:         req.CheckpointID,
+		EntryType:            req.EntryType,
+		LoggingUserID:        user.UserID,
+		ClientTS:             req.ClientTS,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		Status:               models.StatusActive,
+		OrgID:                user.OrgID,
+		Payload:              req.Payload,
+		PayloadSchemaVersion: req.PayloadSchemaVersion,
+	}
+	if entry.RecordID == "" {
+		entry.RecordID = uuid.NewString()
+	}
+	if entry.ClientTS.IsZero() {
+		entry.ClientTS = now
+	}
+
+	if err := h.validateEntry(user, &entry); err != nil {
+		writeError(w, entryRejectionMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	checkpoint, err := scopedDB(h.db, user).GetCheckpoint(entry.CheckpointID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, "Checkpoint not found", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("❌ Failed to look up checkpoint %s: %v", entry.CheckpointID, err)
+		writeError(w, "Failed to create entry", http.StatusInternalServerError)
+		return
+	}
+	if !checkpoint.AllowsEntryType(entry.EntryType) {
+		writeError(w, entryTypeNotAllowed(&entry), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.InsertEntry(&entry); err != nil {
+		if errors.Is(err, db.ErrEntryExists) {
+			writeError(w, "An entry with this record ID already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("❌ Failed to create entry %s: %v", entry.RecordID, err)
+		writeError(w, "Failed to create entry", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📝 Entry %s created by %s at %s", entry.RecordID, user.Username, entry.CheckpointID)
+	recordSync(h.db, user)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// recordSync stamps the user's last sync time in the background so the sync response
+// isn't delayed. A failure only makes sync health reports stale, so it is just logged.
+func recordSync(store syncRecorder, user *models.User) {
+	at := models.Now()
+	go func() {
+		if err := store.TouchLastSync(user.UserID, at); err != nil {
+			log.Printf("⚠️  Failed to record last sync for %s: %v", user.Username, err)
+		}
+	}()
+}
+
+// MyEntries returns the authenticated operator's own entries, newest first, so they can
+// check their shift was logged. Filters: from, to, checkpoint_id and entry_type. Pages
+// are read from Firestore with a cursor, so long-tenured operators don't load everything.
+func (h *SyncHandler) MyEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	// Supervisors and admins have the richer supervisor entry endpoints
+	if user.Role != models.RoleGateOperator {
+		writeError(w, "Only gate operators can list their own entries", http.StatusForbidden)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	startAfter := ""
+	if params.Cursor != "" {
+		if startAfter, err = decodeKeyCursor(params.Cursor); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := db.EntryFilter{
+		CheckpointID:   r.URL.Query().Get("checkpoint_id"),
+		LoggingUserID:  user.UserID,
+		IncludeDeleted: includeDeleted(r),
+	}
+	if filter.From, err = httputil.ParseTimeParam(r, "from"); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = httputil.ParseTimeParam(r, "to"); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.EntryType, err = httputil.ParseEnumParam(r, "entry_type", "", models.EntryTypes()...); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, user)
+	entries, next, err := store.ListEntries(filter, params.Limit, startAfter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, "Invalid 'cursor' parameter", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to get entries for %s: %v", user.Username, err)
+		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+		return
+	}
+
+	total, err := store.CountEntries(filter)
+	if err != nil {
+		log.Printf("❌ Failed to count entries for %s: %v", user.Username, err)
+		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+		return
+	}
+
+	writePaginated(w, entries, Pagination{
+		Total:      total,
+		Limit:      params.Limit,
+		Cursor:     params.Cursor,
+		NextCursor: encodeKeyCursor(next),
+	})
+}
+
+// MyEntryCount is the number of entries an operator logged within a time range
+type MyEntryCount struct {
+	Count int       `json:"count"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
+
+// MyEntryCount counts the authenticated operator's entries between from and to with an
+// aggregation query, for shift-progress indicators. The range defaults to the current
+// day in ?tz (UTC when absent).
+func (h *SyncHandler) MyEntryCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+	if user.Role != models.RoleGateOperator {
+		writeError(w, "Only gate operators can count their own entries", http.StatusForbidden)
+		return
+	}
+
+	loc, err := httputil.ParseLocationParam(r, "tz")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := db.EntryFilter{LoggingUserID: user.UserID, IncludeDeleted: includeDeleted(r)}
+	if filter.From, err = httputil.ParseTimeParam(r, "from"); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = httputil.ParseTimeParam(r, "to"); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().In(loc)
+	if filter.From.IsZero() {
+		filter.From = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	}
+	if filter.To.IsZero() {
+		filter.To = filter.From.AddDate(0, 0, 1)
+	}
+	if !filter.From.Before(filter.To) {
+		writeError(w, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	count, err := scopedDB(h.db, user).CountEntries(filter)
+	if err != nil {
+		log.Printf("❌ Failed to count entries for %s: %v", user.Username, err)
+		writeError(w, "Failed to count entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MyEntryCount{
+		Count: count,
+		From:  filter.From,
+		To:    filter.To,
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"slices"
+)
+
+// SetEntryStatusRequest asks for one entry's status to change
+type SetEntryStatusRequest struct {
+	RecordID string             `json:"record_id"`
+	Status   models.EntryStatus `json:"status"`
+}
+
+// errEntryNotManaged is returned when the caller may not change the entry at all
+var errEntryNotManaged = errors.New("You cannot change entries you don't manage")
+
+// SetStatus moves an entry to another status, such as deleting or restoring it. Only
+// transitions in models.CheckStatusTransition are allowed, for the roles listed there;
+// others get 409. Operators may change their own entries, supervisors those of their
+// managed operators and admins any entry of their organization.
+func (h *SyncHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetEntryStatusRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RecordID == "" {
+		writeError(w, "record_id is required", http.StatusBadRequest)
+		return
+	}
+	if !req.Status.IsValid() {
+		writeError(w, fmt.Sprintf("Invalid entry status %q", req.Status), http.StatusBadRequest)
+		return
+	}
+
+	var from models.EntryStatus
+	entry, err := scopedDB(h.db, user).SetEntryStatus(req.RecordID, req.Status, models.Now(), func(entry *models.Entry) error {
+		if !canManageEntry(user, entry) {
+			return errEntryNotManaged
+		}
+		from = entry.Status
+		return models.CheckStatusTransition(entry.Status, req.Status, user.Role)
+	})
+	var transitionErr *models.StatusTransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		writeError(w, transitionErr.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errEntryNotManaged):
+		writeError(w, errEntryNotManaged.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, "Entry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to set status of entry %s: %v", req.RecordID, err)
+		writeError(w, "Failed to update entry status", http.StatusInternalServerError)
+		return
+	}
+
+	if from != req.Status {
+		log.Printf("🔁 Entry %s status %s -> %s by %s", entry.RecordID, from, entry.Status, user.Username)
+		middleware.SetAuditEvent(r.Context(), models.AuditActionEntryStatus, fmt.Sprintf("User '%s' changed entry '%s' from %s to %s", user.Username, entry.RecordID, from, entry.Status))
+		middleware.SetAuditRecords(r.Context(), []string{entry.RecordID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// canManageEntry reports whether the user may change the entry: admins any entry of
+// their organization, everyone else their own entries and those of operators they manage
+func canManageEntry(user *models.User, entry *models.Entry) bool {
+	if user.IsAdmin() {
+		return true
+	}
+	return entry.LoggingUserID == user.UserID || slices.Contains(user.ManagedOperators, entry.LoggingUserID)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// TrailEvent is one step in the history of an entry. Source "entry" events come from the
+// entry's own timestamps; source "audit" events are audit logs that name the entry.
+type TrailEvent struct {
+	Timestamp  time.Time          `json:"timestamp"`
+	Source     string             `json:"source"`          // "entry" or "audit"
+	Event      string             `json:"event,omitempty"` // created, reviewed, flagged or last_updated for entry events
+	Action     models.AuditAction `json:"action,omitempty"`
+	UserID     string             `json:"user_id,omitempty"`
+	Details    string             `json:"details,omitempty"`
+	Route      string             `json:"route,omitempty"`
+	StatusCode int                `json:"status_code,omitempty"`
+}
+
+// EntryTrail is everything recorded about one entry, oldest first
+type EntryTrail struct {
+	Entry  *models.Entry `json:"entry"`
+	Events []TrailEvent  `json:"events"`
+}
+
+// GetEntryTrail returns an entry with a chronological timeline of what happened to it:
+// its creation, review and last update, merged with the audit events that changed it.
+// Audit logs already moved to the archive are not included.
+func (h *AdminHandler) GetEntryTrail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	recordID := r.URL.Query().Get("record_id")
+	if recordID == "" {
+		writeError(w, "record_id is required", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	entry, err := store.GetEntry(recordID)
+	if err != nil {
+		writeLookupError(w, err, "Entry not found")
+		return
+	}
+	auditLogs, err := store.GetAuditLogsForRecord(recordID)
+	if err != nil {
+		log.Printf("❌ Failed to get audit logs of entry %s: %v", recordID, err)
+		writeError(w, "Failed to retrieve entry trail", http.StatusInternalServerError)
+		return
+	}
+
+	events := []TrailEvent{{
+		Timestamp: entry.CreatedAt,
+		Source:    "entry",
+		Event:     "created",
+		UserID:    entry.LoggingUserID,
+		Details:   "Client time " + entry.ClientTS.UTC().Format(time.RFC3339),
+	}}
+	if !entry.ReviewedAt.IsZero() && entry.ReviewedBy != "" {
+		review := TrailEvent{Timestamp: entry.ReviewedAt, Source: "entry", Event: "reviewed", UserID: entry.ReviewedBy}
+		if entry.FlagReason != "" {
+			review.Event, review.Details = "flagged", entry.FlagReason
+		}
+		events = append(events, review)
+	}
+	if entry.UpdatedAt.After(entry.CreatedAt) {
+		events = append(events, TrailEvent{
+			Timestamp: entry.UpdatedAt,
+			Source:    "entry",
+			Event:     "last_updated",
+			Details:   "Status " + string(entry.Status),
+		})
+	}
+	for _, auditLog := range auditLogs {
+		at, err := time.Parse(time.RFC3339, auditLog.Timestamp)
+		if err != nil {
+			log.Printf("⚠️  Audit log %s has unparseable timestamp %q", auditLog.LogID, auditLog.Timestamp)
+			continue
+		}
+		events = append(events, TrailEvent{
+			Timestamp:  at,
+			Source:     "audit",
+			Action:     auditLog.Action,
+			UserID:     auditLog.UserID,
+			Details:    auditLog.Details,
+			Route:      auditLog.Route,
+			StatusCode: auditLog.StatusCode,
+		})
+	}
+	slices.SortStableFunc(events, func(a, b TrailEvent) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EntryTrail{Entry: entry, Events: events})
+}
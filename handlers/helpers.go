@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isDryRun reports whether the request asked for a dry run via ?dry_run=true.
+// Bulk destructive endpoints must validate and report as usual but skip all writes.
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// includeDeleted reports whether a view read asked for tombstoned entries via
+// ?include_deleted=true. Sync reads always include them so clients mirror deletions.
+func includeDeleted(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	return include
+}
+
+// entriesETag computes a weak ETag from the newest updated_at and the number of entries.
+// The variant distinguishes different representations of the same result set (e.g. projections).
+func entriesETag(entries []models.Entry, variant string) string {
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.UpdatedAt.After(latest) {
+			latest = entry.UpdatedAt
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(variant))
+	return fmt.Sprintf(`W/"%d-%d-%x"`, latest.UnixNano(), len(entries), h.Sum32())
+}
+
+// contentETag computes a weak ETag from the JSON encoding of v, for data without timestamps
+func contentETag(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`W/"%x"`, sum[:16]), nil
+}
+
+// checkNotModified sets the ETag header and replies 304 Not Modified when the
+// client's If-None-Match already matches it. It returns true if the response was written.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// Weak comparison: ignore the W/ prefix on both sides
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopedDB restricts database access to the user's organization.
+// Super admins manage every organization and get the unscoped view.
+func scopedDB(base *db.FirestoreDB, user *models.User) *db.FirestoreDB {
+	if user.Role == models.RoleSuperAdmin {
+		return base
+	}
+	return base.ForOrg(user.OrgID)
+}
+
+// formatExportTime renders a timestamp for file exports in the requested zone.
+// RFC3339 keeps the UTC offset explicit, so the zone is never ambiguous.
+func formatExportTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// errBodyTooLarge is returned by decodeJSON when the body exceeds a limit set with
+// http.MaxBytesReader
+var errBodyTooLarge = errors.New("Request body is too large")
+
+// decodeJSON decodes a JSON request body into v, rejecting fields v does not declare so
+// misspelled or stale fields fail loudly instead of being ignored. The error message says
+// what was wrong and is safe to return to the client.
+func decodeJSON(r *http.Request, v interface{}) error {
+	return decodeRequestBody(r, v, true)
+}
+
+// decodeRequestBody decodes a JSON request body into v, optionally rejecting unknown
+// fields, and translates decoder errors into messages naming the offending field or offset
+func decodeRequestBody(r *http.Request, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(r.Body)
+	// Keeps numbers in entry payloads exact until validateEntry converts them
+	decoder.UseNumber()
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return fmt.Errorf("%w: the limit is %d bytes", errBodyTooLarge, tooLarge.Limit)
+	case errors.Is(err, io.EOF):
+		return errors.New("Invalid request body: body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("Invalid request body: unexpected end of JSON")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("Invalid request body: malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Errorf("Invalid request body: field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return fmt.Errorf("Invalid request body: expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("Invalid request body: unknown field %s", field)
+	}
+	return errors.New("Invalid request body")
+}
+
+// writeLookupError answers a failed lookup of a single record: 404 with notFound when the
+// record doesn't exist or is outside the caller's organization, and 500 for any other
+// failure, so a database outage is never reported as a missing record
+func writeLookupError(w http.ResponseWriter, err error, notFound string) {
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, notFound, http.StatusNotFound)
+		return
+	}
+	log.Printf("❌ Lookup failed: %v", err)
+	writeError(w, "Failed to retrieve record", http.StatusInternalServerError)
+}
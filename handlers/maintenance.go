@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/httputil"
+	"gatekeeper/jobs"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type MaintenanceHandler struct {
+	db           *db.FirestoreDB
+	retentionJob *jobs.RetentionJob
+}
+
+func NewMaintenanceHandler(firestoreDB *db.FirestoreDB, retentionJob *jobs.RetentionJob) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		db:           firestoreDB,
+		retentionJob: retentionJob,
+	}
+}
+
+// backfillBudget is how long one backfill request keeps scanning, well within the
+// server's write timeout; the response cursor picks up where it stopped
+const backfillBudget = 10 * time.Second
+
+// BackfillResponse reports the progress of a backfill request
+type BackfillResponse struct {
+	Field      string `json:"field"`
+	Scanned    int    `json:"scanned"`
+	Updated    int    `json:"updated"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as ?cursor= to continue
+	Done       bool   `json:"done"`
+}
+
+// PurgeEntries runs one batch of the entry retention purge immediately, so the request
+// finishes well within the server's write timeout. Repeat it until done is true.
+func (h *MaintenanceHandler) PurgeEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.retentionJob.RunBatch(adminUser.UserID)
+	if errors.Is(err, jobs.ErrRetentionDisabled) {
+		writeError(w, "Entry retention is disabled (ENTRY_RETENTION_DAYS is not set)", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Manual entry purge by %s failed: %v", adminUser.Username, err)
+		writeError(w, "Failed to purge entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Backfill fills in an entry field that older entries lack, such as sequence. Each
+// request scans entries in pages for up to backfillBudget and returns a cursor to resume
+// from; repeat with ?cursor= until done is true. Only entries missing the field are
+// written, so a backfill can be re-run or resumed safely. Organization admins backfill
+// their own organization's entries.
+func (h *MaintenanceHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	field := query.Get("field")
+	if _, ok := db.BackfillFields[field]; !ok {
+		fields := make([]string, 0, len(db.BackfillFields))
+		for name, fill := range db.BackfillFields {
+			fields = append(fields, fmt.Sprintf("%s (%s)", name, fill))
+		}
+		slices.Sort(fields)
+		writeError(w, "field must be one of: "+strings.Join(fields, ", "), http.StatusBadRequest)
+		return
+	}
+	cursor := ""
+	if query.Get("cursor") != "" {
+		var err error
+		if cursor, err = decodeKeyCursor(query.Get("cursor")); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit, err := httputil.ParseIntParam(r, "limit", db.MaxEntriesPerTransaction, 1, db.MaxEntriesPerTransaction)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser).WithContext(r.Context())
+	response := BackfillResponse{Field: field}
+	started := time.Now()
+	for {
+		page, err := store.BackfillEntries(field, cursor, limit)
+		if page != nil {
+			response.Scanned += page.Scanned
+			response.Updated += page.Updated
+		}
+		if err != nil {
+			log.Printf("❌ Backfill of %s by %s failed after %d updates: %v", field, adminUser.Username, response.Updated, err)
+			middleware.SetAuditEvent(r.Context(), models.AuditActionBackfillEntries, fmt.Sprintf("Admin '%s' backfilled %s on %d entries before failing", adminUser.Username, field, response.Updated))
+			writeError(w, "Failed to backfill entries; retry with the same cursor", http.StatusInternalServerError)
+			return
+		}
+		cursor = page.Next
+		if cursor == "" || time.Since(started) > backfillBudget {
+			break
+		}
+	}
+	response.NextCursor = encodeKeyCursor(cursor)
+	response.Done = cursor == ""
+
+	log.Printf("🔧 Backfill of %s by %s: %d scanned, %d updated (done: %t)", field, adminUser.Username, response.Scanned, response.Updated, response.Done)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionBackfillEntries, fmt.Sprintf("Admin '%s' backfilled %s on %d of %d scanned entries (done: %t)", adminUser.Username, field, response.Updated, response.Scanned, response.Done))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes for routing failures, so clients can tell them apart from handler errors
+const (
+	ErrorCodeNotFound         = "NOT_FOUND"
+	ErrorCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+)
+
+// NotFound answers requests for unregistered paths with the usual JSON error shape
+// instead of the router's plain-text 404. Register it on "/" so it catches every
+// unmatched path while still running behind the global middleware (CORS included).
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeErrorCode(w, "Route not found: "+r.URL.Path, ErrorCodeNotFound, http.StatusNotFound)
+}
+
+// MethodNotAllowed answers requests whose method the route does not support
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeErrorCode(w, "Method not allowed", ErrorCodeMethodNotAllowed, http.StatusMethodNotAllowed)
+}
+
+// writeErrorCode writes an error response carrying a machine-readable code
+func writeErrorCode(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": message,
+		"code":  code,
+	})
+}
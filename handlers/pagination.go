@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gatekeeper/httputil"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Pagination describes which slice of a list a response holds
+type Pagination struct {
+	Total      int    `json:"total"`       // Items matching the query across all pages
+	Limit      int    `json:"limit"`       // Effective page size after defaults and clamping
+	Cursor     string `json:"cursor"`      // Cursor this page was requested with
+	NextCursor string `json:"next_cursor"` // Cursor for the following page; empty on the last page
+}
+
+// PaginatedResponse is the envelope shared by every list endpoint
+type PaginatedResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// PageParams holds the limit and cursor query parameters of a list request
+type PageParams struct {
+	Limit  int
+	Cursor string
+}
+
+// Page sizes applied by parsePageParams; see ConfigurePagination
+var (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// ConfigurePagination sets the page size used when a list request has no limit
+// and the largest limit a client may request
+func ConfigurePagination(defaultSize, maxSize int) {
+	defaultPageSize = defaultSize
+	maxPageSize = maxSize
+}
+
+// parsePageParams reads the limit and cursor query parameters. A missing limit gets
+// the default page size and an oversized one is clamped rather than rejected.
+func parsePageParams(r *http.Request) (PageParams, error) {
+	params := PageParams{Cursor: r.URL.Query().Get("cursor")}
+
+	var err error
+	params.Limit, err = httputil.ParseIntParam(r, "limit", defaultPageSize, 1, math.MaxInt32)
+	params.Limit = min(params.Limit, maxPageSize)
+	return params, err
+}
+
+// encodeOffsetCursor and decodeOffsetCursor turn a list offset into an opaque cursor
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("Invalid 'cursor' parameter")
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, errors.New("Invalid 'cursor' parameter")
+	}
+	return offset, nil
+}
+
+// encodeKeyCursor and decodeKeyCursor wrap a document ID used as a query position
+func encodeKeyCursor(key string) string {
+	if key == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeKeyCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) == 0 {
+		return "", errors.New("Invalid 'cursor' parameter")
+	}
+	return string(data), nil
+}
+
+// paginate returns the page of an already loaded list selected by params
+func paginate[T any](items []T, params PageParams) ([]T, Pagination, error) {
+	pagination := Pagination{
+		Total:  len(items),
+		Limit:  params.Limit,
+		Cursor: params.Cursor,
+	}
+
+	start := 0
+	if params.Cursor != "" {
+		offset, err := decodeOffsetCursor(params.Cursor)
+		if err != nil {
+			return nil, pagination, err
+		}
+		start = min(offset, len(items))
+	}
+
+	end := len(items)
+	if params.Limit > 0 && start+params.Limit < end {
+		end = start + params.Limit
+		pagination.NextCursor = encodeOffsetCursor(end)
+	}
+
+	page := items[start:end]
+	if page == nil {
+		page = []T{}
+	}
+	return page, pagination, nil
+}
+
+// writePaginated writes a list response in the shared envelope
+func writePaginated(w http.ResponseWriter, data interface{}, pagination Pagination) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PaginatedResponse{
+		Data:       data,
+		Pagination: pagination,
+	})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"net/http"
+)
+
+type RateLimitHandler struct {
+	limiter *middleware.RateLimiter
+}
+
+func NewRateLimitHandler(limiter *middleware.RateLimiter) *RateLimitHandler {
+	return &RateLimitHandler{
+		limiter: limiter,
+	}
+}
+
+// Stats lists the allowed and denied request counts of each IP seen within the last
+// hour, most denied first; ?denied_only=true leaves out IPs that were never throttled.
+// Counters are kept per instance and span every organization, so admins of a single
+// organization in a multi-tenant deployment may not read them.
+func (h *RateLimitHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+	if user.OrgID != "" && user.Role != models.RoleSuperAdmin {
+		writeError(w, "Rate limit counters span all organizations and are only available to super admins", http.StatusForbidden)
+		return
+	}
+
+	stats := h.limiter.Stats()
+	if r.URL.Query().Get("denied_only") == "true" {
+		throttled := make([]middleware.ClientRateStats, 0, len(stats))
+		for _, s := range stats {
+			if s.Denied > 0 {
+				throttled = append(throttled, s)
+			}
+		}
+		stats = throttled
+	}
+
+	page, pagination, err := paginate(stats, params)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writePaginated(w, page, pagination)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/auth"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// maxBulkPasswordResets keeps a request within the server's write timeout; each reset
+// costs a deliberately slow bcrypt hash
+const maxBulkPasswordResets = 20
+
+// bulkResetConcurrency bounds the bcrypt hashes computed in parallel per request
+const bulkResetConcurrency = 4
+
+// BulkResetPasswordsRequest lists the users whose passwords should be reset
+type BulkResetPasswordsRequest struct {
+	Users []struct {
+		UserID string `json:"user_id"`
+	} `json:"users"`
+}
+
+// BulkResetPasswordsResponse maps each reset user ID to its generated one-time password,
+// and each refused user ID to the reason
+type BulkResetPasswordsResponse struct {
+	Passwords map[string]string `json:"passwords"`
+	Rejected  map[string]string `json:"rejected,omitempty"`
+}
+
+// ResetPasswords resets several users' passwords to generated one-time passwords, for
+// shift rotations. Each user is authorized like ResetPassword; users that can't be reset
+// are reported in rejected without failing the rest. Every reset is audited on its own.
+func (h *SupervisorHandler) ResetPasswords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	supervisor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req BulkResetPasswordsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Users) == 0 {
+		writeError(w, "users is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Users) > maxBulkPasswordResets {
+		writeError(w, fmt.Sprintf("At most %d passwords can be reset per request", maxBulkPasswordResets), http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, supervisor)
+	response := BulkResetPasswordsResponse{Passwords: make(map[string]string)}
+	var mu sync.Mutex
+	reject := func(userID, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if response.Rejected == nil {
+			response.Rejected = make(map[string]string)
+		}
+		response.Rejected[userID] = reason
+	}
+
+	seen := make(map[string]bool, len(req.Users))
+	sem := make(chan struct{}, bulkResetConcurrency)
+	var wg sync.WaitGroup
+	for _, u := range req.Users {
+		userID := u.UserID
+		switch {
+		case userID == "":
+			reject(userID, "User ID is required")
+			continue
+		case seen[userID]:
+			continue
+		}
+		seen[userID] = true
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			password, err := h.resetToGeneratedPassword(store, supervisor, userID)
+			if err != nil {
+				reject(userID, err.Error())
+				return
+			}
+			mu.Lock()
+			response.Passwords[userID] = password
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// One audit event per reset, so each appears in the target user's history
+	for userID := range response.Passwords {
+		middleware.WriteAuditLog(h.db, r, models.AuditActionResetPassword, fmt.Sprintf("Password of user '%s' reset to a generated password", userID), http.StatusOK)
+	}
+
+	log.Printf("🔑 Bulk password reset by %s: %d reset, %d rejected", supervisor.Username, len(response.Passwords), len(response.Rejected))
+
+	// Generated passwords must never be cached along the way
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// resetToGeneratedPassword checks that supervisor may reset the user's password, then
+// replaces it with a generated one. Errors are safe to show to the caller.
+func (h *SupervisorHandler) resetToGeneratedPassword(store *db.FirestoreDB, supervisor *models.User, userID string) (string, error) {
+	target, err := store.GetUser(userID)
+	if errors.Is(err, db.ErrNotFound) {
+		return "", errors.New("User not found")
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up user %s: %v", userID, err)
+		return "", errors.New("Failed to retrieve user")
+	}
+	if !canResetPassword(supervisor, userID) {
+		return "", errors.New("You can only reset passwords for operators you manage")
+	}
+
+	password, err := auth.GeneratePassword()
+	if err != nil {
+		log.Printf("❌ Failed to generate password for %s: %v", target.Username, err)
+		return "", errors.New("Failed to generate password")
+	}
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("❌ Failed to hash password for %s: %v", target.Username, err)
+		return "", errors.New("Failed to hash password")
+	}
+	if err := store.StorePasswordHash(userID, passwordHash); err != nil {
+		log.Printf("❌ Failed to store password for %s: %v", target.Username, err)
+		return "", errors.New("Failed to update password")
+	}
+	revokeSessions(store, userID)
+	return password, nil
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// Conflict resolutions accepted by ResolveEntry
+const (
+	resolutionKeepServer = "keep_server" // Keep the stored entry; the client should take it over
+	resolutionKeepClient = "keep_client" // Replace the stored entry with the client's copy
+	resolutionMerge      = "merge"       // The client's copy, with payload fields it lacks kept from the stored entry
+)
+
+// ResolveEntryRequest settles a conflict between the stored entry and the client's copy
+type ResolveEntryRequest struct {
+	RecordID   string        `json:"record_id"`
+	Resolution string        `json:"resolution"`
+	Entry      *models.Entry `json:"entry,omitempty"` // The client's copy; required for keep_client and merge
+	// ServerUpdatedAt is the updated_at of the stored entry the user compared against.
+	// When set, the resolution is refused with 409 if the entry changed since.
+	ServerUpdatedAt time.Time `json:"server_updated_at"`
+}
+
+var (
+	// errEntryNotOwned is returned when the caller did not log the entry
+	errEntryNotOwned = errors.New("You can only resolve conflicts on your own entries")
+	// errServerCopyChanged is returned when the stored entry changed after the user compared it
+	errServerCopyChanged = errors.New("The server copy changed since it was compared; review it again")
+	// errCheckpointChanged is returned when the client's copy names another checkpoint
+	errCheckpointChanged = errors.New("An entry cannot move to another checkpoint")
+)
+
+// ResolveEntry settles a conflict between a stored entry and the client's copy of it:
+// keep_server leaves the stored entry as is, keep_client replaces it with the client's
+// copy and merge does the same but keeps payload fields the client's copy lacks. The
+// choice is applied in a transaction against the stored entry, so with server_updated_at
+// set a concurrent change is reported instead of overwritten. Only the user who logged
+// the entry may resolve it, and the client's copy is validated like a pushed entry.
+// Returns the entry as stored afterwards.
+func (h *SyncHandler) ResolveEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ResolveEntryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RecordID == "" {
+		writeError(w, "record_id is required", http.StatusBadRequest)
+		return
+	}
+	switch req.Resolution {
+	case resolutionKeepServer:
+	case resolutionKeepClient, resolutionMerge:
+		if req.Entry == nil {
+			writeError(w, fmt.Sprintf("entry is required for %s", req.Resolution), http.StatusBadRequest)
+			return
+		}
+		req.Entry.RecordID = req.RecordID
+	default:
+		writeError(w, "resolution must be keep_server, keep_client or merge", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, user).WithContext(r.Context())
+	if req.Entry != nil {
+		// Entries may not move between checkpoints (the resolve below rejects a changed one),
+		// so this is also the stored entry's checkpoint, checked once outside the transaction
+		checkpoint, err := newPushCheckpoints(store).get(req.Entry.CheckpointID)
+		if err != nil {
+			log.Printf("❌ Failed to look up checkpoint %s: %v", req.Entry.CheckpointID, err)
+			writeError(w, "Failed to resolve entry", http.StatusInternalServerError)
+			return
+		}
+		if checkpoint != nil && !checkpoint.AllowsEntryType(req.Entry.EntryType) {
+			writeError(w, entryTypeNotAllowed(req.Entry), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entry, err := store.ReplaceEntry(req.RecordID, user.Role, models.Now(), func(stored *models.Entry) (*models.Entry, error) {
+		if stored.LoggingUserID != user.UserID {
+			return nil, errEntryNotOwned
+		}
+		if !req.ServerUpdatedAt.IsZero() && !req.ServerUpdatedAt.Equal(stored.UpdatedAt) {
+			return nil, errServerCopyChanged
+		}
+		if req.Resolution == resolutionKeepServer {
+			return nil, nil
+		}
+
+		// The transaction may run more than once, so it works on a copy
+		resolved := *req.Entry
+		if resolved.CheckpointID != stored.CheckpointID {
+			return nil, errCheckpointChanged
+		}
+		if req.Resolution == resolutionMerge {
+			payload := maps.Clone(stored.Payload)
+			if payload == nil {
+				payload = make(map[string]interface{})
+			}
+			maps.Copy(payload, req.Entry.Payload)
+			resolved.Payload = payload
+		} else {
+			resolved.Payload = maps.Clone(req.Entry.Payload)
+		}
+		if err := h.validateEntry(user, &resolved); err != nil {
+			return nil, &resolveValidationError{err}
+		}
+		return &resolved, nil
+	})
+
+	var validationErr *resolveValidationError
+	var accessErr *entryAccessError
+	var transitionErr *models.StatusTransitionError
+	switch {
+	case errors.As(err, &validationErr) && errors.As(err, &accessErr):
+		writeError(w, accessErr.message, http.StatusForbidden)
+		return
+	case errors.As(err, &validationErr):
+		writeError(w, entryRejectionMessage(validationErr.err), http.StatusBadRequest)
+		return
+	case errors.Is(err, errCheckpointChanged):
+		writeError(w, errCheckpointChanged.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errEntryNotOwned):
+		writeError(w, errEntryNotOwned.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, errServerCopyChanged):
+		writeError(w, errServerCopyChanged.Error(), http.StatusConflict)
+		return
+	case errors.As(err, &transitionErr):
+		writeError(w, transitionErr.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, db.ErrWriteConflict):
+		writeError(w, "The entry changed while the resolution was applied; retry it", http.StatusConflict)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, "Entry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to resolve entry %s: %v", req.RecordID, err)
+		writeError(w, "Failed to resolve entry", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🔀 Entry %s conflict resolved by %s: %s", entry.RecordID, user.Username, req.Resolution)
+	if req.Resolution != resolutionKeepServer {
+		middleware.SetAuditEvent(r.Context(), models.AuditActionResolveEntry, fmt.Sprintf("User '%s' resolved a conflict on entry '%s' with %s", user.Username, entry.RecordID, req.Resolution))
+		middleware.SetAuditRecords(r.Context(), []string{entry.RecordID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// resolveValidationError marks a client copy that validateEntry rejected
+type resolveValidationError struct {
+	err error
+}
+
+func (e *resolveValidationError) Error() string { return e.err.Error() }
+func (e *resolveValidationError) Unwrap() error { return e.err }
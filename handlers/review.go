@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxReviewIDs keeps a review within one batched write
+const maxReviewIDs = 500
+
+// maxFlagReasonLength bounds the free-text reason stored on each flagged entry
+const maxFlagReasonLength = 500
+
+// ReviewEntriesRequest sets the review state of entries. A flag_reason marks the entries
+// for follow-up; reviewed false with no flag_reason clears the review.
+type ReviewEntriesRequest struct {
+	RecordIDs  []string `json:"record_ids"`
+	Reviewed   bool     `json:"reviewed"`
+	FlagReason string   `json:"flag_reason,omitempty"`
+}
+
+// ReviewEntriesResponse reports the review state applied to the entries
+type ReviewEntriesResponse struct {
+	RecordIDs  []string  `json:"record_ids"`
+	Reviewed   bool      `json:"reviewed"`
+	ReviewedBy string    `json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at"`
+	FlagReason string    `json:"flag_reason,omitempty"`
+}
+
+// ReviewEntries marks entries as reviewed or flagged for follow-up. Supervisors may only
+// review entries of operators they manage; admins any entry of their organization.
+// Every entry is checked before any is changed, so the request applies fully or not at all.
+func (h *SupervisorHandler) ReviewEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ReviewEntriesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.FlagReason = strings.TrimSpace(req.FlagReason)
+	if len(req.RecordIDs) == 0 {
+		writeError(w, "record_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.RecordIDs) > maxReviewIDs {
+		writeError(w, fmt.Sprintf("At most %d entries can be reviewed per request", maxReviewIDs), http.StatusBadRequest)
+		return
+	}
+	if len(req.FlagReason) > maxFlagReasonLength {
+		writeError(w, fmt.Sprintf("flag_reason must be at most %d characters", maxFlagReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, user)
+	recordIDs := slices.Compact(slices.Sorted(slices.Values(req.RecordIDs)))
+	for _, recordID := range recordIDs {
+		entry, err := store.GetEntry(recordID)
+		if err != nil {
+			writeLookupError(w, err, fmt.Sprintf("Entry %s not found", recordID))
+			return
+		}
+		if !user.IsAdmin() && !slices.Contains(user.ManagedOperators, entry.LoggingUserID) {
+			writeError(w, fmt.Sprintf("Entry %s was not logged by an operator you manage", recordID), http.StatusForbidden)
+			return
+		}
+	}
+
+	review := db.EntryReview{
+		Reviewed:   req.Reviewed,
+		ReviewedAt: models.Now(),
+		FlagReason: req.FlagReason,
+	}
+	if req.Reviewed || req.FlagReason != "" {
+		review.ReviewedBy = user.UserID
+	}
+	if err := store.ReviewEntries(recordIDs, review); err != nil {
+		log.Printf("❌ Failed to review entries for %s: %v", user.Username, err)
+		writeError(w, "Failed to review entries", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📝 %s reviewed %d entries (reviewed=%t, flagged=%t)", user.Username, len(recordIDs), review.Reviewed, review.FlagReason != "")
+	middleware.SetAuditEvent(r.Context(), models.AuditActionReviewEntries, fmt.Sprintf("User '%s' set reviewed=%t flag_reason=%q on %d entries", user.Username, review.Reviewed, review.FlagReason, len(recordIDs)))
+	middleware.SetAuditRecords(r.Context(), recordIDs)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReviewEntriesResponse{
+		RecordIDs:  recordIDs,
+		Reviewed:   review.Reviewed,
+		ReviewedBy: review.ReviewedBy,
+		ReviewedAt: review.ReviewedAt,
+		FlagReason: review.FlagReason,
+	})
+}
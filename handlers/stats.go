@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"time"
+)
+
+// activeUserWindow is how recently a user must have logged in to count as active
+const activeUserWindow = 30 * 24 * time.Hour
+
+// AdminStats summarizes the size of an organization's data
+type AdminStats struct {
+	Users          int                     `json:"users"`
+	UsersByRole    map[models.UserRole]int `json:"users_by_role"`
+	ActiveUsers    int                     `json:"active_users"`   // Logged in within the last 30 days
+	InactiveUsers  int                     `json:"inactive_users"` // Everyone else, including users who never logged in
+	LockedAccounts int                     `json:"locked_accounts"`
+	Checkpoints    int                     `json:"checkpoints"`
+	Entries        int                     `json:"entries"`
+	EntriesLast24h int                     `json:"entries_last_24h"`
+}
+
+// LockoutSource reports the usernames currently locked out after failed logins
+type LockoutSource interface {
+	LockedUsernames() []string
+}
+
+// SetLockoutSource sets where Stats reads locked accounts from; without one it reports none
+func (h *AdminHandler) SetLockoutSource(source LockoutSource) {
+	h.lockouts = source
+}
+
+// Stats returns user, checkpoint and entry counts. Counts come from aggregation
+// queries, so the cost does not grow with the number of documents counted.
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	stats := AdminStats{UsersByRole: map[models.UserRole]int{}}
+
+	var err error
+	if stats.Users, err = store.CountUsers(db.UserFilter{}); err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	for _, role := range []models.UserRole{models.RoleSuperAdmin, models.RoleAdmin, models.RoleSupervisor, models.RoleGateOperator} {
+		if stats.UsersByRole[role], err = store.CountUsers(db.UserFilter{Role: role}); err != nil {
+			writeStatsError(w, err)
+			return
+		}
+	}
+
+	if stats.ActiveUsers, err = store.CountUsers(db.UserFilter{LoggedInSince: time.Now().Add(-activeUserWindow)}); err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	stats.InactiveUsers = stats.Users - stats.ActiveUsers
+	if stats.LockedAccounts, err = h.countLockedAccounts(store); err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	// Checkpoints are served from the checkpoint cache, so counting the list is cheapest
+	checkpoints, err := store.GetAllCheckpoints()
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	stats.Checkpoints = len(checkpoints)
+
+	// Tombstoned entries are left out unless ?include_deleted=true
+	withDeleted := includeDeleted(r)
+	if stats.Entries, err = store.CountEntries(db.EntryFilter{IncludeDeleted: withDeleted}); err != nil {
+		writeStatsError(w, err)
+		return
+	}
+	if stats.EntriesLast24h, err = store.CountEntries(db.EntryFilter{From: time.Now().Add(-24 * time.Hour), IncludeDeleted: withDeleted}); err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// usernameLookup finds users by username within an organization; *db.FirestoreDB implements it
+type usernameLookup interface {
+	GetUserByUsername(username string) (*models.User, error)
+}
+
+// countLockedAccounts counts the locked-out usernames that belong to the store's
+// organization. Lockouts are tracked in memory, so this reflects this instance only;
+// the few locked usernames are looked up individually to apply the org scope.
+func (h *AdminHandler) countLockedAccounts(store usernameLookup) (int, error) {
+	if h.lockouts == nil {
+		return 0, nil
+	}
+
+	locked := 0
+	for _, username := range h.lockouts.LockedUsernames() {
+		_, err := store.GetUserByUsername(username)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		locked++
+	}
+	return locked, nil
+}
+
+func writeStatsError(w http.ResponseWriter, err error) {
+	log.Printf("❌ Failed to compute stats: %v", err)
+	writeError(w, "Failed to compute stats", http.StatusInternalServerError)
+}
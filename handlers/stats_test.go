@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"testing"
+)
+
+// orgUsers looks users up by username within one organization, like an org-scoped view
+type orgUsers struct {
+	orgID string
+	users map[string]*models.User
+}
+
+func (o *orgUsers) GetUserByUsername(username string) (*models.User, error) {
+	user, ok := o.users[username]
+	if !ok || user.OrgID != o.orgID {
+		return nil, fmt.Errorf("%w: %s", db.ErrUserNotFound, username)
+	}
+	return user, nil
+}
+
+type lockedUsernames []string
+
+func (l lockedUsernames) LockedUsernames() []string { return l }
+
+func TestCountLockedAccountsOnlyCountsOwnOrg(t *testing.T) {
+	users := map[string]*models.User{
+		"east_op":  {Username: "east_op", OrgID: "org-1"},
+		"east_sup": {Username: "east_sup", OrgID: "org-1"},
+		"west_op":  {Username: "west_op", OrgID: "org-2"},
+	}
+	h := &AdminHandler{}
+	h.SetLockoutSource(lockedUsernames{"east_op", "west_op", "east_sup", "deleted_user"})
+
+	for orgID, want := range map[string]int{"org-1": 2, "org-2": 1} {
+		got, err := h.countLockedAccounts(&orgUsers{orgID: orgID, users: users})
+		if err != nil {
+			t.Fatalf("countLockedAccounts(%s): %v", orgID, err)
+		}
+		if got != want {
+			t.Errorf("locked accounts in %s = %d, want %d", orgID, got, want)
+		}
+	}
+}
@@ -3,18 +3,25 @@ package handlers
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gatekeeper/auth"
 	"gatekeeper/db"
+	"gatekeeper/exports"
+	"gatekeeper/httputil"
 	"gatekeeper/middleware"
 	"gatekeeper/models"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 )
 
 type SupervisorHandler struct {
-	db *db.FirestoreDB
+	db      *db.FirestoreDB
+	exports *exports.Uploader // nil when storage export is not configured
 }
 
 func NewSupervisorHandler(firestoreDB *db.FirestoreDB) *SupervisorHandler {
@@ -23,10 +30,21 @@ func NewSupervisorHandler(firestoreDB *db.FirestoreDB) *SupervisorHandler {
 	}
 }
 
+// EnableStorageExport turns on exports uploaded to Cloud Storage
+func (h *SupervisorHandler) EnableStorageExport(uploader *exports.Uploader) {
+	h.exports = uploader
+}
+
 // GetEntries returns entries filtered by role
 func (h *SupervisorHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -37,27 +55,130 @@ func (h *SupervisorHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all entries
-	entries, err := h.db.GetAllEntries()
+	entries, err := scopedDB(h.db, user).GetAllEntries()
 	if err != nil {
 		log.Printf("❌ Failed to get entries: %v", err)
 		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
 		return
 	}
 
-	// Filter based on role
-	filteredEntries := filterEntriesByRole(entries, user)
+	// Filter based on role, hiding tombstones unless requested
+	filteredEntries := filterEntriesForView(entries, user, includeDeleted(r))
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"entries": filteredEntries,
-		"count":   len(filteredEntries),
+	page, pagination, err := paginate(filteredEntries, params)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writePaginated(w, page, pagination)
+}
+
+// GetCheckpointEntries pages through a single checkpoint's entries, newest first, using
+// the checkpoint_id query instead of loading every entry and filtering. Supervisors must
+// be authorized for the checkpoint directly or through one of their operators.
+func (h *SupervisorHandler) GetCheckpointEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	checkpointID := r.URL.Query().Get("checkpoint_id")
+	if checkpointID == "" {
+		writeError(w, "Checkpoint ID is required", http.StatusBadRequest)
+		return
+	}
+	startAfter := ""
+	if params.Cursor != "" {
+		if startAfter, err = decodeKeyCursor(params.Cursor); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	store := scopedDB(h.db, user)
+	if _, err := store.GetCheckpoint(checkpointID); err != nil {
+		writeLookupError(w, err, "Checkpoint not found")
+		return
+	}
+	if !h.checkpointInScope(store, user, checkpointID) {
+		writeError(w, "Checkpoint is not in your scope", http.StatusForbidden)
+		return
+	}
+
+	filter := db.EntryFilter{CheckpointID: checkpointID, IncludeDeleted: includeDeleted(r)}
+	entries, next, err := store.ListEntries(filter, params.Limit, startAfter)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, "Invalid 'cursor' parameter", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to get entries for checkpoint %s: %v", checkpointID, err)
+		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+		return
+	}
+
+	total, err := store.CountEntries(filter)
+	if err != nil {
+		log.Printf("❌ Failed to count entries for checkpoint %s: %v", checkpointID, err)
+		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+		return
+	}
+
+	writePaginated(w, entries, Pagination{
+		Total:      total,
+		Limit:      params.Limit,
+		Cursor:     params.Cursor,
+		NextCursor: encodeKeyCursor(next),
 	})
 }
 
+// checkpointInScope reports whether the user may monitor the checkpoint. Admins see every
+// checkpoint of their organization; supervisors those they or a managed operator are allowed at.
+func (h *SupervisorHandler) checkpointInScope(store *db.FirestoreDB, user *models.User, checkpointID string) bool {
+	if user.Role == models.RoleAdmin || user.Role == models.RoleSuperAdmin {
+		return true
+	}
+	if slices.Contains(user.AllowedCheckpoints, checkpointID) {
+		return true
+	}
+	if len(user.ManagedOperators) == 0 {
+		return false
+	}
+	operators, err := store.GetUsersByIDs(user.ManagedOperators)
+	if err != nil {
+		log.Printf("⚠️  Failed to load managed operators of %s: %v", user.Username, err)
+		return false
+	}
+	for _, operator := range operators {
+		if slices.Contains(operator.AllowedCheckpoints, checkpointID) {
+			return true
+		}
+	}
+	return false
+}
+
 // ExportEntries exports entries to CSV
 func (h *SupervisorHandler) ExportEntries(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	loc, err := httputil.ParseLocationParam(r, "tz")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -68,15 +189,15 @@ func (h *SupervisorHandler) ExportEntries(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get all entries
-	entries, err := h.db.GetAllEntries()
+	entries, err := scopedDB(h.db, user).GetAllEntries()
 	if err != nil {
 		log.Printf("❌ Failed to get entries: %v", err)
 		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
 		return
 	}
 
-	// Filter based on role
-	filteredEntries := filterEntriesByRole(entries, user)
+	// Filter based on role, hiding tombstones unless requested
+	filteredEntries := filterEntriesForView(entries, user, includeDeleted(r))
 
 	// Set headers for CSV download
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
@@ -84,15 +205,94 @@ func (h *SupervisorHandler) ExportEntries(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-	// Create CSV writer
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
+	if err := writeEntriesCSV(w, filteredEntries, loc); err != nil {
+		log.Printf("❌ Failed to write CSV export: %v", err)
+		return
+	}
+
+	log.Printf("📊 CSV export by %s: %d entries", user.Username, len(filteredEntries))
+}
+
+// ExportUploadResponse points to an export uploaded to Cloud Storage
+type ExportUploadResponse struct {
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Object      string    `json:"object"`
+	Entries     int       `json:"entries"`
+}
+
+// ExportEntriesToStorage uploads the same CSV as ExportEntries to Cloud Storage and
+// returns a signed download URL, so large exports don't stream through the API
+func (h *SupervisorHandler) ExportEntriesToStorage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	if h.exports == nil {
+		writeError(w, "Storage export is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	loc, err := httputil.ParseLocationParam(r, "tz")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	entries, err := scopedDB(h.db, user).GetAllEntries()
+	if err != nil {
+		log.Printf("❌ Failed to get entries: %v", err)
+		writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+		return
+	}
+	filteredEntries := filterEntriesForView(entries, user, includeDeleted(r))
+
+	// Prefix with the user so objects are traceable and never collide between users
+	object := fmt.Sprintf("exports/%s/gatekeeper_entries_%s.csv", user.UserID, time.Now().UTC().Format("2006-01-02_15-04-05.000"))
+	err = h.exports.Upload(r.Context(), object, "text/csv", func(out io.Writer) error {
+		return writeEntriesCSV(out, filteredEntries, loc)
+	})
+	if err != nil {
+		log.Printf("❌ Failed to upload export for %s: %v", user.Username, err)
+		writeError(w, "Failed to upload export", http.StatusBadGateway)
+		return
+	}
+
+	url, expiresAt, err := h.exports.SignedURL(object)
+	if err != nil {
+		log.Printf("❌ Failed to sign export URL for %s: %v", user.Username, err)
+		writeError(w, "Failed to create download link: export storage cannot sign URLs", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📊 CSV export uploaded by %s: %d entries to %s", user.Username, len(filteredEntries), object)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionDataExport, fmt.Sprintf("User '%s' exported %d entries to '%s' (link expires %s)", user.Username, len(filteredEntries), object, expiresAt.UTC().Format(time.RFC3339)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ExportUploadResponse{
+		DownloadURL: url,
+		ExpiresAt:   expiresAt,
+		Object:      object,
+		Entries:     len(filteredEntries),
+	})
+}
+
+// writeEntriesCSV writes entries as CSV with timestamps in loc
+func writeEntriesCSV(out io.Writer, entries []models.Entry, loc *time.Location) error {
+	writer := csv.NewWriter(out)
 
-	// Write header
 	header := []string{
 		"Record ID",
 		"Entry Type",
 		"Checkpoint ID",
+		"Sequence",
 		"Logging User ID",
 		"Created At",
 		"Client Timestamp",
@@ -100,12 +300,10 @@ func (h *SupervisorHandler) ExportEntries(w http.ResponseWriter, r *http.Request
 		"Payload",
 	}
 	if err := writer.Write(header); err != nil {
-		log.Printf("❌ Failed to write CSV header: %v", err)
-		return
+		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Write data
-	for _, entry := range filteredEntries {
+	for _, entry := range entries {
 		// Convert payload to JSON string
 		payloadJSON := ""
 		if entry.Payload != nil {
@@ -118,19 +316,28 @@ func (h *SupervisorHandler) ExportEntries(w http.ResponseWriter, r *http.Request
 			entry.RecordID,
 			string(entry.EntryType),
 			entry.CheckpointID,
+			formatSequence(entry.Sequence),
 			entry.LoggingUserID,
-			entry.CreatedAt.Format(time.RFC3339),
-			entry.ClientTS.Format(time.RFC3339),
+			formatExportTime(entry.CreatedAt, loc),
+			formatExportTime(entry.ClientTS, loc),
 			string(entry.Status),
 			payloadJSON,
 		}
 		if err := writer.Write(row); err != nil {
-			log.Printf("❌ Failed to write CSV row: %v", err)
-			return
+			return fmt.Errorf("failed to write CSV row: %w", err)
 		}
 	}
 
-	log.Printf("📊 CSV export by %s: %d entries", user.Username, len(filteredEntries))
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatSequence leaves the column blank for entries that predate sequence numbers
+func formatSequence(sequence int64) string {
+	if sequence == 0 {
+		return ""
+	}
+	return strconv.FormatInt(sequence, 10)
 }
 
 // ResetPasswordRequest represents password reset request
@@ -142,7 +349,7 @@ type ResetPasswordRequest struct {
 // ResetPassword resets a user's password
 func (h *SupervisorHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -153,8 +360,8 @@ func (h *SupervisorHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 	}
 
 	var req ResetPasswordRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -165,34 +372,24 @@ func (h *SupervisorHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 
 	// Validate password strength
 	if err := auth.ValidatePasswordStrength(req.NewPassword); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
 
+	store := scopedDB(h.db, supervisor)
+
 	// Get target user
-	targetUser, err := h.db.GetUser(req.UserID)
+	targetUser, err := store.GetUser(req.UserID)
 	if err != nil {
-		writeError(w, "User not found", http.StatusNotFound)
+		writeLookupError(w, err, "User not found")
 		return
 	}
 
 	// Authorization check: supervisors can only reset passwords for their managed operators
-	if supervisor.Role == models.RoleSupervisor {
-		canReset := false
-		if supervisor.ManagedOperators != nil {
-			for _, operatorID := range supervisor.ManagedOperators {
-				if operatorID == req.UserID {
-					canReset = true
-					break
-				}
-			}
-		}
-		if !canReset {
-			writeError(w, "You can only reset passwords for operators you manage", http.StatusForbidden)
-			return
-		}
+	if !canResetPassword(supervisor, req.UserID) {
+		writeError(w, "You can only reset passwords for operators you manage", http.StatusForbidden)
+		return
 	}
-	// Admins can reset any password (already checked by middleware)
 
 	// Hash new password
 	passwordHash, err := auth.HashPassword(req.NewPassword)
@@ -203,16 +400,27 @@ func (h *SupervisorHandler) ResetPassword(w http.ResponseWriter, r *http.Request
 	}
 
 	// Store new password hash
-	if err := h.db.StorePasswordHash(req.UserID, passwordHash); err != nil {
+	if err := store.StorePasswordHash(req.UserID, passwordHash); err != nil {
 		log.Printf("❌ Failed to store password: %v", err)
 		writeError(w, "Failed to update password", http.StatusInternalServerError)
 		return
 	}
+	revokeSessions(store, req.UserID)
 
 	log.Printf("🔑 Password reset by %s for user: %s", supervisor.Username, targetUser.Username)
+	middleware.SetAuditEvent(r.Context(), models.AuditActionResetPassword, fmt.Sprintf("User '%s' reset the password of '%s'", supervisor.Username, targetUser.Username))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Password reset successfully",
 	})
 }
+
+// canResetPassword reports whether the user may reset the target's password. Admins may
+// reset any user; everyone else holding the permission only their managed operators.
+func canResetPassword(user *models.User, targetID string) bool {
+	if user.IsAdmin() {
+		return true
+	}
+	return slices.Contains(user.ManagedOperators, targetID)
+}
@@ -1,23 +1,69 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gatekeeper/db"
+	"gatekeeper/httputil"
 	"gatekeeper/middleware"
 	"gatekeeper/models"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 type SyncHandler struct {
-	db *db.FirestoreDB
+	db              *db.FirestoreDB
+	pushConcurrency int           // Entries of a push validated and written in parallel
+	maxPayloadBytes int           // Largest accepted JSON-encoded entry payload
+	maxClockAhead   time.Duration // How far client_ts may run ahead of the server clock
+	// pushStore opens the storage Push uses for a request; firestorePushStore by default
+	pushStore func(ctx context.Context, user *models.User) pushStore
 }
 
+// defaultPushConcurrency bounds parallel Firestore round-trips per push request
+const defaultPushConcurrency = 8
+
+// defaultMaxPayloadBytes keeps entries well under Firestore's 1 MiB document limit
+const defaultMaxPayloadBytes = 256 * 1024
+
+// defaultMaxClockAhead tolerates ordinary device clock drift while rejecting entries
+// stamped far in the future, which would sort ahead of everything in reports
+const defaultMaxClockAhead = 5 * time.Minute
+
 func NewSyncHandler(firestoreDB *db.FirestoreDB) *SyncHandler {
-	return &SyncHandler{
-		db: firestoreDB,
+	h := &SyncHandler{
+		db:              firestoreDB,
+		pushConcurrency: defaultPushConcurrency,
+		maxPayloadBytes: defaultMaxPayloadBytes,
+		maxClockAhead:   defaultMaxClockAhead,
+	}
+	h.pushStore = h.firestorePushStore
+	return h
+}
+
+// SetMaxPayloadBytes sets the largest JSON-encoded payload an entry may carry
+func (h *SyncHandler) SetMaxPayloadBytes(n int) {
+	h.maxPayloadBytes = n
+}
+
+// SetMaxClockAhead sets how far ahead of server time an entry's client_ts may be.
+// Past timestamps are always accepted, since offline devices push old backlogs.
+func (h *SyncHandler) SetMaxClockAhead(d time.Duration) {
+	h.maxClockAhead = d
+}
+
+// SetPushConcurrency sets how many entries of a single push are processed in parallel
+func (h *SyncHandler) SetPushConcurrency(n int) {
+	if n < 1 {
+		n = 1
 	}
+	h.pushConcurrency = n
 }
 
 // SyncPushRequest represents the request body for sync push
@@ -27,23 +73,99 @@ type SyncPushRequest struct {
 
 // SyncPushResponse represents the response for sync push
 type SyncPushResponse struct {
-	Success      bool     `json:"success"`
-	Accepted     int      `json:"accepted"`
-	Rejected     int      `json:"rejected"`
-	RejectedIDs  []string `json:"rejected_ids,omitempty"`
-	Message      string   `json:"message"`
+	Success         bool              `json:"success"`
+	Accepted        int               `json:"accepted"`
+	Rejected        int               `json:"rejected"`
+	Duplicates      int               `json:"duplicates"` // Earlier copies of records sent more than once; only the last valid copy is written
+	RejectedIDs     []string          `json:"rejected_ids,omitempty"`
+	RejectedReasons map[string]string `json:"rejected_reasons,omitempty"` // RecordID -> why it was rejected
+	IDMap           map[string]string `json:"id_map,omitempty"`           // Client RecordID -> server RecordID for entries the server re-keyed
+	Groups          []SyncPushGroup   `json:"groups,omitempty"`
+	// RejectedByCategory is set when entries were rejected; its counts add up to Rejected
+	RejectedByCategory *SyncRejectionCounts `json:"rejected_by_category,omitempty"`
+	Message            string               `json:"message"`
 }
 
-// SyncPullResponse represents the response for sync pull
-type SyncPullResponse struct {
-	Entries []models.Entry `json:"entries"`
-	Count   int            `json:"count"`
+// SyncRejectionCounts breaks the rejected entries of a push down by cause, so a device
+// can be triaged at a glance: unauthorized entries point at the user's role or checkpoint
+// assignments, conflicts at concurrent writers, and db_errors at the server.
+type SyncRejectionCounts struct {
+	Conflicts        int `json:"conflicts"`         // Lost to concurrent writes; retry
+	Unauthorized     int `json:"unauthorized"`      // Another user's entry or a checkpoint the user can't access
+	ValidationFailed int `json:"validation_failed"` // Malformed entry; retrying won't help
+	DBErrors         int `json:"db_errors"`         // Storing failed; retry
+}
+
+// rejectionCategory is why a pushed entry was rejected
+type rejectionCategory int
+
+const (
+	rejectedValidation rejectionCategory = iota
+	rejectedUnauthorized
+	rejectedConflict
+	rejectedDBError
+)
+
+func (c *SyncRejectionCounts) add(category rejectionCategory) {
+	switch category {
+	case rejectedUnauthorized:
+		c.Unauthorized++
+	case rejectedConflict:
+		c.Conflicts++
+	case rejectedDBError:
+		c.DBErrors++
+	default:
+		c.ValidationFailed++
+	}
+}
+
+// entryValidationError is returned by validateEntry for a malformed entry. Error describes
+// the problem for logs; message is the sentence shown to the client.
+type entryValidationError struct {
+	err     error
+	message string
+}
+
+func (e *entryValidationError) Error() string { return e.err.Error() }
+func (e *entryValidationError) Unwrap() error { return e.err }
+
+// entryAccessError is returned by validateEntry when an entry is well formed but the
+// user may not store it
+type entryAccessError struct {
+	err     error
+	message string
+}
+
+func (e *entryAccessError) Error() string { return e.err.Error() }
+func (e *entryAccessError) Unwrap() error { return e.err }
+
+// entryRejectionMessage returns the client-facing message for an error from validateEntry
+func entryRejectionMessage(err error) string {
+	var validationErr *entryValidationError
+	var accessErr *entryAccessError
+	switch {
+	case errors.As(err, &accessErr):
+		return accessErr.message
+	case errors.As(err, &validationErr):
+		return validationErr.message
+	}
+	return "Invalid entry"
+}
+
+// SyncPushGroup reports one transaction of a push: the valid entries of one checkpoint,
+// up to db.MaxEntriesPerTransaction of them. Either all of a group's entries were
+// stored or none were, so a failed group can be retried as a whole.
+type SyncPushGroup struct {
+	CheckpointID string   `json:"checkpoint_id"`
+	RecordIDs    []string `json:"record_ids"` // As sent by the client
+	Committed    bool     `json:"committed"`
+	Error        string   `json:"error,omitempty"`
 }
 
 // Push handles syncing entries from client to server
 func (h *SyncHandler) Push(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -53,71 +175,399 @@ func (h *SyncHandler) Push(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Unknown fields are tolerated here, unlike other endpoints: during rollouts newer
+	// clients push entry fields this server version doesn't know yet
 	var req SyncPushRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeRequestBody(r, &req, false); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	accepted := 0
-	rejected := 0
-	var rejectedIDs []string
+	// Entries are validated on a bounded pool of workers, each filling only its own slots,
+	// and each checkpoint is read at most once. When a push carries the same record more
+	// than once, only its last valid copy is written, matching what sequential
+	// last-write-wins processing would leave behind.
+	ctx := r.Context()
+	store := h.pushStore(ctx, user)
+	results := make([]bool, len(req.Entries))
+	reasons := make([]string, len(req.Entries))
+	categories := make([]rejectionCategory, len(req.Entries))
+	checkpoints := newPushCheckpoints(store)
+	sem := make(chan struct{}, h.pushConcurrency)
+	var wg sync.WaitGroup
+	for i := range req.Entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], reasons[i], categories[i] = h.checkPushEntry(user, &req.Entries[i], checkpoints)
+		}(i)
+	}
+	wg.Wait()
 
-	for _, entry := range req.Entries {
-		// Validate entry belongs to user (security check)
-		if entry.LoggingUserID != user.UserID {
-			log.Printf("⚠️  User %s attempted to push entry for user %s", user.Username, entry.LoggingUserID)
-			rejected++
-			rejectedIDs = append(rejectedIDs, entry.RecordID)
-			continue
+	lastValid := make(map[string]int, len(req.Entries))
+	for i, ok := range results {
+		if ok {
+			lastValid[req.Entries[i].RecordID] = i
 		}
+	}
 
-		// Validate checkpoint access for gate operators
-		if user.Role == models.RoleGateOperator {
-			hasAccess := false
-			for _, cp := range user.AllowedCheckpoints {
-				if cp == entry.CheckpointID {
-					hasAccess = true
-					break
+	// Each checkpoint's entries are written in one transaction, so a failure never leaves
+	// a checkpoint with part of the push. Groups are written on a bounded pool of workers;
+	// each worker only fills its own slots, so the tally below keeps the request's order.
+	// Writes run under the request's context: once the client disconnects, unstarted
+	// groups are skipped and running transactions abort, so neither counts as accepted.
+	groupIndices := groupPushEntries(req.Entries, lastValid)
+	groups := make([]SyncPushGroup, len(groupIndices))
+	serverIDs := make([]string, len(req.Entries))
+	for g, indices := range groupIndices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(g int, indices []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			groups[g] = writePushGroup(ctx, store, user, req.Entries, indices, serverIDs, results, reasons, categories)
+		}(g, indices)
+	}
+	wg.Wait()
+
+	response := tallyPush(req.Entries, lastValid, results, reasons, categories, serverIDs)
+	response.Groups = groups
+
+	log.Printf("📤 Sync push from %s: %d accepted, %d rejected, %d duplicates in %d groups", user.Username, response.Accepted, response.Rejected, response.Duplicates, len(groups))
+	if ctx.Err() != nil {
+		log.Printf("⚠️  Sync push from %s: client disconnected before the push finished", user.Username)
+	}
+	if byCategory := response.RejectedByCategory; byCategory != nil {
+		log.Printf("📤 Sync push from %s rejected: %d validation, %d unauthorized, %d conflicts, %d db errors",
+			user.Username, byCategory.ValidationFailed, byCategory.Unauthorized, byCategory.Conflicts, byCategory.DBErrors)
+	}
+
+	if response.Accepted > 0 {
+		recordSync(store, user)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// tallyPush builds the push response from the outcome of each pushed entry, in the order
+// the entries were sent. lastValid maps each record ID to its last valid copy, the only
+// one written; earlier valid copies are counted as duplicates.
+func tallyPush(entries []models.Entry, lastValid map[string]int, results []bool, reasons []string, categories []rejectionCategory, serverIDs []string) SyncPushResponse {
+	response := SyncPushResponse{Message: "Sync completed"}
+	var byCategory SyncRejectionCounts
+	for i, ok := range results {
+		recordID := entries[i].RecordID
+		switch {
+		case ok && lastValid[recordID] != i:
+			response.Duplicates++
+		case ok:
+			response.Accepted++
+			if serverIDs[i] != "" && serverIDs[i] != recordID {
+				if response.IDMap == nil {
+					response.IDMap = make(map[string]string)
 				}
+				response.IDMap[recordID] = serverIDs[i]
+			}
+		default:
+			response.Rejected++
+			response.RejectedIDs = append(response.RejectedIDs, recordID)
+			byCategory.add(categories[i])
+			if reasons[i] != "" {
+				if response.RejectedReasons == nil {
+					response.RejectedReasons = make(map[string]string)
+				}
+				response.RejectedReasons[recordID] = reasons[i]
+			}
+		}
+	}
+
+	response.Success = response.Rejected == 0
+	if response.Rejected > 0 {
+		response.RejectedByCategory = &byCategory
+	}
+	return response
+}
+
+// validateEntry checks that the user may store the entry. It is shared by sync push
+// and single-entry creation so both paths enforce the same rules.
+func (h *SyncHandler) validateEntry(user *models.User, entry *models.Entry) error {
+	// Reject unknown entry types and statuses before they reach Firestore
+	if !entry.EntryType.IsValid() {
+		return &entryValidationError{
+			err:     fmt.Errorf("invalid entry type %q", entry.EntryType),
+			message: fmt.Sprintf("Invalid entry type %q", entry.EntryType),
+		}
+	}
+	if !entry.Status.IsValid() {
+		return &entryValidationError{
+			err:     fmt.Errorf("invalid entry status %q", entry.Status),
+			message: fmt.Sprintf("Invalid entry status %q", entry.Status),
+		}
+	}
+	models.NormalizePayloadNumbers(entry.Payload)
+
+	// Clients on different form versions coexist during rollouts; bring the payload to
+	// the latest schema the server knows and check its required fields
+	version := entry.PayloadSchemaVersion
+	if err := models.MigratePayload(entry); err != nil {
+		message := fmt.Sprintf("Unknown payload schema version %d for entry type %s", version, entry.EntryType)
+		var schemaErr *models.PayloadSchemaError
+		if errors.As(err, &schemaErr) {
+			message = "Invalid payload: " + strings.Join(schemaErr.Violations, "; ")
+		}
+		return &entryValidationError{err: err, message: message}
+	}
+
+	// A device with a badly wrong clock would otherwise distort time-range queries
+	if h.maxClockAhead > 0 {
+		if ahead := time.Until(entry.ClientTS); ahead > h.maxClockAhead {
+			return &entryValidationError{
+				err:     fmt.Errorf("client_ts is %s ahead of server time", ahead.Round(time.Second)),
+				message: fmt.Sprintf("client_ts is %s ahead of server time; check the device clock", ahead.Round(time.Second)),
 			}
-			if !hasAccess {
-				log.Printf("⚠️  User %s attempted to push entry for unauthorized checkpoint %s", user.Username, entry.CheckpointID)
-				rejected++
-				rejectedIDs = append(rejectedIDs, entry.RecordID)
-				continue
+		}
+	}
+
+	// Oversized payloads would otherwise fail later with an opaque Firestore error
+	if h.maxPayloadBytes > 0 {
+		data, err := json.Marshal(entry.Payload)
+		if err != nil {
+			return &entryValidationError{err: fmt.Errorf("invalid payload: %w", err), message: "Invalid payload"}
+		}
+		if len(data) > h.maxPayloadBytes {
+			return &entryValidationError{
+				err:     fmt.Errorf("payload is %d bytes, over the limit of %d", len(data), h.maxPayloadBytes),
+				message: fmt.Sprintf("Payload is %d bytes; the limit is %d", len(data), h.maxPayloadBytes),
 			}
 		}
+	}
 
-		// Create entry in Firestore
-		if err := h.db.CreateEntry(&entry); err != nil {
-			log.Printf("❌ Failed to create entry %s: %v", entry.RecordID, err)
-			rejected++
-			rejectedIDs = append(rejectedIDs, entry.RecordID)
-			continue
+	// Validate entry belongs to user (security check)
+	if entry.LoggingUserID != user.UserID {
+		return &entryAccessError{
+			err:     fmt.Errorf("entry belongs to user %s", entry.LoggingUserID),
+			message: fmt.Sprintf("Entry belongs to user %s", entry.LoggingUserID),
 		}
+	}
 
-		accepted++
+	// Validate checkpoint access for gate operators
+	if user.Role == models.RoleGateOperator {
+		hasAccess := false
+		for _, cp := range user.AllowedCheckpoints {
+			if cp == entry.CheckpointID {
+				hasAccess = true
+				break
+			}
+		}
+		if !hasAccess {
+			return &entryAccessError{
+				err:     fmt.Errorf("no access to checkpoint %s", entry.CheckpointID),
+				message: fmt.Sprintf("No access to checkpoint %s", entry.CheckpointID),
+			}
+		}
 	}
 
-	log.Printf("📤 Sync push from %s: %d accepted, %d rejected", user.Username, accepted, rejected)
+	return nil
+}
 
-	response := SyncPushResponse{
-		Success:     rejected == 0,
-		Accepted:    accepted,
-		Rejected:    rejected,
-		RejectedIDs: rejectedIDs,
-		Message:     "Sync completed",
+// checkPushEntry validates one pushed entry and checks that its checkpoint accepts the
+// entry's type. It returns whether the entry is valid, and otherwise why not.
+func (h *SyncHandler) checkPushEntry(user *models.User, entry *models.Entry, checkpoints *pushCheckpoints) (bool, string, rejectionCategory) {
+	if err := h.validateEntry(user, entry); err != nil {
+		log.Printf("⚠️  User %s pushed invalid entry %s: %v", user.Username, entry.RecordID, err)
+		var accessErr *entryAccessError
+		if errors.As(err, &accessErr) {
+			return false, entryRejectionMessage(err), rejectedUnauthorized
+		}
+		return false, entryRejectionMessage(err), rejectedValidation
+	}
+	checkpoint, err := checkpoints.get(entry.CheckpointID)
+	if err != nil {
+		log.Printf("❌ Failed to look up checkpoint %s: %v", entry.CheckpointID, err)
+		return false, "Failed to look up the entry's checkpoint; retry it", rejectedDBError
+	}
+	if checkpoint != nil && !checkpoint.AllowsEntryType(entry.EntryType) {
+		log.Printf("⚠️  User %s pushed entry %s of a type not allowed at its checkpoint: %s", user.Username, entry.RecordID, entry.EntryType)
+		return false, entryTypeNotAllowed(entry), rejectedValidation
 	}
+	return true, "", rejectedValidation
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// checkpointSource looks up checkpoints; *db.FirestoreDB implements it
+type checkpointSource interface {
+	GetCheckpoint(checkpointID string) (*models.Checkpoint, error)
+}
+
+// syncRecorder stamps a user's last sync time; *db.FirestoreDB implements it
+type syncRecorder interface {
+	TouchLastSync(userID string, at time.Time) error
+}
+
+// pushStore is the storage a sync push reads checkpoints from and writes entries to
+type pushStore interface {
+	checkpointSource
+	syncRecorder
+	WriteCheckpointEntries(entries []*models.Entry, role models.UserRole) error
+}
+
+// firestorePushStore looks checkpoints up in the user's organization but writes entries
+// through an unscoped view, so record ID collisions with any stored entry are detected.
+// Both run under the request's context; last sync times are stamped outside it.
+type firestorePushStore struct {
+	checkpoints *db.FirestoreDB
+	entries     *db.FirestoreDB
+	base        *db.FirestoreDB
+}
+
+func (h *SyncHandler) firestorePushStore(ctx context.Context, user *models.User) pushStore {
+	return &firestorePushStore{
+		checkpoints: scopedDB(h.db, user).WithContext(ctx),
+		entries:     h.db.WithContext(ctx),
+		base:        h.db,
+	}
+}
+
+func (s *firestorePushStore) GetCheckpoint(checkpointID string) (*models.Checkpoint, error) {
+	return s.checkpoints.GetCheckpoint(checkpointID)
+}
+
+func (s *firestorePushStore) WriteCheckpointEntries(entries []*models.Entry, role models.UserRole) error {
+	return s.entries.WriteCheckpointEntries(entries, role)
 }
 
-// Pull handles syncing entries from server to client
+func (s *firestorePushStore) TouchLastSync(userID string, at time.Time) error {
+	return s.base.TouchLastSync(userID, at)
+}
+
+// pushCheckpoints reads the checkpoints of a push, each at most once. It is safe for
+// concurrent use; concurrent lookups of the same checkpoint share one read.
+type pushCheckpoints struct {
+	store checkpointSource
+	mu    sync.Mutex
+	byID  map[string]*checkpointLookup
+}
+
+// checkpointLookup is the single read of one checkpoint
+type checkpointLookup struct {
+	once       sync.Once
+	checkpoint *models.Checkpoint // nil when the checkpoint was not found
+	err        error
+}
+
+func newPushCheckpoints(store checkpointSource) *pushCheckpoints {
+	return &pushCheckpoints{store: store, byID: make(map[string]*checkpointLookup)}
+}
+
+// get returns the checkpoint, or nil when it doesn't exist in the user's organization.
+// Pushes have never required a known checkpoint, so such entries are not rejected here.
+func (p *pushCheckpoints) get(checkpointID string) (*models.Checkpoint, error) {
+	p.mu.Lock()
+	lookup, ok := p.byID[checkpointID]
+	if !ok {
+		lookup = &checkpointLookup{}
+		p.byID[checkpointID] = lookup
+	}
+	p.mu.Unlock()
+
+	lookup.once.Do(func() {
+		lookup.checkpoint, lookup.err = p.store.GetCheckpoint(checkpointID)
+		if errors.Is(lookup.err, db.ErrNotFound) {
+			lookup.checkpoint, lookup.err = nil, nil
+		}
+	})
+	return lookup.checkpoint, lookup.err
+}
+
+// entryTypeNotAllowed is the rejection reason for an entry its checkpoint doesn't accept
+func entryTypeNotAllowed(entry *models.Entry) string {
+	return fmt.Sprintf("Entry type %s is not allowed at checkpoint %s", entry.EntryType, entry.CheckpointID)
+}
+
+// groupPushEntries returns the indices of the entries to write, grouped by checkpoint in
+// order of first appearance and split into groups that fit in one transaction
+func groupPushEntries(entries []models.Entry, lastValid map[string]int) [][]int {
+	indices := make([]int, 0, len(lastValid))
+	for _, i := range lastValid {
+		indices = append(indices, i)
+	}
+	slices.Sort(indices)
+
+	var order []string
+	byCheckpoint := make(map[string][]int)
+	for _, i := range indices {
+		checkpointID := entries[i].CheckpointID
+		if _, seen := byCheckpoint[checkpointID]; !seen {
+			order = append(order, checkpointID)
+		}
+		byCheckpoint[checkpointID] = append(byCheckpoint[checkpointID], i)
+	}
+
+	var groups [][]int
+	for _, checkpointID := range order {
+		groups = slices.AppendSeq(groups, slices.Chunk(byCheckpoint[checkpointID], db.MaxEntriesPerTransaction))
+	}
+	return groups
+}
+
+// writePushGroup stores the validated entries at indices in one transaction and fills in
+// their slots of serverIDs, results, reasons and categories. A record ID already used by another
+// user's entry is a collision between clients: the entry is re-keyed instead of
+// overwriting the other one. Nothing is written once ctx is done.
+func writePushGroup(ctx context.Context, store pushStore, user *models.User, entries []models.Entry, indices []int, serverIDs []string, results []bool, reasons []string, categories []rejectionCategory) SyncPushGroup {
+	group := make([]*models.Entry, len(indices))
+	result := SyncPushGroup{CheckpointID: entries[indices[0]].CheckpointID, RecordIDs: make([]string, len(indices))}
+	fail := func(message string, category rejectionCategory, reason string) SyncPushGroup {
+		result.Error = message
+		for _, i := range indices {
+			results[i] = false
+			reasons[i] = reason
+			categories[i] = category
+		}
+		return result
+	}
+	for j, i := range indices {
+		entry := entries[i]
+		// Entries always belong to the pushing user's organization
+		entry.OrgID = user.OrgID
+		// Review state is only set through the supervisor review endpoint
+		entry.Reviewed, entry.ReviewedBy, entry.ReviewedAt, entry.FlagReason = false, "", time.Time{}, ""
+		group[j] = &entry
+		result.RecordIDs[j] = entry.RecordID
+	}
+
+	if ctx.Err() != nil {
+		return fail("Request cancelled; none of this group was saved", rejectedDBError, "Request cancelled before the checkpoint group was stored; retry it")
+	}
+	if err := store.WriteCheckpointEntries(group, user.Role); err != nil {
+		if ctx.Err() != nil {
+			return fail("Request cancelled; none of this group was saved", rejectedDBError, "Request cancelled before the checkpoint group was stored; retry it")
+		}
+		log.Printf("❌ Failed to store %d entries of checkpoint %s from %s: %v", len(group), result.CheckpointID, user.Username, err)
+		if errors.Is(err, db.ErrWriteConflict) {
+			return fail("Failed to store entries; none of this group was saved", rejectedConflict, "Checkpoint group conflicted with concurrent writes; retry it")
+		}
+		return fail("Failed to store entries; none of this group was saved", rejectedDBError, "Checkpoint group failed to commit; retry it")
+	}
+
+	result.Committed = true
+	for j, i := range indices {
+		serverIDs[i] = group[j].RecordID
+		if serverIDs[i] != entries[i].RecordID {
+			log.Printf("🔀 Record ID collision from %s: entry %s re-keyed to %s", user.Username, entries[i].RecordID, serverIDs[i])
+		}
+	}
+	return result
+}
+
+// Pull handles syncing entries from server to client. Clients that pass device_id get
+// entries least recently updated first plus a sync_token to acknowledge via Ack; without
+// since, their pull resumes from the device's last acknowledged position.
 func (h *SyncHandler) Pull(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w, r)
 		return
 	}
 
@@ -129,22 +579,43 @@ func (h *SyncHandler) Pull(w http.ResponseWriter, r *http.Request) {
 
 	// Parse query parameters
 	query := r.URL.Query()
-	sinceParam := query.Get("since")
-
-	var entries []models.Entry
-	var err error
+	since, err := httputil.ParseTimeParam(r, "since")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// If 'since' parameter is provided, get entries after that timestamp
-	if sinceParam != "" {
-		sinceTime, parseErr := time.Parse(time.RFC3339, sinceParam)
-		if parseErr != nil {
-			writeError(w, "Invalid 'since' parameter format. Use RFC3339", http.StatusBadRequest)
+	deviceID := query.Get("device_id")
+	if deviceID != "" {
+		if err := validateDeviceID(deviceID); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		entries, err = h.db.GetEntriesSince(sinceTime)
+		if since.IsZero() {
+			since, err = h.deviceSince(user, deviceID)
+			if err != nil {
+				log.Printf("❌ Failed to get sync cursor for %s device %s: %v", user.Username, deviceID, err)
+				writeError(w, "Failed to retrieve entries", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// A pull the client abandoned needn't finish reading
+	store := scopedDB(h.db, user).WithContext(r.Context())
+	var entries []models.Entry
+
+	// If 'since' parameter is provided, get entries changed after that timestamp
+	if !since.IsZero() {
+		entries, err = store.GetEntriesSince(since)
 	} else {
 		// Get all entries
-		entries, err = h.db.GetAllEntries()
+		entries, err = store.GetAllEntries()
 	}
 
 	if err != nil {
@@ -153,24 +624,132 @@ func (h *SyncHandler) Pull(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Filter entries based on user role
+	// Filter entries based on user role. This is a sync read, so tombstones stay in
+	// for clients to mirror deletions.
 	filteredEntries := filterEntriesByRole(entries, user)
+	if deviceID != "" {
+		sortEntriesByUpdate(filteredEntries)
+	}
 
-	log.Printf("📥 Sync pull for %s: %d entries", user.Username, len(filteredEntries))
+	page, pagination, err := paginate(filteredEntries, params)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response := SyncPullResponse{
-		Entries: filteredEntries,
-		Count:   len(filteredEntries),
+	// A 304 is a successful pull too, so record it before the conditional check
+	recordSync(h.db, user)
+
+	// Conditional GET: nothing changed since the client's last pull
+	fieldsParam := query.Get("fields")
+	variant := fmt.Sprintf("%s|%d|%s|%s", fieldsParam, params.Limit, params.Cursor, deviceID)
+	if checkNotModified(w, r, entriesETag(filteredEntries, variant)) {
+		return
 	}
 
+	log.Printf("📥 Sync pull for %s: %d of %d entries", user.Username, len(page), len(filteredEntries))
+
+	// Optional column projection to keep payloads small on slow links
+	var data interface{} = page
+	if fieldsParam != "" {
+		projected, err := projectEntries(page, strings.Split(fieldsParam, ","))
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data = projected
+	}
+
+	if deviceID == "" {
+		writePaginated(w, data, pagination)
+		return
+	}
+
+	through, err := syncWatermark(filteredEntries, pagination, since)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(SyncPullResponse{
+		Data:       data,
+		Pagination: pagination,
+		SyncToken:  encodeSyncToken(through),
+	})
+}
+
+// projectEntries reduces each entry to the requested JSON fields
+func projectEntries(entries []models.Entry, fields []string) ([]map[string]interface{}, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+		if _, ok := entryJSONFields[fields[i]]; !ok {
+			return nil, fmt.Errorf("Unknown field in 'fields' parameter: %s", fields[i])
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var full []map[string]interface{}
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]interface{}, 0, len(full))
+	for _, entry := range full {
+		row := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			row[field] = entry[field]
+		}
+		projected = append(projected, row)
+	}
+
+	return projected, nil
+}
+
+// entryJSONFields is the set of fields that may be requested via the 'fields' parameter
+var entryJSONFields = map[string]struct{}{
+	"record_id":              {},
+	"checkpoint_id":          {},
+	"entry_type":             {},
+	"logging_user_id":        {},
+	"client_ts":              {},
+	"updated_at":             {},
+	"created_at":             {},
+	"status":                 {},
+	"payload":                {},
+	"payload_schema_version": {},
+	"sequence":               {},
+	"reviewed":               {},
+	"reviewed_by":            {},
+	"reviewed_at":            {},
+	"flag_reason":            {},
+}
+
+// filterEntriesForView filters entries for views and exports: by role, and without
+// tombstones unless includeDeleted is set
+func filterEntriesForView(entries []models.Entry, user *models.User, includeDeleted bool) []models.Entry {
+	filtered := filterEntriesByRole(entries, user)
+	if includeDeleted {
+		return filtered
+	}
+
+	visible := make([]models.Entry, 0, len(filtered))
+	for _, entry := range filtered {
+		if entry.Status != models.StatusDeleted {
+			visible = append(visible, entry)
+		}
+	}
+	return visible
 }
 
-// filterEntriesByRole filters entries based on user role and permissions
+// filterEntriesByRole filters entries based on user role and permissions. Sync reads use
+// it directly so tombstones reach clients; views use filterEntriesForView.
 func filterEntriesByRole(entries []models.Entry, user *models.User) []models.Entry {
-	// Admins see everything
-	if user.Role == models.RoleAdmin {
+	// Admins see everything (already scoped to their organization by the query)
+	if user.Role == models.RoleAdmin || user.Role == models.RoleSuperAdmin {
 		return entries
 	}
 
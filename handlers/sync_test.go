@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testOperator() *models.User {
+	return &models.User{
+		UserID:             "op-1",
+		Username:           "op_east",
+		Role:               models.RoleGateOperator,
+		AllowedCheckpoints: []string{"CP-1"},
+	}
+}
+
+func testEntry(recordID string) models.Entry {
+	return models.Entry{
+		RecordID:      recordID,
+		CheckpointID:  "CP-1",
+		EntryType:     models.EntryTypePersonnel,
+		LoggingUserID: "op-1",
+		ClientTS:      time.Now(),
+		Status:        models.StatusActive,
+		Payload:       map[string]interface{}{"name": "Visitor"},
+	}
+}
+
+func TestValidateEntryErrorStyle(t *testing.T) {
+	h := &SyncHandler{maxPayloadBytes: defaultMaxPayloadBytes, maxClockAhead: defaultMaxClockAhead}
+
+	entry := testEntry("rec-1")
+	entry.EntryType = "BICYCLE"
+	err := h.validateEntry(testOperator(), &entry)
+	if err == nil {
+		t.Fatal("entry of an unknown type was accepted")
+	}
+	if got, want := err.Error(), `invalid entry type "BICYCLE"`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := entryRejectionMessage(err), `Invalid entry type "BICYCLE"`; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+func TestValidateEntryAccessError(t *testing.T) {
+	h := &SyncHandler{}
+
+	entry := testEntry("rec-1")
+	entry.CheckpointID = "CP-2"
+	err := h.validateEntry(testOperator(), &entry)
+	var accessErr *entryAccessError
+	if !errors.As(err, &accessErr) {
+		t.Fatalf("error = %v, want an entryAccessError", err)
+	}
+	if got, want := entryRejectionMessage(err), "No access to checkpoint CP-2"; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+func TestTallyPushCountsSupersededCopiesAsDuplicates(t *testing.T) {
+	entries := []models.Entry{testEntry("rec-1"), testEntry("rec-2"), testEntry("rec-1")}
+	lastValid := map[string]int{"rec-1": 2, "rec-2": 1}
+	results := []bool{true, true, true}
+	serverIDs := []string{"", "rec-2", "rec-1"}
+
+	response := tallyPush(entries, lastValid, results, make([]string, 3), make([]rejectionCategory, 3), serverIDs)
+	if response.Accepted != 2 || response.Duplicates != 1 || response.Rejected != 0 {
+		t.Errorf("accepted/duplicates/rejected = %d/%d/%d, want 2/1/0", response.Accepted, response.Duplicates, response.Rejected)
+	}
+	if !response.Success {
+		t.Error("push with only duplicates was not a success")
+	}
+}
+
+// fakePushStore serves checkpoints from memory and records writes. Each lookup takes a
+// little while so concurrent lookups overlap.
+type fakePushStore struct {
+	checkpoints map[string]*models.Checkpoint
+
+	mu       sync.Mutex
+	lookups  map[string]int
+	inFlight int
+	maxInFly int
+	written  []string
+}
+
+func (s *fakePushStore) GetCheckpoint(checkpointID string) (*models.Checkpoint, error) {
+	s.mu.Lock()
+	s.lookups[checkpointID]++
+	s.inFlight++
+	s.maxInFly = max(s.maxInFly, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	checkpoint, ok := s.checkpoints[checkpointID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	return checkpoint, nil
+}
+
+func (s *fakePushStore) WriteCheckpointEntries(entries []*models.Entry, role models.UserRole) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		s.written = append(s.written, entry.RecordID)
+	}
+	return nil
+}
+
+func (s *fakePushStore) TouchLastSync(userID string, at time.Time) error {
+	return nil
+}
+
+func TestPushValidatesInParallelAndKeepsRejectedIDsInOrder(t *testing.T) {
+	const concurrency = 4
+	user := testOperator()
+	user.AllowedCheckpoints = []string{"CP-0", "CP-1", "CP-2", "CP-3", "CP-4", "CP-5"}
+	store := &fakePushStore{
+		checkpoints: map[string]*models.Checkpoint{
+			"CP-0": {CheckpointID: "CP-0"},
+			"CP-1": {CheckpointID: "CP-1", AllowedEntryTypes: []models.EntryType{models.EntryTypeTruck}},
+			"CP-2": {CheckpointID: "CP-2"},
+			"CP-3": {CheckpointID: "CP-3"},
+			"CP-4": {CheckpointID: "CP-4"},
+			// CP-5 is unknown, which pushes have never been rejected for
+		},
+		lookups: make(map[string]int),
+	}
+	h := &SyncHandler{pushConcurrency: concurrency, maxPayloadBytes: defaultMaxPayloadBytes, maxClockAhead: defaultMaxClockAhead}
+	h.pushStore = func(ctx context.Context, user *models.User) pushStore { return store }
+
+	// Every fifth entry has an unknown type; personnel entries at CP-1 aren't allowed there
+	var req SyncPushRequest
+	var wantRejected []string
+	for i := 0; i < 30; i++ {
+		entry := testEntry(fmt.Sprintf("rec-%02d", i))
+		entry.CheckpointID = fmt.Sprintf("CP-%d", i%6)
+		if i%5 == 0 {
+			entry.EntryType = "BICYCLE"
+		}
+		if i%5 == 0 || i%6 == 1 {
+			wantRejected = append(wantRejected, entry.RecordID)
+		}
+		req.Entries = append(req.Entries, entry)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/sync/push", bytes.NewReader(body))
+	httpReq = httpReq.WithContext(context.WithValue(httpReq.Context(), middleware.UserContextKey, user))
+	rec := httptest.NewRecorder()
+	h.Push(rec, httpReq)
+
+	var response SyncPushResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !slices.Equal(response.RejectedIDs, wantRejected) {
+		t.Errorf("RejectedIDs = %v, want %v", response.RejectedIDs, wantRejected)
+	}
+	if want := len(req.Entries) - len(wantRejected); response.Accepted != want || len(store.written) != want {
+		t.Errorf("accepted %d and wrote %d entries, want %d", response.Accepted, len(store.written), want)
+	}
+	for checkpointID, n := range store.lookups {
+		if n != 1 {
+			t.Errorf("checkpoint %s was read %d times, want once", checkpointID, n)
+		}
+	}
+	if store.maxInFly < 2 || store.maxInFly > concurrency {
+		t.Errorf("%d checkpoint lookups ran at once, want between 2 and %d", store.maxInFly, concurrency)
+	}
+}
+
+func TestPushCancelledMidwayAcceptsOnlyCommittedGroups(t *testing.T) {
+	entries := []models.Entry{testEntry("rec-1"), testEntry("rec-2"), testEntry("rec-3"), testEntry("rec-4")}
+	entries[2].CheckpointID = "CP-2"
+	entries[3].CheckpointID = "CP-2"
+	lastValid := map[string]int{"rec-1": 0, "rec-2": 1, "rec-3": 2, "rec-4": 3}
+	results := []bool{true, true, true, true}
+	reasons := make([]string, 4)
+	categories := make([]rejectionCategory, 4)
+	serverIDs := make([]string, 4)
+
+	// The CP-1 group committed before the client disconnected
+	serverIDs[0], serverIDs[1] = "rec-1", "rec-2"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The CP-2 group starts after the disconnect and never reaches the store
+	group := writePushGroup(ctx, nil, testOperator(), entries, []int{2, 3}, serverIDs, results, reasons, categories)
+	if group.Committed {
+		t.Error("group started after the disconnect was committed")
+	}
+
+	response := tallyPush(entries, lastValid, results, reasons, categories, serverIDs)
+	if response.Accepted != 2 || response.Rejected != 2 {
+		t.Errorf("accepted/rejected = %d/%d, want 2/2", response.Accepted, response.Rejected)
+	}
+	if !slices.Equal(response.RejectedIDs, []string{"rec-3", "rec-4"}) {
+		t.Errorf("RejectedIDs = %v, want [rec-3 rec-4]", response.RejectedIDs)
+	}
+	if response.RejectedByCategory == nil || response.RejectedByCategory.DBErrors != 2 {
+		t.Errorf("RejectedByCategory = %+v, want 2 db errors", response.RejectedByCategory)
+	}
+}
+
+func TestLargePayloadNumberSurvivesPushAndPull(t *testing.T) {
+	// 2^53 + 1: the smallest integer a float64 cannot represent
+	const nationalID = "9007199254740993"
+	body := `{"entries":[{"record_id":"rec-1","checkpoint_id":"CP-1","entry_type":"PERSONNEL",` +
+		`"logging_user_id":"op-1","client_ts":"` + time.Now().UTC().Format(time.RFC3339) + `","status":"ACTIVE",` +
+		`"payload":{"name":"Visitor","national_id":` + nationalID + `,"vehicles":[{"plate_id":` + nationalID + `}]}}]}`
+
+	// Push: the body is decoded and validated like a sync push
+	var req SyncPushRequest
+	if err := decodeRequestBody(httptest.NewRequest(http.MethodPost, "/api/sync/push", strings.NewReader(body)), &req, false); err != nil {
+		t.Fatalf("decodeRequestBody: %v", err)
+	}
+	h := &SyncHandler{maxPayloadBytes: defaultMaxPayloadBytes, maxClockAhead: defaultMaxClockAhead}
+	entry := &req.Entries[0]
+	if err := h.validateEntry(testOperator(), entry); err != nil {
+		t.Fatalf("validateEntry: %v", err)
+	}
+	if _, ok := entry.Payload["national_id"].(int64); !ok {
+		t.Fatalf("national_id stored as %T, want int64", entry.Payload["national_id"])
+	}
+
+	// Pull: the stored entry is encoded into the response
+	encoded, err := json.Marshal(SyncPullResponse{Data: []models.Entry{*entry}})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	for _, want := range []string{`"national_id":` + nationalID, `"plate_id":` + nationalID} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("pull response %s does not contain %s", encoded, want)
+		}
+	}
+}
+
+func TestViewReadsHideTombstonesAndSyncReadsKeepThem(t *testing.T) {
+	deleted := testEntry("rec-2")
+	deleted.Status = models.StatusDeleted
+	other := testEntry("rec-3")
+	other.LoggingUserID = "op-2"
+	entries := []models.Entry{testEntry("rec-1"), deleted, other}
+	user := testOperator()
+
+	recordIDs := func(entries []models.Entry) []string {
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.RecordID
+		}
+		return ids
+	}
+
+	view := httptest.NewRequest(http.MethodGet, "/api/entries/mine", nil)
+	if got := recordIDs(filterEntriesForView(entries, user, includeDeleted(view))); !slices.Equal(got, []string{"rec-1"}) {
+		t.Errorf("view read = %v, want [rec-1]", got)
+	}
+
+	withDeleted := httptest.NewRequest(http.MethodGet, "/api/entries/mine?include_deleted=true", nil)
+	if got := recordIDs(filterEntriesForView(entries, user, includeDeleted(withDeleted))); !slices.Equal(got, []string{"rec-1", "rec-2"}) {
+		t.Errorf("view read with include_deleted = %v, want [rec-1 rec-2]", got)
+	}
+
+	// Sync pulls carry tombstones so clients mirror deletions
+	if got := recordIDs(filterEntriesByRole(entries, user)); !slices.Equal(got, []string{"rec-1", "rec-2"}) {
+		t.Errorf("sync read = %v, want [rec-1 rec-2]", got)
+	}
+}
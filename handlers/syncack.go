@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDeviceIDLength bounds device IDs, which become part of a Firestore document ID
+const maxDeviceIDLength = 128
+
+// SyncPullResponse is the pull envelope for clients that name their device. The client
+// confirms receipt by posting SyncToken to /api/sync/ack.
+type SyncPullResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+	SyncToken  string      `json:"sync_token"`
+}
+
+// SyncAckRequest confirms that a device received a pull response
+type SyncAckRequest struct {
+	DeviceID  string `json:"device_id"`
+	SyncToken string `json:"sync_token"`
+}
+
+// validateDeviceID rejects device IDs that can't be stored as part of a document ID
+func validateDeviceID(deviceID string) error {
+	if deviceID == "" {
+		return errors.New("device_id is required")
+	}
+	if len(deviceID) > maxDeviceIDLength || strings.Contains(deviceID, "/") {
+		return errors.New("Invalid device_id")
+	}
+	return nil
+}
+
+// encodeSyncToken and decodeSyncToken wrap the update time a pull response covers
+func encodeSyncToken(through time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(through.UTC().Format(time.RFC3339Nano)))
+}
+
+func decodeSyncToken(token string) (time.Time, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid sync_token")
+	}
+	through, err := time.Parse(time.RFC3339Nano, string(data))
+	// Update times are server-assigned, so a token can never be ahead of the server
+	if err != nil || through.After(time.Now()) {
+		return time.Time{}, errors.New("Invalid sync_token")
+	}
+	return through, nil
+}
+
+// sortEntriesByUpdate orders entries least recently updated first, so every page of a
+// pull covers a contiguous range of update times
+func sortEntriesByUpdate(entries []models.Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].UpdatedAt.Equal(entries[j].UpdatedAt) {
+			return entries[i].UpdatedAt.Before(entries[j].UpdatedAt)
+		}
+		return entries[i].RecordID < entries[j].RecordID
+	})
+}
+
+// syncWatermark returns the update time through which a client holding this page and
+// the ones before it has received every change. entries must be sorted by update time.
+// Entries sharing a timestamp with the first entry of the next page are left out, since
+// acknowledging that timestamp would skip the rest of them.
+func syncWatermark(entries []models.Entry, pagination Pagination, since time.Time) (time.Time, error) {
+	end := len(entries)
+	if pagination.NextCursor != "" {
+		offset, err := decodeOffsetCursor(pagination.NextCursor)
+		if err != nil {
+			return time.Time{}, err
+		}
+		end = min(offset, len(entries))
+	}
+
+	for i := end - 1; i >= 0; i-- {
+		if end == len(entries) || entries[i].UpdatedAt.Before(entries[end].UpdatedAt) {
+			return entries[i].UpdatedAt, nil
+		}
+	}
+	return since, nil
+}
+
+// Ack records that a device received a pull response, advancing the device's cursor to
+// the response's sync_token. Until a response is acknowledged, pulls naming the device
+// keep starting from the previous cursor, so a response lost in transit is sent again.
+func (h *SyncHandler) Ack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req SyncAckRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateDeviceID(req.DeviceID); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	through, err := decodeSyncToken(req.SyncToken)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cursor, err := h.db.AckSyncCursor(user.UserID, req.DeviceID, through)
+	if err != nil {
+		log.Printf("❌ Failed to acknowledge sync for %s device %s: %v", user.Username, req.DeviceID, err)
+		writeError(w, "Failed to acknowledge sync", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Sync ack from %s device %s through %s", user.Username, req.DeviceID, cursor.AckedThrough.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cursor)
+}
+
+// deviceSince returns where a pull for the device starts when the client gave no since:
+// the device's acknowledged cursor, or the zero time for a device that never acked
+func (h *SyncHandler) deviceSince(user *models.User, deviceID string) (time.Time, error) {
+	cursor, err := h.db.GetSyncCursor(user.UserID, deviceID)
+	if errors.Is(err, db.ErrSyncCursorNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cursor.AckedThrough, nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"gatekeeper/models"
+	"testing"
+	"time"
+)
+
+func TestSyncWatermarkFollowsUpdates(t *testing.T) {
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	entries := []models.Entry{
+		// Created long ago but tombstoned most recently
+		{RecordID: "old", CreatedAt: base, UpdatedAt: base.Add(3 * time.Hour), Status: models.StatusDeleted},
+		{RecordID: "new", CreatedAt: base.Add(time.Hour), UpdatedAt: base.Add(time.Hour)},
+	}
+	sortEntriesByUpdate(entries)
+	if entries[0].RecordID != "new" || entries[1].RecordID != "old" {
+		t.Fatalf("order = %s, %s; want new, old", entries[0].RecordID, entries[1].RecordID)
+	}
+
+	through, err := syncWatermark(entries, Pagination{}, base)
+	if err != nil {
+		t.Fatalf("syncWatermark: %v", err)
+	}
+	if want := base.Add(3 * time.Hour); !through.Equal(want) {
+		t.Errorf("watermark = %s, want the tombstone's update time %s", through, want)
+	}
+}
+
+func TestSyncWatermarkStopsBeforeSharedTimestamp(t *testing.T) {
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	entries := []models.Entry{
+		{RecordID: "a", UpdatedAt: base},
+		{RecordID: "b", UpdatedAt: base.Add(time.Minute)},
+		{RecordID: "c", UpdatedAt: base.Add(time.Minute)},
+	}
+
+	// The page ends between b and c, which share an update time
+	through, err := syncWatermark(entries, Pagination{NextCursor: encodeOffsetCursor(2)}, base.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("syncWatermark: %v", err)
+	}
+	if !through.Equal(base) {
+		t.Errorf("watermark = %s, want %s", through, base)
+	}
+}
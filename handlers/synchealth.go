@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"gatekeeper/httputil"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// defaultSyncStaleAfter is how long an operator may go without syncing before being flagged
+const defaultSyncStaleAfter = 24 * time.Hour
+
+// OperatorSyncHealth reports how far behind an operator's device is on sync
+type OperatorSyncHealth struct {
+	UserID           string     `json:"user_id"`
+	Username         string     `json:"username"`
+	SupervisorID     string     `json:"supervisor_id,omitempty"`
+	LastSyncAt       *time.Time `json:"last_sync_at"`                 // Null when the operator has never synced
+	SecondsSinceSync *int64     `json:"seconds_since_sync,omitempty"` // Omitted when the operator has never synced
+	Stale            bool       `json:"stale"`
+}
+
+// SyncHealth lists every operator with the time of their last successful sync, stalest
+// first. Operators whose last sync is older than ?stale_after (default 24h) or who
+// never synced are flagged as stale.
+func (h *AdminHandler) SyncHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	staleAfter, err := httputil.ParseDurationParam(r, "stale_after", defaultSyncStaleAfter)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	users, err := scopedDB(h.db, adminUser).GetAllUsers()
+	if err != nil {
+		log.Printf("❌ Failed to get users: %v", err)
+		writeError(w, "Failed to retrieve sync health", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	report := []OperatorSyncHealth{}
+	for _, user := range users {
+		if user.Role != models.RoleGateOperator {
+			continue
+		}
+
+		health := OperatorSyncHealth{
+			UserID:       user.UserID,
+			Username:     user.Username,
+			SupervisorID: user.SupervisorID,
+			Stale:        true,
+		}
+		if !user.LastSyncAt.IsZero() {
+			lastSync := user.LastSyncAt
+			since := int64(now.Sub(lastSync).Seconds())
+			health.LastSyncAt = &lastSync
+			health.SecondsSinceSync = &since
+			health.Stale = now.Sub(lastSync) > staleAfter
+		}
+		report = append(report, health)
+	}
+
+	// Never-synced operators first, then the longest silence
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].LastSyncAt == nil || report[j].LastSyncAt == nil {
+			return report[i].LastSyncAt == nil && report[j].LastSyncAt != nil
+		}
+		return report[i].LastSyncAt.Before(*report[j].LastSyncAt)
+	})
+
+	page, pagination, err := paginate(report, params)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writePaginated(w, page, pagination)
+}
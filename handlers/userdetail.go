@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"time"
+)
+
+// UserDetail is a user with every linked record resolved, for the admin user drawer
+type UserDetail struct {
+	User              *UserProfile        `json:"user"`
+	LastSyncAt        *time.Time          `json:"last_sync_at"`                 // Null when the user has never synced
+	Supervisor        *UserProfile        `json:"supervisor"`                   // Null for users without a (resolvable) supervisor
+	ManagedOperators  []*UserProfile      `json:"managed_operators"`            // Resolved for supervisors
+	Checkpoints       []models.Checkpoint `json:"checkpoints"`                  // Allowed checkpoints with names
+	MissingReferences []string            `json:"missing_references,omitempty"` // Linked IDs that no longer resolve
+}
+
+// GetUserDetail returns a user with their supervisor, managed operators and allowed
+// checkpoints resolved in one response. Linked users are fetched with batched queries
+// and checkpoints come from the checkpoint cache. Only profile fields are returned.
+func (h *AdminHandler) GetUserDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	user, err := store.GetUser(userID)
+	if err != nil {
+		writeLookupError(w, err, "User not found")
+		return
+	}
+
+	detail := UserDetail{
+		User:             newUserProfile(user),
+		ManagedOperators: []*UserProfile{},
+		Checkpoints:      []models.Checkpoint{},
+	}
+	if !user.LastSyncAt.IsZero() {
+		detail.LastSyncAt = &user.LastSyncAt
+	}
+
+	// Resolve the supervisor and managed operators in one batch
+	linkedIDs := append([]string{}, user.ManagedOperators...)
+	if user.SupervisorID != "" {
+		linkedIDs = append(linkedIDs, user.SupervisorID)
+	}
+	linked, err := store.GetUsersByIDs(linkedIDs)
+	if err != nil {
+		log.Printf("❌ Failed to resolve users linked to %s: %v", user.Username, err)
+		writeError(w, "Failed to retrieve user detail", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]*models.User, len(linked))
+	for i := range linked {
+		byID[linked[i].UserID] = &linked[i]
+	}
+
+	if user.SupervisorID != "" {
+		if supervisor, ok := byID[user.SupervisorID]; ok {
+			detail.Supervisor = newUserProfile(supervisor)
+		} else {
+			detail.MissingReferences = append(detail.MissingReferences, "supervisor:"+user.SupervisorID)
+		}
+	}
+	for _, operatorID := range user.ManagedOperators {
+		if operator, ok := byID[operatorID]; ok {
+			detail.ManagedOperators = append(detail.ManagedOperators, newUserProfile(operator))
+		} else {
+			detail.MissingReferences = append(detail.MissingReferences, "operator:"+operatorID)
+		}
+	}
+
+	if len(user.AllowedCheckpoints) > 0 {
+		checkpoints, err := store.GetAllCheckpoints()
+		if err != nil {
+			log.Printf("❌ Failed to get checkpoints: %v", err)
+			writeError(w, "Failed to retrieve user detail", http.StatusInternalServerError)
+			return
+		}
+		byCheckpointID := make(map[string]models.Checkpoint, len(checkpoints))
+		for _, checkpoint := range checkpoints {
+			byCheckpointID[checkpoint.CheckpointID] = checkpoint
+		}
+		for _, checkpointID := range user.AllowedCheckpoints {
+			if checkpoint, ok := byCheckpointID[checkpointID]; ok {
+				detail.Checkpoints = append(detail.Checkpoints, checkpoint)
+			} else {
+				detail.MissingReferences = append(detail.MissingReferences, "checkpoint:"+checkpointID)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
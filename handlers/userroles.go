@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/middleware"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxBulkRoleChanges bounds the transactions run by one request; each row is its own
+const maxBulkRoleChanges = 200
+
+// BulkRoleChangeRequest lists the role each user should have
+type BulkRoleChangeRequest struct {
+	Changes []struct {
+		UserID string          `json:"user_id"`
+		Role   models.UserRole `json:"role"`
+	} `json:"changes"`
+}
+
+// RoleChangeResult reports the outcome for a single row of a bulk role change
+type RoleChangeResult struct {
+	UserID            string          `json:"user_id"`
+	Username          string          `json:"username,omitempty"`
+	Status            string          `json:"status"` // "updated", "unchanged" or "rejected"
+	OldRole           models.UserRole `json:"old_role,omitempty"`
+	Role              models.UserRole `json:"role,omitempty"`
+	DetachedOperators []string        `json:"detached_operators,omitempty"`
+	FormerSupervisor  string          `json:"former_supervisor,omitempty"`
+	Reason            string          `json:"reason,omitempty"`
+}
+
+type BulkRoleChangeResponse struct {
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Rejected  int                `json:"rejected"`
+	Results   []RoleChangeResult `json:"results"`
+}
+
+// UpdateRoles changes the roles of many users at once, for reorganizations. Each row is
+// checked like UpdateUser and applied in its own transaction that reconciles the
+// supervisor hierarchy; a failed row is reported without stopping the rest. Rows run in
+// order, so demoting every admin of an organization stops at the last one.
+func (h *AdminHandler) UpdateRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w, r)
+		return
+	}
+
+	adminUser, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		writeError(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req BulkRoleChangeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) == 0 {
+		writeError(w, "At least one change is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) > maxBulkRoleChanges {
+		writeError(w, fmt.Sprintf("At most %d roles can be changed per request", maxBulkRoleChanges), http.StatusBadRequest)
+		return
+	}
+
+	store := scopedDB(h.db, adminUser)
+	response := BulkRoleChangeResponse{Results: make([]RoleChangeResult, 0, len(req.Changes))}
+	var changed []string
+	seen := make(map[string]bool, len(req.Changes))
+
+	for _, row := range req.Changes {
+		result := RoleChangeResult{UserID: row.UserID, Role: row.Role}
+		reject := func(reason string) {
+			result.Status = "rejected"
+			result.Reason = reason
+			response.Rejected++
+			response.Results = append(response.Results, result)
+		}
+
+		if row.UserID == "" {
+			reject("User ID is required")
+			continue
+		}
+		if !row.Role.IsValid() {
+			reject("Invalid role")
+			continue
+		}
+		if seen[row.UserID] {
+			reject("Duplicate user ID in request")
+			continue
+		}
+		seen[row.UserID] = true
+		if err := checkRoleGrant(adminUser, row.Role); err != nil {
+			reject(err.Error())
+			continue
+		}
+
+		user, err := store.GetUser(row.UserID)
+		if errors.Is(err, db.ErrNotFound) {
+			reject("User not found")
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to get user %s: %v", row.UserID, err)
+			reject("Failed to get user")
+			continue
+		}
+		result.Username = user.Username
+		result.OldRole = user.Role
+		// Organization admins can't demote super admins either
+		if err := checkRoleGrant(adminUser, user.Role); err != nil {
+			reject(err.Error())
+			continue
+		}
+		if user.Role == row.Role {
+			result.Status = "unchanged"
+			response.Unchanged++
+			response.Results = append(response.Results, result)
+			continue
+		}
+
+		change, err := store.ChangeRole(user.UserID, row.Role)
+		if errors.Is(err, db.ErrLastAdmin) {
+			reject("Cannot change the role of the last admin")
+			continue
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			reject("User not found")
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ Failed to change role of %s: %v", user.Username, err)
+			reject("Failed to change role")
+			continue
+		}
+		revokeSessions(store, user.UserID)
+
+		result.Status = "updated"
+		result.DetachedOperators = change.DetachedOperators
+		result.FormerSupervisor = change.FormerSupervisor
+		response.Updated++
+		response.Results = append(response.Results, result)
+		changed = append(changed, fmt.Sprintf("%s %s -> %s", user.Username, change.OldRole, row.Role))
+	}
+
+	log.Printf("✅ Bulk role change by %s: %d updated, %d unchanged, %d rejected", adminUser.Username, response.Updated, response.Unchanged, response.Rejected)
+	if len(changed) > 0 {
+		middleware.SetAuditEvent(r.Context(), models.AuditActionBulkUpdateRoles, fmt.Sprintf("Admin '%s' changed %d roles (%d rejected): %s", adminUser.Username, len(changed), response.Rejected, strings.Join(changed, ", ")))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
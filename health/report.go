@@ -0,0 +1,81 @@
+package health
+
+import (
+	"log"
+	"time"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn" // Works, but likely not what the operator intended
+	StatusFail Status = "fail" // The server must not start (or report ready) with this
+)
+
+// Check is one line of a self-check report
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report collects the checks run at startup so they can be logged and served by /readyz
+type Report struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Check   `json:"checks"`
+}
+
+// NewReport starts an empty report
+func NewReport() *Report {
+	return &Report{CheckedAt: time.Now().UTC(), Checks: []Check{}}
+}
+
+// Pass records a successful check
+func (r *Report) Pass(name, detail string) {
+	r.Add(Check{Name: name, Status: StatusPass, Detail: detail})
+}
+
+// Warn records a check that passed with a caveat
+func (r *Report) Warn(name, detail string) {
+	r.Add(Check{Name: name, Status: StatusWarn, Detail: detail})
+}
+
+// Fail records a failed check
+func (r *Report) Fail(name, detail string) {
+	r.Add(Check{Name: name, Status: StatusFail, Detail: detail})
+}
+
+// Add records a check
+func (r *Report) Add(check Check) {
+	r.Checks = append(r.Checks, check)
+}
+
+// Failed reports whether any check failed
+func (r *Report) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Log writes one line per check, so a failed boot shows everything wrong at once
+func (r *Report) Log() {
+	for _, check := range r.Checks {
+		icon := "✅"
+		switch check.Status {
+		case StatusWarn:
+			icon = "⚠️ "
+		case StatusFail:
+			icon = "❌"
+		}
+		if check.Detail == "" {
+			log.Printf("%s Self-check %s: %s", icon, check.Name, check.Status)
+			continue
+		}
+		log.Printf("%s Self-check %s: %s (%s)", icon, check.Name, check.Status, check.Detail)
+	}
+}
@@ -0,0 +1,101 @@
+// Package httputil holds shared parsing for query parameters so that every
+// endpoint rejects the same class of bad input with the same 400 message.
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeParam parses an RFC3339 query parameter. It returns the zero time when the parameter is absent.
+func ParseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid '%s' parameter format. Use RFC3339", name)
+	}
+	return t, nil
+}
+
+// ParseIntParam parses an integer query parameter within [minValue, maxValue].
+// It returns defaultValue when the parameter is absent.
+func ParseIntParam(r *http.Request, name string, defaultValue, minValue, maxValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < minValue || i > maxValue {
+		return 0, fmt.Errorf("Invalid '%s' parameter. Use an integer between %d and %d", name, minValue, maxValue)
+	}
+	return i, nil
+}
+
+// ParseDurationParam parses a Go duration query parameter (e.g. 90m, 24h) that must be positive.
+// It returns defaultValue when the parameter is absent.
+func ParseDurationParam(r *http.Request, name string, defaultValue time.Duration) (time.Duration, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return defaultValue, fmt.Errorf("Invalid '%s' parameter. Use a positive duration such as 90m or 24h", name)
+	}
+	return d, nil
+}
+
+// ParseBoolParam parses a boolean query parameter (true/false, 1/0). It returns defaultValue when the parameter is absent.
+func ParseBoolParam(r *http.Request, name string, defaultValue bool) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("Invalid '%s' parameter. Use true or false", name)
+	}
+	return b, nil
+}
+
+// ParseEnumParam parses a query parameter that must be one of allowed.
+// It returns defaultValue when the parameter is absent.
+func ParseEnumParam[T ~string](r *http.Request, name string, defaultValue T, allowed ...T) (T, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	names := make([]string, 0, len(allowed))
+	for _, candidate := range allowed {
+		if T(value) == candidate {
+			return candidate, nil
+		}
+		names = append(names, string(candidate))
+	}
+	return defaultValue, fmt.Errorf("Invalid '%s' parameter. Use one of: %s", name, strings.Join(names, ", "))
+}
+
+// ParseLocationParam parses an IANA time zone name (e.g. Africa/Lusaka). It returns UTC when the parameter is absent.
+func ParseLocationParam(r *http.Request, name string) (*time.Location, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid '%s' parameter. Use an IANA time zone name such as Africa/Lusaka", name)
+	}
+	return loc, nil
+}
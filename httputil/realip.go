@@ -0,0 +1,84 @@
+package httputil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses proxy addresses given as CIDR ranges ("10.0.0.0/8") or
+// single IPs ("192.0.2.7")
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither a CIDR range nor an IP address", value)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// RealIP returns the IP address of the client that sent the request.
+//
+// X-Forwarded-For and X-Real-IP are set by whoever sends the request, so they are only
+// honored when the peer (RemoteAddr) is one of trustedProxies; otherwise any client could
+// pick its own address and dodge rate limits, lockouts and IP checks. X-Forwarded-For is
+// read right to left, skipping trusted proxies, because only the entries appended by
+// trusted proxies are reliable. With no trusted proxies the peer address is always used.
+func RealIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	peer := peerIP(r)
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !trusted(addr, trustedProxies) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// Everything left of a malformed entry is unreliable
+			break
+		}
+		if i == 0 || !trusted(hop, trustedProxies) {
+			return hop.Unmap().String()
+		}
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return peer
+}
+
+// peerIP returns the address of the connection's peer without the port
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func trusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrAuditRetentionDisabled is returned when an archival is requested but no audit
+// retention window is configured
+var ErrAuditRetentionDisabled = errors.New("audit log retention is disabled")
+
+// AuditArchiver writes audit logs to cold storage; *exports.Uploader implements it
+type AuditArchiver interface {
+	Upload(ctx context.Context, objectName, contentType string, write func(io.Writer) error) error
+}
+
+// AuditArchiveResult summarizes a single audit archival run
+type AuditArchiveResult struct {
+	Cutoff   time.Time `json:"cutoff"`
+	Exported int       `json:"exported"`
+	Objects  []string  `json:"objects"`
+	Deleted  bool      `json:"deleted"` // Whether exported logs were deleted rather than moved to audit_logs_archive
+}
+
+// AuditRetentionJob periodically exports audit logs older than the retention window to
+// cold storage, then moves them to audit_logs_archive, or deletes them when configured to.
+// A batch only leaves audit_logs once its export has been written.
+type AuditRetentionJob struct {
+	db            *db.FirestoreDB
+	archiver      AuditArchiver
+	retentionDays int
+	deleteLogs    bool
+	interval      time.Duration
+	batchSize     int
+	mu            sync.Mutex // Prevents overlapping runs
+}
+
+// NewAuditRetentionJob creates a new audit log retention job
+func NewAuditRetentionJob(firestoreDB *db.FirestoreDB, archiver AuditArchiver, retentionDays int, deleteLogs bool, interval time.Duration, batchSize int) *AuditRetentionJob {
+	if batchSize <= 0 || batchSize > maxPurgeBatchSize {
+		batchSize = maxPurgeBatchSize
+	}
+	return &AuditRetentionJob{
+		db:            firestoreDB,
+		archiver:      archiver,
+		retentionDays: retentionDays,
+		deleteLogs:    deleteLogs,
+		interval:      interval,
+		batchSize:     batchSize,
+	}
+}
+
+// Enabled reports whether an audit retention window is configured
+func (j *AuditRetentionJob) Enabled() bool {
+	return j.retentionDays > 0
+}
+
+// Start runs the archival on a ticker in the background. It is a no-op when retention is disabled.
+func (j *AuditRetentionJob) Start() {
+	if !j.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	go func() {
+		for range ticker.C {
+			if _, err := j.Run("system"); err != nil {
+				log.Printf("❌ Audit log archival failed: %v", err)
+			}
+		}
+	}()
+}
+
+// auditUploadTimeout bounds the upload of one batch, so a hung upload cannot hold the
+// job lock forever
+const auditUploadTimeout = 5 * time.Minute
+
+// Run archives all audit logs recorded before the retention cutoff, batch by batch, and
+// records an audit event attributed to actorID summarizing the run. A run that fails
+// partway is audited too, with the batches that were exported before the failure.
+func (j *AuditRetentionJob) Run(actorID string) (*AuditArchiveResult, error) {
+	if !j.Enabled() {
+		return nil, ErrAuditRetentionDisabled
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	started := time.Now().UTC()
+	result := &AuditArchiveResult{
+		Cutoff:  started.AddDate(0, 0, -j.retentionDays),
+		Objects: []string{},
+		Deleted: j.deleteLogs,
+	}
+
+	err := j.archive(result, started)
+
+	mode := "archived"
+	if j.deleteLogs {
+		mode = "deleted"
+	}
+	details := fmt.Sprintf("%d audit logs recorded before %s exported to %d objects and %s", result.Exported, result.Cutoff.Format(time.RFC3339), len(result.Objects), mode)
+	if err != nil {
+		details += " before failing"
+	} else {
+		log.Printf("🗄️  Audit log archival: %d logs exported and %s (cutoff %s)", result.Exported, mode, result.Cutoff.Format(time.RFC3339))
+	}
+
+	auditLog := &models.AuditLog{
+		Timestamp: models.FormatTimestamp(time.Now()),
+		UserID:    actorID,
+		Action:    models.AuditActionAuditLogArchival,
+		Details:   details,
+	}
+	if err := j.db.CreateAuditLog(auditLog); err != nil {
+		log.Printf("❌ Failed to write audit log for audit archival: %v", err)
+	}
+
+	return result, err
+}
+
+// archive exports and removes audit logs before the cutoff until none are left, adding
+// each completed batch to result
+func (j *AuditRetentionJob) archive(result *AuditArchiveResult, started time.Time) error {
+	for batch := 0; ; batch++ {
+		auditLogs, err := j.db.GetAuditLogsBefore(result.Cutoff, j.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(auditLogs) == 0 {
+			return nil
+		}
+
+		object := fmt.Sprintf("audit-logs/%s/%04d.jsonl", started.Format("2006-01-02_15-04-05"), batch)
+		if err := j.upload(object, auditLogs); err != nil {
+			return err
+		}
+		result.Objects = append(result.Objects, object)
+
+		if j.deleteLogs {
+			logIDs := make([]string, 0, len(auditLogs))
+			for _, auditLog := range auditLogs {
+				logIDs = append(logIDs, auditLog.LogID)
+			}
+			err = j.db.DeleteAuditLogs(logIDs)
+		} else {
+			err = j.db.ArchiveAuditLogs(auditLogs)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Exported += len(auditLogs)
+		if len(auditLogs) < j.batchSize {
+			return nil
+		}
+		time.Sleep(batchPause)
+	}
+}
+
+// upload writes one batch of audit logs to object as JSON lines
+func (j *AuditRetentionJob) upload(object string, auditLogs []models.AuditLog) error {
+	ctx, cancel := context.WithTimeout(context.Background(), auditUploadTimeout)
+	defer cancel()
+
+	return j.archiver.Upload(ctx, object, "application/x-ndjson", func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for i := range auditLogs {
+			if err := encoder.Encode(&auditLogs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
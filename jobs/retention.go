@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrRetentionDisabled is returned when a purge is requested but no retention window is configured
+var ErrRetentionDisabled = errors.New("entry retention is disabled")
+
+// maxPurgeBatchSize keeps archive batches (two writes per entry) under Firestore's 500-write limit
+const maxPurgeBatchSize = 250
+
+// batchPause spaces out batches so a large purge doesn't saturate Firestore
+const batchPause = 500 * time.Millisecond
+
+// PurgeResult summarizes a single purge run
+type PurgeResult struct {
+	Cutoff   time.Time `json:"cutoff"`
+	Purged   int       `json:"purged"`
+	Archived bool      `json:"archived"`
+	Done     bool      `json:"done"` // Whether no entries before the cutoff are left
+}
+
+// RetentionJob periodically removes entries older than the retention window
+type RetentionJob struct {
+	db            *db.FirestoreDB
+	retentionDays int
+	archive       bool
+	interval      time.Duration
+	batchSize     int
+	mu            sync.Mutex // Prevents overlapping scheduled and manual runs
+}
+
+// NewRetentionJob creates a new entry retention job
+func NewRetentionJob(firestoreDB *db.FirestoreDB, retentionDays int, archive bool, interval time.Duration, batchSize int) *RetentionJob {
+	if batchSize <= 0 || batchSize > maxPurgeBatchSize {
+		batchSize = maxPurgeBatchSize
+	}
+	return &RetentionJob{
+		db:            firestoreDB,
+		retentionDays: retentionDays,
+		archive:       archive,
+		interval:      interval,
+		batchSize:     batchSize,
+	}
+}
+
+// Enabled reports whether a retention window is configured
+func (j *RetentionJob) Enabled() bool {
+	return j.retentionDays > 0
+}
+
+// Start runs the purge on a ticker in the background. It is a no-op when retention is disabled.
+func (j *RetentionJob) Start() {
+	if !j.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	go func() {
+		for range ticker.C {
+			if _, err := j.Run("system"); err != nil {
+				log.Printf("❌ Entry retention purge failed: %v", err)
+			}
+		}
+	}()
+}
+
+// Run purges all entries created before the retention cutoff, batch by batch, and
+// records an audit event attributed to actorID summarizing the purge. A purge that fails
+// partway is audited too, with the batches removed before the failure.
+func (j *RetentionJob) Run(actorID string) (*PurgeResult, error) {
+	return j.run(actorID, 0)
+}
+
+// RunBatch purges a single batch of entries created before the retention cutoff, so a
+// manual purge fits in one request; repeat it until the result is done. Like Run it
+// records an audit event attributed to actorID.
+func (j *RetentionJob) RunBatch(actorID string) (*PurgeResult, error) {
+	return j.run(actorID, 1)
+}
+
+// run purges up to maxBatches batches, or until no entries are left when maxBatches is 0
+func (j *RetentionJob) run(actorID string, maxBatches int) (*PurgeResult, error) {
+	if !j.Enabled() {
+		return nil, ErrRetentionDisabled
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	result := &PurgeResult{
+		Cutoff:   time.Now().UTC().AddDate(0, 0, -j.retentionDays),
+		Archived: j.archive,
+	}
+
+	err := j.purge(result, maxBatches)
+
+	mode := "deleted"
+	if j.archive {
+		mode = "archived"
+	}
+	details := fmt.Sprintf("%d entries created before %s %s", result.Purged, result.Cutoff.Format(time.RFC3339), mode)
+	if err != nil {
+		details += " before failing"
+	} else {
+		log.Printf("🧹 Entry retention purge: %d entries %s (cutoff %s, done: %t)", result.Purged, mode, result.Cutoff.Format(time.RFC3339), result.Done)
+	}
+
+	auditLog := &models.AuditLog{
+		Timestamp: models.FormatTimestamp(time.Now()),
+		UserID:    actorID,
+		Action:    models.AuditActionEntryRetentionPurge,
+		Details:   details,
+	}
+	if err := j.db.CreateAuditLog(auditLog); err != nil {
+		log.Printf("❌ Failed to write audit log for retention purge: %v", err)
+	}
+
+	return result, err
+}
+
+// purge removes entries before the cutoff batch by batch, adding each completed batch to
+// result, until none are left or maxBatches batches are done (no limit when 0)
+func (j *RetentionJob) purge(result *PurgeResult, maxBatches int) error {
+	for batch := 1; ; batch++ {
+		entries, err := j.db.GetEntriesCreatedBefore(result.Cutoff, j.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			result.Done = true
+			return nil
+		}
+
+		if j.archive {
+			err = j.db.ArchiveEntries(entries)
+		} else {
+			recordIDs := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				recordIDs = append(recordIDs, entry.RecordID)
+			}
+			err = j.db.DeleteEntries(recordIDs)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Purged += len(entries)
+		if len(entries) < j.batchSize {
+			result.Done = true
+			return nil
+		}
+		if batch == maxBatches {
+			return nil
+		}
+		time.Sleep(batchPause)
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 var mockAuditLogStore []models.AuditLog
 
-func logAuditEvent(userID, action, details string) {
+func logAuditEvent(userID string, action models.AuditAction, details string) {
 	logEntry := models.AuditLog{
 		LogID:     fmt.Sprintf("log-%d", time.Now().UnixNano()),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
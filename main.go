@@ -6,11 +6,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"gatekeeper/auth"
 	"gatekeeper/config"
 	"gatekeeper/db"
+	"gatekeeper/exports"
 	"gatekeeper/handlers"
+	"gatekeeper/health"
+	"gatekeeper/jobs"
 	"gatekeeper/middleware"
 	"log"
 	"net/http"
@@ -31,10 +36,28 @@ var (
 	syncHandler      *handlers.SyncHandler
 	adminHandler     *handlers.AdminHandler
 	supervisorHandler *handlers.SupervisorHandler
+	maintenanceHandler *handlers.MaintenanceHandler
+	rateLimitHandler *handlers.RateLimitHandler
+	auditHandler     *handlers.AuditHandler
+	retentionJob     *jobs.RetentionJob
 	rateLimiter      *middleware.RateLimiter
+	startupReport    *health.Report
 )
 
 func main() {
+	printIndexes := flag.Bool("print-indexes", false, "print the firestore.indexes.json required by all known queries and exit")
+	printConfig := flag.Bool("print-config", false, "print the resolved configuration as JSON with secrets redacted and exit, with status 1 if it is invalid")
+	flag.Parse()
+
+	if *printIndexes {
+		indexes, err := db.IndexesJSON()
+		if err != nil {
+			log.Fatalf("❌ Failed to render indexes: %v", err)
+		}
+		fmt.Println(string(indexes))
+		return
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("⚠️  No .env file found, using system environment variables")
@@ -42,6 +65,18 @@ func main() {
 
 	// Load configuration
 	cfg = config.Load()
+	if *printConfig {
+		dump := cfg.Dump()
+		out, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			log.Fatalf("❌ Failed to render configuration: %v", err)
+		}
+		fmt.Println(string(out))
+		if len(dump.Problems) > 0 || len(dump.InvalidEnv) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 	cfg.Validate()
 
 	log.Printf("🚀 Starting GateKeeper API Server")
@@ -51,11 +86,21 @@ func main() {
 	// Initialize Firestore
 	ctx := context.Background()
 	var err error
-	firestoreDB, err = db.NewFirestoreDB(ctx, cfg.Firebase.ProjectID, cfg.Firebase.CredentialsPath)
+	firestoreDB, err = db.NewFirestoreDB(ctx, cfg.FirestoreOptions())
 	if err != nil {
 		log.Fatalf("❌ Failed to initialize Firestore: %v", err)
 	}
 	defer firestoreDB.Close()
+	firestoreDB.SetCheckpointCacheTTL(cfg.Cache.CheckpointTTL)
+	firestoreDB.SetUserCacheTTL(cfg.Cache.UserTTL)
+
+	// Fail fast on configuration that would otherwise fall back to defaults silently
+	startupReport = cfg.SelfCheck()
+	startupReport.Add(firestoreCheck(ctx))
+	startupReport.Log()
+	if startupReport.Failed() {
+		log.Fatal("❌ Startup self-check failed; fix the issues above")
+	}
 
 	// Initialize JWT Manager
 	jwtManager = auth.NewJWTManager(
@@ -63,53 +108,232 @@ func main() {
 		cfg.JWT.Expiration,
 		cfg.JWT.RefreshTokenExpiration,
 	)
+	jwtManager.SetLeeway(cfg.JWT.Leeway)
+	jwtManager.SetValidMethods(cfg.JWT.Algorithms)
 	log.Printf("🔐 JWT Manager initialized (expiration: %v)", cfg.JWT.Expiration)
 
+	denyList, err := cfg.PasswordDenyList()
+	if err != nil {
+		log.Fatalf("❌ Failed to load password deny list: %v", err)
+	}
+	if denyList != nil {
+		auth.SetPasswordDenyList(denyList)
+		log.Printf("🚫 Password deny list enabled (%d banned passwords, breach dataset: %t)", denyList.Size(), cfg.Password.BreachedRangesDir != "")
+	}
+
 	// Initialize handlers
-	authHandler = handlers.NewAuthHandler(firestoreDB, jwtManager)
+	var tokenStore auth.TokenStore = firestoreDB
+	if cfg.JWT.TokenStore == "memory" {
+		tokenStore = auth.NewMemoryTokenStore()
+	}
+	log.Printf("🎟️  Refresh sessions stored in %s", cfg.JWT.TokenStore)
+	// Validate has already rejected malformed ranges
+	trustedProxies, err := cfg.ParsedTrustedProxies()
+	if err != nil {
+		log.Fatalf("❌ Invalid trusted proxies: %v", err)
+	}
+	if len(trustedProxies) > 0 {
+		log.Printf("🔀 Client IPs taken from X-Forwarded-For when sent by %v", trustedProxies)
+	} else {
+		log.Printf("🔀 X-Forwarded-For ignored; set TRUSTED_PROXIES when running behind a load balancer")
+	}
+	authHandler = handlers.NewAuthHandler(firestoreDB, jwtManager, tokenStore)
+	authHandler.SetTrustedProxies(trustedProxies)
+	if cfg.Captcha.Enabled {
+		verifyURL := auth.TurnstileVerifyURL
+		if cfg.Captcha.Provider == "hcaptcha" {
+			verifyURL = auth.HCaptchaVerifyURL
+		}
+		authHandler.EnableCaptcha(auth.NewHTTPCaptchaVerifier(verifyURL, cfg.Captcha.Secret), cfg.Captcha.Threshold)
+		log.Printf("🧩 CAPTCHA challenge enabled (%s after %d failed logins)", cfg.Captcha.Provider, cfg.Captcha.Threshold)
+	}
+	if cfg.Lockout.Threshold > 0 {
+		authHandler.EnableLockout(cfg.Lockout.Threshold)
+		log.Printf("🔒 Account lockout enabled (after %d failed logins)", cfg.Lockout.Threshold)
+	}
+	if idle := cfg.IdlePolicy(); idle.Enabled() {
+		authHandler.SetIdlePolicy(idle)
+		log.Printf("💤 Idle session expiry enabled (default %v, per role %v)", idle.Timeout, idle.RoleTimeout)
+	}
+	handlers.ConfigurePagination(cfg.Pagination.DefaultPageSize, cfg.Pagination.MaxPageSize)
 	syncHandler = handlers.NewSyncHandler(firestoreDB)
+	syncHandler.SetPushConcurrency(cfg.Sync.PushConcurrency)
+	syncHandler.SetMaxPayloadBytes(cfg.Sync.MaxPayloadBytes)
+	syncHandler.SetMaxClockAhead(cfg.Sync.MaxClockAhead)
 	adminHandler = handlers.NewAdminHandler(firestoreDB)
+	adminHandler.SetLockoutSource(authHandler)
+	adminHandler.SetUserDefaults(handlers.UserDefaults{
+		Role:               cfg.UserDefaults.Role,
+		AllowedCheckpoints: cfg.UserDefaults.AllowedCheckpoints,
+	})
 	supervisorHandler = handlers.NewSupervisorHandler(firestoreDB)
+	if cfg.Export.Bucket != "" {
+		uploader, err := exports.NewUploader(ctx, cfg.ExportOptions())
+		if err != nil {
+			log.Fatalf("❌ Failed to initialize export storage: %v", err)
+		}
+		defer uploader.Close()
+		supervisorHandler.EnableStorageExport(uploader)
+	}
+	auditHandler = handlers.NewAuditHandler(firestoreDB)
+
+	// Initialize background jobs
+	retentionJob = jobs.NewRetentionJob(
+		firestoreDB,
+		cfg.Retention.EntryRetentionDays,
+		cfg.Retention.EntryArchive,
+		cfg.Retention.PurgeInterval,
+		cfg.Retention.PurgeBatchSize,
+	)
+	retentionJob.Start()
+	if retentionJob.Enabled() {
+		log.Printf("🧹 Entry retention enabled (%d days, archive: %t)", cfg.Retention.EntryRetentionDays, cfg.Retention.EntryArchive)
+	}
+	if cfg.Retention.AuditRetentionDays > 0 {
+		archiver, err := exports.NewUploader(ctx, cfg.AuditArchiveOptions())
+		if err != nil {
+			log.Fatalf("❌ Failed to initialize audit archive storage: %v", err)
+		}
+		defer archiver.Close()
+		auditRetentionJob := jobs.NewAuditRetentionJob(
+			firestoreDB,
+			archiver,
+			cfg.Retention.AuditRetentionDays,
+			cfg.Retention.AuditDelete,
+			cfg.Retention.PurgeInterval,
+			cfg.Retention.PurgeBatchSize,
+		)
+		auditRetentionJob.Start()
+		log.Printf("🗄️  Audit log archival enabled (%d days, to bucket %s, delete: %t)", cfg.Retention.AuditRetentionDays, cfg.Retention.AuditArchiveBucket, cfg.Retention.AuditDelete)
+	}
+	maintenanceHandler = handlers.NewMaintenanceHandler(firestoreDB, retentionJob)
 	log.Printf("✅ Handlers initialized")
 
 	// Initialize rate limiter
 	rateLimiter = middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	rateLimiter.SetExemptPaths(cfg.RateLimit.ExemptPaths)
+	rateLimiter.SetTrustedProxies(trustedProxies)
+	rateLimiter.SetAdminLimit(jwtManager, cfg.RateLimit.AdminRequests)
 	rateLimiter.CleanupOldLimiters()
+	rateLimitHandler = handlers.NewRateLimitHandler(rateLimiter)
 	log.Printf("🛡️  Rate limiter initialized (%d requests per %v)", cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	if cfg.RateLimit.AdminRequests > 0 {
+		log.Printf("🛡️  Admins limited per user instead (%d requests per %v)", cfg.RateLimit.AdminRequests, cfg.RateLimit.Window)
+	}
+
+	// Optional endpoints are wrapped in features.RequireFeature and 404 until enabled
+	features := middleware.NewFeatureFlags(cfg.Features.Flags, http.HandlerFunc(handlers.NotFound))
+	if enabled := features.EnabledFlags(); len(enabled) > 0 {
+		log.Printf("🚩 Features enabled: %v", enabled)
+	} else {
+		log.Printf("🚩 No optional features enabled; set FEATURE_FLAGS to enable some")
+	}
 
 	// Set up router
 	mux := http.NewServeMux()
 
+	// Unmatched paths get a JSON 404 rather than the router's plain-text default
+	mux.HandleFunc("/", handlers.NotFound)
+
 	// Public routes (no authentication required)
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/readyz", handleReady)
 	mux.HandleFunc("/api/login", authHandler.Login)
 	mux.HandleFunc("/api/refresh", authHandler.RefreshToken)
 
-	// Protected routes (authentication required)
-	authMiddleware := middleware.AuthMiddleware(jwtManager, firestoreDB)
+	// Protected routes (authentication required). The global rate limiter runs before
+	// authentication and trusts the token's admin role on these routes only; the admin tier
+	// check runs after it and charges the IP when the stored user is no longer an admin.
+	verifyUser := middleware.AuthMiddleware(jwtManager, firestoreDB, cfg.JWT.RejectStaleRole, cfg.IdlePolicy())
+	adminTier := rateLimiter.AdminTierMiddleware()
+	authMiddleware := func(next http.Handler) http.Handler {
+		return middleware.AdminTierRoute{Handler: verifyUser(adminTier(next))}
+	}
+	rateLimiter.SetRoutes(mux)
+	audit := middleware.AuditMiddleware(firestoreDB)
+	mux.Handle("/api/auth/verify", authMiddleware(http.HandlerFunc(authHandler.VerifyToken)))
 	
 	// Sync endpoints
 	mux.Handle("/api/sync/push", authMiddleware(http.HandlerFunc(syncHandler.Push)))
 	mux.Handle("/api/sync/pull", authMiddleware(http.HandlerFunc(syncHandler.Pull)))
+	mux.Handle("/api/sync/ack", authMiddleware(http.HandlerFunc(syncHandler.Ack)))
+	// Conflict resolution rolls out with the client release that uses it
+	mux.Handle("/api/sync/resolve", features.RequireFeature("sync_resolve")(authMiddleware(audit(http.HandlerFunc(syncHandler.ResolveEntry)))))
+
+	// Online entry creation and status changes
+	mux.Handle("/api/entries", authMiddleware(http.HandlerFunc(syncHandler.CreateEntry)))
+	mux.Handle("/api/entries/status", authMiddleware(audit(http.HandlerFunc(syncHandler.SetStatus))))
+	mux.Handle("/api/entries/mine", authMiddleware(http.HandlerFunc(syncHandler.MyEntries)))
+	mux.Handle("/api/entries/mine/count", authMiddleware(http.HandlerFunc(syncHandler.MyEntryCount)))
 
-	// Admin endpoints (admin only)
+	// Admin endpoints (admin only). The whole subtree runs behind the audit middleware,
+	// so every mutating admin request is audited, including endpoints added later.
 	adminOnly := middleware.RequireRole("ADMIN")
-	mux.Handle("/api/admin/users", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.GetUsers))))
-	mux.Handle("/api/admin/users/create", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.CreateUser))))
-	mux.Handle("/api/admin/users/update", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.UpdateUser))))
-	mux.Handle("/api/admin/users/delete", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.DeleteUser))))
-	mux.Handle("/api/admin/checkpoints", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.GetCheckpoints))))
-	mux.Handle("/api/admin/checkpoints/create", authMiddleware(adminOnly(http.HandlerFunc(adminHandler.CreateCheckpoint))))
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("/api/admin/", handlers.NotFound)
+	adminMux.HandleFunc("/api/admin/users", adminHandler.GetUsers)
+	adminMux.HandleFunc("/api/admin/users/detail", adminHandler.GetUserDetail)
+	adminMux.HandleFunc("/api/admin/users/create", adminHandler.CreateUser)
+	adminMux.HandleFunc("/api/admin/users/update", adminHandler.UpdateUser)
+	adminMux.HandleFunc("/api/admin/users/checkpoints", adminHandler.SetUserCheckpoints)
+	adminMux.HandleFunc("/api/admin/users/roles", adminHandler.UpdateRoles)
+	adminMux.HandleFunc("/api/admin/users/unassign-supervisor", adminHandler.UnassignSupervisor)
+	adminMux.HandleFunc("/api/admin/users/delete", adminHandler.DeleteUser)
+	adminMux.HandleFunc("/api/admin/users/lockout", authHandler.Lockout)
+	adminMux.HandleFunc("/api/admin/users/import", adminHandler.ImportUsers)
+	adminMux.HandleFunc("/api/admin/checkpoints", adminHandler.GetCheckpoints)
+	adminMux.HandleFunc("/api/admin/checkpoints/create", adminHandler.CreateCheckpoint)
+	adminMux.HandleFunc("/api/admin/checkpoints/upsert", adminHandler.UpsertCheckpoint)
+	adminMux.HandleFunc("/api/admin/checkpoints/import", adminHandler.ImportCheckpoints)
+	adminMux.HandleFunc("/api/admin/checkpoints/assign", adminHandler.AssignCheckpoint)
+	adminMux.HandleFunc("/api/admin/checkpoints/unassign", adminHandler.UnassignCheckpoint)
+	adminMux.HandleFunc("/api/admin/entries/delete", adminHandler.DeleteEntries)
+	adminMux.HandleFunc("/api/admin/entries/reassign", adminHandler.ReassignEntries)
+	adminMux.HandleFunc("/api/admin/entries/trail", adminHandler.GetEntryTrail)
+	adminMux.HandleFunc("/api/admin/stats", adminHandler.Stats)
+	adminMux.HandleFunc("/api/admin/sync-health", adminHandler.SyncHealth)
+	adminMux.HandleFunc("/api/admin/audit", auditHandler.GetAuditLogs)
+	adminMux.HandleFunc("/api/admin/audit/export", auditHandler.ExportAuditLogs)
+	adminMux.HandleFunc("/api/admin/diagnostics", handlers.NewDiagnosticsHandler(cfg.Summary()).Diagnostics)
+	adminMux.HandleFunc("/api/admin/ratelimit", rateLimitHandler.Stats)
+	adminMux.HandleFunc("/api/admin/maintenance/purge", maintenanceHandler.PurgeEntries)
+	adminMux.HandleFunc("/api/admin/maintenance/backfill", maintenanceHandler.Backfill)
+	mux.Handle("/api/admin/", authMiddleware(adminOnly(audit(adminMux))))
+
+	// CORS diagnostics are open during development; in production they reveal the
+	// allowlist, so only admins may read them
+	corsDebug := http.Handler(http.HandlerFunc(handlers.NewCORSDebugHandler(cfg.CORS.AllowedOrigins, cfg.CORS.OperationalOrigins, cfg.CORS.OperationalPaths).CORS))
+	if cfg.IsProduction() {
+		corsDebug = authMiddleware(adminOnly(corsDebug))
+	}
+	mux.Handle("/api/debug/cors", corsDebug)
 
 	// Supervisor endpoints (supervisor or admin)
 	supervisorOrAdmin := middleware.RequireRole("SUPERVISOR", "ADMIN")
+	// Granted to supervisors and admins by default and to other users individually
+	canReview := middleware.RequirePermission("REVIEW_ENTRIES")
+	canExport := middleware.RequirePermission("EXPORT_ENTRIES")
+	canResetPasswords := middleware.RequirePermission("RESET_PASSWORDS")
 	mux.Handle("/api/supervisor/entries", authMiddleware(supervisorOrAdmin(http.HandlerFunc(supervisorHandler.GetEntries))))
-	mux.Handle("/api/supervisor/export", authMiddleware(supervisorOrAdmin(http.HandlerFunc(supervisorHandler.ExportEntries))))
-	mux.Handle("/api/supervisor/reset-password", authMiddleware(supervisorOrAdmin(http.HandlerFunc(supervisorHandler.ResetPassword))))
+	mux.Handle("/api/supervisor/entries/review", authMiddleware(canReview(audit(http.HandlerFunc(supervisorHandler.ReviewEntries)))))
+	mux.Handle("/api/supervisor/checkpoint-entries", authMiddleware(supervisorOrAdmin(http.HandlerFunc(supervisorHandler.GetCheckpointEntries))))
+	mux.Handle("/api/supervisor/export", authMiddleware(canExport(http.HandlerFunc(supervisorHandler.ExportEntries))))
+	mux.Handle("/api/supervisor/export/upload", authMiddleware(canExport(audit(http.HandlerFunc(supervisorHandler.ExportEntriesToStorage)))))
+	mux.Handle("/api/supervisor/reset-password", authMiddleware(canResetPasswords(audit(http.HandlerFunc(supervisorHandler.ResetPassword)))))
+	mux.Handle("/api/supervisor/reset-passwords", authMiddleware(canResetPasswords(http.HandlerFunc(supervisorHandler.ResetPasswords))))
 
 	// Apply global middleware
-	handler := middleware.CORSMiddleware(cfg.CORS.AllowedOrigins)(mux)
+	handler := http.Handler(mux)
+	if cfg.Logging.DebugRequests {
+		handler = middleware.RequestLogMiddleware()(handler)
+	}
+	handler = middleware.GzipMiddleware(cfg.Compression.MinSize, cfg.Compression.Level)(handler)
+	handler = middleware.RequireJSONMiddleware("/api/")(handler)
+	handler = middleware.CORSMiddleware(cfg.CORS.AllowedOrigins, cfg.CORS.OperationalOrigins, cfg.CORS.OperationalPaths)(handler)
 	handler = rateLimiter.Middleware()(handler)
+	if cfg.TLSEnabled() {
+		handler = middleware.HSTSMiddleware(cfg.Server.HSTSMaxAge)(handler)
+	}
 
 	// Create server
 	server := &http.Server{
@@ -120,14 +344,48 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	if cfg.TLSEnabled() {
+		// Validate has already rejected settings this would fail on
+		tlsConfig, err := cfg.TLSConfig()
+		if err != nil {
+			log.Fatalf("❌ Invalid TLS configuration: %v", err)
+		}
+		server.TLSConfig = tlsConfig
+		log.Printf("🔐 TLS minimum version %s", cfg.Server.TLSMinVersion)
+	}
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("✅ Server listening on %s", server.Addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if cfg.TLSEnabled() {
+			log.Printf("✅ Server listening on %s (TLS)", server.Addr)
+			err = server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			log.Printf("✅ Server listening on %s", server.Addr)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("❌ Server failed to start: %v", err)
 		}
 	}()
 
+	// Optional HTTP listener that redirects everything to HTTPS
+	var redirectServer *http.Server
+	if cfg.TLSEnabled() && cfg.Server.HTTPRedirectPort != "" {
+		redirectServer = &http.Server{
+			Addr:         fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.HTTPRedirectPort),
+			Handler:      middleware.HTTPSRedirectHandler(cfg.Server.Port),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+		go func() {
+			log.Printf("↪️  HTTP redirect listening on %s", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("❌ Redirect server failed to start: %v", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -141,6 +399,9 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("❌ Server forced to shutdown: %v", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
 
 	log.Println("✅ Server stopped gracefully")
 }
@@ -151,3 +412,45 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, `{"status":"healthy","timestamp":%d,"version":"1.0.0"}`, time.Now().Unix())
 }
+
+// readyTimeout bounds the Firestore round-trip of a readiness check
+const readyTimeout = 3 * time.Second
+
+// firestoreCheck pings Firestore for the self-check report
+func firestoreCheck(ctx context.Context) health.Check {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := firestoreDB.Ping(ctx); err != nil {
+		return health.Check{Name: "firestore", Status: health.StatusFail, Detail: err.Error()}
+	}
+	return health.Check{Name: "firestore", Status: health.StatusPass, Detail: fmt.Sprintf("reachable in %v", time.Since(start).Round(time.Millisecond))}
+}
+
+// Readiness endpoint: the startup self-check report with a live Firestore check.
+// Responds 503 while any check fails so load balancers stop routing here.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	report := &health.Report{CheckedAt: time.Now().UTC()}
+	for _, check := range startupReport.Checks {
+		if check.Name != "firestore" {
+			report.Add(check)
+		}
+	}
+	report.Add(firestoreCheck(r.Context()))
+
+	status := http.StatusOK
+	ready := "ready"
+	if report.Failed() {
+		status = http.StatusServiceUnavailable
+		ready = "not_ready"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     ready,
+		"checked_at": report.CheckedAt,
+		"checks":     report.Checks,
+	})
+}
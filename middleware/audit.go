@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"log"
+	"net/http"
+	"time"
+)
+
+const auditContextKey contextKey = "audit"
+
+// auditDetails collects the action and details a handler attaches to the audit event
+type auditDetails struct {
+	action    models.AuditAction
+	details   string
+	recordIDs []string
+}
+
+// statusRecorder wraps a ResponseWriter to capture the response status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// AuditMiddleware records an audit event for every mutating request (POST/PUT/DELETE)
+// once the handler has completed. It must run after AuthMiddleware so the actor is known.
+func AuditMiddleware(firestoreDB *db.FirestoreDB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			details := &auditDetails{action: models.AuditActionAdminRequest}
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), auditContextKey, details)))
+
+			writeAuditLog(firestoreDB, r, details.action, details.details, details.recordIDs, recorder.status)
+		})
+	}
+}
+
+// WriteAuditLog records one audit event for the request's authenticated user. Handlers
+// that perform several audited operations in one request use it to log each separately.
+func WriteAuditLog(firestoreDB *db.FirestoreDB, r *http.Request, action models.AuditAction, details string, status int) {
+	writeAuditLog(firestoreDB, r, action, details, nil, status)
+}
+
+func writeAuditLog(firestoreDB *db.FirestoreDB, r *http.Request, action models.AuditAction, details string, recordIDs []string, status int) {
+	actorID, orgID := "", ""
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		actorID = user.UserID
+		orgID = user.OrgID
+	}
+
+	route := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+	auditLog := &models.AuditLog{
+		Timestamp:  models.FormatTimestamp(time.Now()),
+		UserID:     actorID,
+		Action:     action,
+		Details:    details,
+		Route:      route,
+		StatusCode: status,
+		OrgID:      orgID,
+		RecordIDs:  recordIDs,
+	}
+	if err := firestoreDB.CreateAuditLog(auditLog); err != nil {
+		log.Printf("❌ Failed to write audit log for %s: %v", route, err)
+	}
+}
+
+// SetAuditEvent names the action and attaches details to the audit event recorded for the
+// current request. It is a no-op when the request is not wrapped by AuditMiddleware.
+func SetAuditEvent(ctx context.Context, action models.AuditAction, details string) {
+	if d, ok := ctx.Value(auditContextKey).(*auditDetails); ok {
+		d.action = action
+		d.details = details
+	}
+}
+
+// SetAuditRecords attaches the record IDs of the entries the request changed to its audit
+// event, so the event shows up in each entry's trail. Like SetAuditEvent it is a no-op
+// outside AuditMiddleware.
+func SetAuditRecords(ctx context.Context, recordIDs []string) {
+	if d, ok := ctx.Value(auditContextKey).(*auditDetails); ok {
+		d.recordIDs = recordIDs
+	}
+}
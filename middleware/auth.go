@@ -3,18 +3,33 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"gatekeeper/auth"
 	"gatekeeper/db"
 	"gatekeeper/models"
+	"log"
 	"net/http"
+	"time"
 )
 
 type contextKey string
 
 const UserContextKey contextKey = "user"
 
-// AuthMiddleware validates JWT tokens and injects user into context
-func AuthMiddleware(jwtManager *auth.JWTManager, firestoreDB *db.FirestoreDB) func(http.Handler) http.Handler {
+// ClaimsContextKey holds the validated token claims of the authenticated request
+const ClaimsContextKey contextKey = "claims"
+
+// UserStore is the user storage AuthMiddleware needs. *db.FirestoreDB implements it.
+type UserStore interface {
+	GetCachedUser(userID string) (*models.User, error)
+	TouchLastActivity(userID string, at time.Time) error
+}
+
+// AuthMiddleware validates JWT tokens and injects user into context. The user's role is
+// always taken from the database; when rejectStaleRole is set, tokens whose embedded role
+// no longer matches it are refused so the client must log in again. Users inactive for
+// longer than the idle policy allows must log in again as well.
+func AuthMiddleware(jwtManager *auth.JWTManager, users UserStore, rejectStaleRole bool, idle auth.IdlePolicy) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -30,22 +45,68 @@ func AuthMiddleware(jwtManager *auth.JWTManager, firestoreDB *db.FirestoreDB) fu
 				return
 			}
 
-			// Validate token
-			claims, err := jwtManager.ValidateToken(token)
+			// Validate token; refresh tokens are not accepted here
+			claims, err := jwtManager.ValidateAccessToken(token)
 			if err != nil {
 				writeError(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
 			}
 
-			// Fetch user from database to get latest data
-			user, err := firestoreDB.GetUser(claims.UserID)
+			// The subject and user_id claims are both set at issue time and must agree
+			if claims.Subject != "" && claims.Subject != claims.UserID {
+				log.Printf("⚠️  Token subject %s does not match user_id %s", claims.Subject, claims.UserID)
+				writeError(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			// Fetch user from database to get latest data, allowing for the few seconds
+			// another instance's change may take to show up through the user cache.
+			// A lookup failure is not a reason to log the client out
+			user, err := users.GetCachedUser(claims.UserID)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				log.Printf("❌ Failed to load user %s: %v", claims.UserID, err)
+				writeError(w, "Failed to load user", http.StatusInternalServerError)
+				return
+			}
 			if err != nil {
 				writeError(w, "User not found", http.StatusUnauthorized)
 				return
 			}
 
+			// Tokens are only valid for the organization they were issued in,
+			// and only until the user's password changes
+			if claims.OrgID != user.OrgID || claims.IssuedBeforePasswordChange(user) {
+				writeError(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			// A role change since the token was issued means a stale token is in use
+			if claims.Role != user.Role {
+				log.Printf("⚠️  Stale role in token for %s: token has %s, user is now %s", user.Username, claims.Role, user.Role)
+				if rejectStaleRole {
+					writeError(w, "Your role has changed. Please log in again", http.StatusUnauthorized)
+					return
+				}
+			}
+
+			now := models.Now()
+			if idle.Expired(user, now) {
+				log.Printf("Session for %s expired after inactivity since %s", user.Username, user.LastActivityAt.Format(time.RFC3339))
+				writeError(w, "Session expired due to inactivity. Please log in again", http.StatusUnauthorized)
+				return
+			}
+			if idle.ActivityDue(user, now) {
+				// Failing to record activity must not fail the request
+				if err := users.TouchLastActivity(user.UserID, now); err != nil {
+					log.Printf("⚠️  Failed to record activity for %s: %v", user.Username, err)
+				}
+			}
+
+			setRequestLogUser(r.Context(), user.UserID)
+
 			// Inject user into context
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -57,7 +118,14 @@ func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	return user, ok
 }
 
-// RequireRole middleware checks if the user has the required role
+// GetClaimsFromContext retrieves the validated token claims from the request context
+func GetClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(ClaimsContextKey).(*auth.Claims)
+	return claims, ok
+}
+
+// RequireRole middleware checks if the user has the required role.
+// Super admins pass every role check.
 func RequireRole(allowedRoles ...models.UserRole) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -68,7 +136,7 @@ func RequireRole(allowedRoles ...models.UserRole) func(http.Handler) http.Handle
 			}
 
 			// Check if user has one of the allowed roles
-			hasRole := false
+			hasRole := user.Role == models.RoleSuperAdmin
 			for _, role := range allowedRoles {
 				if user.Role == role {
 					hasRole = true
@@ -86,6 +154,27 @@ func RequireRole(allowedRoles ...models.UserRole) func(http.Handler) http.Handle
 	}
 }
 
+// RequirePermission middleware checks that the user holds the permission, either through
+// their role's defaults or an explicit grant.
+func RequirePermission(permission models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
+				writeError(w, "User not found in context", http.StatusUnauthorized)
+				return
+			}
+
+			if !user.HasPermission(permission) {
+				writeError(w, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func writeError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"gatekeeper/auth"
+	"gatekeeper/db"
+	"gatekeeper/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeUserStore serves users from memory
+type fakeUserStore struct {
+	users map[string]*models.User
+}
+
+func (s *fakeUserStore) GetCachedUser(userID string) (*models.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (s *fakeUserStore) TouchLastActivity(userID string, at time.Time) error {
+	return nil
+}
+
+func authenticate(t *testing.T, users UserStore, jwtManager *auth.JWTManager, token string) int {
+	t.Helper()
+	handler := AuthMiddleware(jwtManager, users, false, auth.IdlePolicy{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/verify", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestTokenIssuedBeforePasswordResetRejected(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret-at-least-32-characters-long", time.Hour, 24*time.Hour)
+	user := &models.User{
+		UserID:            "user-1",
+		Username:          "operator",
+		Role:              models.RoleGateOperator,
+		PasswordChangedAt: time.Now().Add(-time.Hour),
+	}
+	store := &fakeUserStore{users: map[string]*models.User{user.UserID: user}}
+
+	token, err := jwtManager.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if code := authenticate(t, store, jwtManager, token); code != http.StatusOK {
+		t.Fatalf("status before reset = %d, want %d", code, http.StatusOK)
+	}
+
+	// The password is reset after the token was issued
+	user.PasswordChangedAt = time.Now().Add(time.Second)
+
+	if code := authenticate(t, store, jwtManager, token); code != http.StatusUnauthorized {
+		t.Errorf("status after reset = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	fresh, err := jwtManager.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if code := authenticate(t, store, jwtManager, fresh); code != http.StatusOK {
+		t.Errorf("status of token issued after reset = %d, want %d", code, http.StatusOK)
+	}
+}
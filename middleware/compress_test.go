@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveGzip sends body through GzipMiddleware with a 1 KB threshold
+func serveGzip(t *testing.T, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := GzipMiddleware(1024, gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, body)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/pull", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipSmallResponseUncompressed(t *testing.T) {
+	body := `{"entries":[]}`
+	rec := serveGzip(t, "application/json", body)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+	if rec.Body.String() != body {
+		t.Errorf("body = %q, want %q", rec.Body.String(), body)
+	}
+}
+
+func TestGzipLargeResponseCompressed(t *testing.T) {
+	body := `{"entries":[` + strings.Repeat(`{"record_id":"rec","checkpoint_id":"CP-1"},`, 100) + `{}]}`
+	rec := serveGzip(t, "application/json", body)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if rec.Body.Len() >= len(body) {
+		t.Errorf("compressed body is %d bytes, not smaller than %d", rec.Body.Len(), len(body))
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if string(decoded) != body {
+		t.Error("decompressed body differs from the original")
+	}
+}
+
+func TestGzipSkipsPrecompressedContent(t *testing.T) {
+	body := strings.Repeat("x", 4096)
+	rec := serveGzip(t, "image/png", body)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none for an image", got)
+	}
+	if rec.Body.Len() != len(body) {
+		t.Errorf("body is %d bytes, want %d", rec.Body.Len(), len(body))
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// RequireJSONMiddleware rejects POST, PUT and DELETE requests under prefix that carry a
+// body with any Content-Type other than application/json, answering 415 instead of
+// letting the handler fail with a generic decode error. Bodiless requests pass through.
+func RequireJSONMiddleware(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, prefix) || !hasBody(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeError(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasBody reports whether the request carries a body; a length of -1 means unknown
+// (e.g. chunked), which is treated as present
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
@@ -2,35 +2,62 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 )
 
-// CORSMiddleware handles CORS headers
-func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+// defaultAllowedHeaders are always permitted, even if the preflight doesn't list them
+const defaultAllowedHeaders = "Content-Type, Authorization"
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight response
+const corsMaxAge = 3600
+
+// corsRule returns the origin allowlist and methods that apply to path. Operational
+// endpoints are read-only and never need credentials.
+func corsRule(allowedOrigins, opsOrigins, opsPaths []string, path string) (origins []string, methods string, operational bool) {
+	if matchesPath(opsPaths, path) {
+		return opsOrigins, "GET, OPTIONS", true
+	}
+	return allowedOrigins, "GET, POST, PUT, DELETE, OPTIONS", false
+}
+
+// originAllowed reports whether origin is in origins. Only operational endpoints, which
+// never send credentials, may use the * wildcard.
+func originAllowed(origins []string, origin string, operational bool) bool {
+	return origin != "" && (slices.Contains(origins, origin) || (operational && slices.Contains(origins, "*")))
+}
+
+// CORSMiddleware handles CORS headers. Operational endpoints (opsPaths, where a trailing /
+// matches a subtree) use their own origin allowlist, so a monitoring dashboard can read
+// them without being trusted by the app; with no opsOrigins they are same-origin only.
+func CORSMiddleware(allowedOrigins, opsOrigins, opsPaths []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
-				if origin == allowedOrigin {
-					allowed = true
-					break
-				}
-			}
+			origins, methods, operational := corsRule(allowedOrigins, opsOrigins, opsPaths, r.URL.Path)
+			allowed := originAllowed(origins, origin, operational)
+
+			// The response varies by origin, so caches must not share it across origins
+			w.Header().Add("Vary", "Origin")
 
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if !operational {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Max-Age", "3600")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-
 			// Handle preflight requests
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodOptions {
+				if allowed {
+					w.Header().Add("Vary", "Access-Control-Request-Headers")
+					w.Header().Set("Access-Control-Allow-Methods", methods)
+					w.Header().Set("Access-Control-Allow-Headers", allowedHeaders(r.Header.Get("Access-Control-Request-Headers")))
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+				}
+				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 
@@ -38,3 +65,65 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// allowedHeaders echoes the headers a browser asked for in its preflight,
+// always including Content-Type and Authorization for credentialed JSON calls
+func allowedHeaders(requested string) string {
+	if requested == "" {
+		return defaultAllowedHeaders
+	}
+	return defaultAllowedHeaders + ", " + requested
+}
+
+// CORSExplanation describes how CORSMiddleware treats requests from an origin to a path
+type CORSExplanation struct {
+	Origin         string   `json:"origin"`
+	Path           string   `json:"path"`
+	Allowed        bool     `json:"allowed"`
+	Credentials    bool     `json:"credentials"` // Whether cookies and Authorization may be sent
+	Reason         string   `json:"reason"`
+	Operational    bool     `json:"operational"`
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers"` // Always allowed; preflights also echo any requested headers
+	MaxAgeSeconds  int      `json:"max_age_seconds"`
+}
+
+// ExplainCORS reports the CORS policy CORSMiddleware applies to path with the same
+// arguments, and whether origin would be allowed and why
+func ExplainCORS(allowedOrigins, opsOrigins, opsPaths []string, origin, path string) CORSExplanation {
+	origins, methods, operational := corsRule(allowedOrigins, opsOrigins, opsPaths, path)
+	allowed := originAllowed(origins, origin, operational)
+
+	explanation := CORSExplanation{
+		Origin:         origin,
+		Path:           path,
+		Allowed:        allowed,
+		Credentials:    allowed && !operational,
+		Operational:    operational,
+		AllowedOrigins: append([]string{}, origins...),
+		AllowedMethods: strings.Split(methods, ", "),
+		AllowedHeaders: strings.Split(defaultAllowedHeaders, ", "),
+		MaxAgeSeconds:  corsMaxAge,
+	}
+
+	switch {
+	case origin == "":
+		explanation.Reason = "no origin given; pass ?origin=https://host[:port]"
+	case slices.Contains(origins, origin):
+		explanation.Reason = "origin is listed"
+	case allowed:
+		explanation.Reason = "operational endpoints allow any origin"
+	case strings.HasSuffix(origin, "/") && slices.Contains(origins, strings.TrimSuffix(origin, "/")):
+		explanation.Reason = "browsers send origins without a trailing slash; the origin without it is listed"
+	case slices.ContainsFunc(origins, func(o string) bool { return strings.EqualFold(o, origin) }):
+		explanation.Reason = "origin differs from a listed origin only in case; list it exactly as the browser sends it"
+	case !operational && slices.Contains(origins, "*"):
+		explanation.Reason = "the * wildcard is ignored for credentialed endpoints; list the origin explicitly"
+	case operational:
+		explanation.Reason = "origin is not in CORS_OPERATIONAL_ORIGINS"
+	default:
+		explanation.Reason = "origin is not in ALLOWED_ORIGINS"
+	}
+	return explanation
+}
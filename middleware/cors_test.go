@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const devOrigin = "http://localhost:5173"
+
+// preflight sends a browser preflight for a credentialed PUT to /api/admin/users/update
+func preflight(t *testing.T, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	called := false
+	handler := CORSMiddleware([]string{devOrigin}, nil, []string{"/health"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	req := httptest.NewRequest(http.MethodOptions, "/api/admin/users/update", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type, x-request-id")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if called {
+		t.Error("preflight reached the handler")
+	}
+	return rec
+}
+
+func TestPreflightForCredentialedPut(t *testing.T) {
+	rec := preflight(t, devOrigin)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	header := rec.Header()
+	if got := header.Get("Access-Control-Allow-Origin"); got != devOrigin {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, devOrigin)
+	}
+	if got := header.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := header.Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPut) {
+		t.Errorf("Access-Control-Allow-Methods = %q, want it to include PUT", got)
+	}
+	allowHeaders := strings.ToLower(header.Get("Access-Control-Allow-Headers"))
+	for _, want := range []string{"authorization", "content-type", "x-request-id"} {
+		if !strings.Contains(allowHeaders, want) {
+			t.Errorf("Access-Control-Allow-Headers = %q, want it to include %s", allowHeaders, want)
+		}
+	}
+	if got := header.Get("Access-Control-Max-Age"); got == "" {
+		t.Error("Access-Control-Max-Age is not set")
+	}
+}
+
+func TestPreflightFromUnknownOrigin(t *testing.T) {
+	rec := preflight(t, "https://evil.example")
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+}
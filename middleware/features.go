@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// FeatureFlags records which optional endpoints are switched on
+type FeatureFlags struct {
+	enabled  map[string]bool
+	notFound http.Handler
+}
+
+// NewFeatureFlags creates the flag set from config.FeaturesConfig.Flags. Requests to a
+// disabled feature are answered by notFound, which should be the handler for
+// unregistered paths.
+func NewFeatureFlags(flags map[string]bool, notFound http.Handler) *FeatureFlags {
+	enabled := make(map[string]bool, len(flags))
+	for name, on := range flags {
+		enabled[strings.ToLower(name)] = on
+	}
+	return &FeatureFlags{
+		enabled:  enabled,
+		notFound: notFound,
+	}
+}
+
+// Enabled reports whether the feature is switched on; unknown features are off
+func (f *FeatureFlags) Enabled(flag string) bool {
+	return f.enabled[strings.ToLower(flag)]
+}
+
+// EnabledFlags returns the names of the enabled features, sorted
+func (f *FeatureFlags) EnabledFlags() []string {
+	var names []string
+	for name, on := range f.enabled {
+		if on {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// RequireFeature middleware serves the route only while the feature is enabled. A
+// disabled feature gets the same 404 as an unregistered path, so clients can't tell
+// it exists. Wrap it outermost so the 404 comes before any authentication error.
+func (f *FeatureFlags) RequireFeature(flag string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !f.Enabled(flag) {
+				f.notFound.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
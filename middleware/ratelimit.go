@@ -1,25 +1,79 @@
 package middleware
 
 import (
+	"cmp"
+	"context"
+	"gatekeeper/auth"
+	"gatekeeper/httputil"
+	"gatekeeper/models"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter stores rate limiters for each IP
+// RateLimitIdleTTL is how long an IP's limiter and counters are kept after its last request
+const RateLimitIdleTTL = time.Hour
+
+// clientLimit is the token bucket and request counters of one IP
+type clientLimit struct {
+	limiter        *rate.Limiter
+	allowed        int64
+	denied         int64
+	firstSeen      time.Time
+	lastSeen       time.Time
+	lastDenied     time.Time
+	lastDeniedPath string
+}
+
+// ClientRateStats reports how one IP has fared against the rate limit since it was first
+// seen. Counters are dropped once the IP has been idle for RateLimitIdleTTL.
+type ClientRateStats struct {
+	IP             string     `json:"ip"`
+	Allowed        int64      `json:"allowed"`
+	Denied         int64      `json:"denied"`
+	FirstSeen      time.Time  `json:"first_seen"`
+	LastSeen       time.Time  `json:"last_seen"`
+	LastDenied     *time.Time `json:"last_denied,omitempty"`
+	LastDeniedPath string     `json:"last_denied_path,omitempty"`
+}
+
+// adminTierContextKey marks requests that Middleware charged to an admin's own bucket
+// instead of their IP's
+const adminTierContextKey contextKey = "rate_limit_admin_tier"
+
+// RateLimiter stores rate limiters for each IP, and for each admin when admins have
+// their own limit
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
+	clients  map[string]*clientLimit
+	admins   map[string]*clientLimit // By user ID
 	mu       sync.Mutex
 	requests int
 	window   time.Duration
+	exempt   []string // Paths never rate limited; entries ending in / match a subtree
+	proxies  []netip.Prefix
+
+	jwtManager    *auth.JWTManager
+	adminRequests int            // Per admin and window; 0 limits admins by IP like everyone else
+	routes        *http.ServeMux // Where Middleware looks up whether a request's route is an AdminTierRoute
+}
+
+// AdminTierRoute marks a route handler that runs AuthMiddleware and then
+// AdminTierMiddleware, which re-checks a token's admin role against the stored user.
+// Only requests to such routes may be charged to an admin's own bucket.
+type AdminTierRoute struct {
+	http.Handler
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
 	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
+		clients:  make(map[string]*clientLimit),
+		admins:   make(map[string]*clientLimit),
 		requests: requests,
 		window:   window,
 	}
@@ -29,31 +83,176 @@ func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
 func (rl *RateLimiter) GetLimiter(ip string) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	return rl.client(ip, time.Now()).limiter
+}
+
+// client returns the entry for ip, creating it if needed. rl.mu must be held.
+func (rl *RateLimiter) client(ip string, now time.Time) *clientLimit {
+	return getClientLimit(rl.clients, ip, rl.requests, rl.window, now)
+}
 
-	limiter, exists := rl.limiters[ip]
+// getClientLimit returns the entry for key, creating one allowing requests per window
+func getClientLimit(clients map[string]*clientLimit, key string, requests int, window time.Duration, now time.Time) *clientLimit {
+	client, exists := clients[key]
 	if !exists {
 		// Calculate rate: requests per second
-		ratePerSecond := float64(rl.requests) / rl.window.Seconds()
-		limiter = rate.NewLimiter(rate.Limit(ratePerSecond), rl.requests)
-		rl.limiters[ip] = limiter
+		ratePerSecond := float64(requests) / window.Seconds()
+		client = &clientLimit{
+			limiter:   rate.NewLimiter(rate.Limit(ratePerSecond), requests),
+			firstSeen: now,
+		}
+		clients[key] = client
+	}
+	return client
+}
+
+// allow takes a token from the IP's bucket and counts the outcome
+func (rl *RateLimiter) allow(ip, path string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return take(rl.client(ip, time.Now()), path)
+}
+
+// allowAdmin takes a token from the admin's own bucket
+func (rl *RateLimiter) allowAdmin(userID, path string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return take(getClientLimit(rl.admins, userID, rl.adminRequests, rl.window, time.Now()), path)
+}
+
+// take takes a token from the client's bucket and counts the outcome. rl.mu must be held.
+func take(client *clientLimit, path string) bool {
+	now := time.Now()
+	client.lastSeen = now
+	if client.limiter.AllowN(now, 1) {
+		client.allowed++
+		return true
+	}
+	client.denied++
+	client.lastDenied = now
+	client.lastDeniedPath = path
+	return false
+}
+
+// Stats returns the counters of every IP seen within RateLimitIdleTTL, most denied first
+func (rl *RateLimiter) Stats() []ClientRateStats {
+	rl.mu.Lock()
+	stats := make([]ClientRateStats, 0, len(rl.clients))
+	for ip, client := range rl.clients {
+		entry := ClientRateStats{
+			IP:             ip,
+			Allowed:        client.allowed,
+			Denied:         client.denied,
+			FirstSeen:      client.firstSeen,
+			LastSeen:       client.lastSeen,
+			LastDeniedPath: client.lastDeniedPath,
+		}
+		if !client.lastDenied.IsZero() {
+			lastDenied := client.lastDenied
+			entry.LastDenied = &lastDenied
+		}
+		stats = append(stats, entry)
 	}
+	rl.mu.Unlock()
+
+	slices.SortFunc(stats, func(a, b ClientRateStats) int {
+		return cmp.Or(cmp.Compare(b.Denied, a.Denied), b.LastSeen.Compare(a.LastSeen), strings.Compare(a.IP, b.IP))
+	})
+	return stats
+}
+
+// SetExemptPaths sets the paths that bypass rate limiting, such as orchestrator
+// health probes that would otherwise exhaust the probe source's bucket
+func (rl *RateLimiter) SetExemptPaths(paths []string) {
+	rl.exempt = paths
+}
+
+// SetTrustedProxies sets the proxies whose forwarding headers identify the client;
+// requests from anywhere else are limited by their peer address
+func (rl *RateLimiter) SetTrustedProxies(proxies []netip.Prefix) {
+	rl.proxies = proxies
+}
 
-	return limiter
+// SetAdminLimit gives admins their own limit of requests per window, replacing the
+// per-IP limit for their requests, so bulk imports and backfills aren't throttled by the
+// limit meant for anonymous traffic. 0 keeps admins on the per-IP limit.
+func (rl *RateLimiter) SetAdminLimit(jwtManager *auth.JWTManager, requests int) {
+	rl.jwtManager = jwtManager
+	rl.adminRequests = requests
 }
 
-// Middleware returns the rate limiting middleware
+// SetRoutes sets the mux whose routes Middleware consults. Requests routed to anything
+// but an AdminTierRoute, such as login, token refresh and unknown paths, are always
+// charged to their IP, since nothing re-checks an admin token's role there.
+func (rl *RateLimiter) SetRoutes(mux *http.ServeMux) {
+	rl.routes = mux
+}
+
+// adminTierRoute reports whether the request is routed to an AdminTierRoute
+func (rl *RateLimiter) adminTierRoute(r *http.Request) bool {
+	if rl.routes == nil {
+		return false
+	}
+	handler, _ := rl.routes.Handler(r)
+	_, ok := handler.(AdminTierRoute)
+	return ok
+}
+
+// adminClaims returns the claims of the request's bearer token when it is a valid admin
+// token and admins have their own limit
+func (rl *RateLimiter) adminClaims(r *http.Request) *auth.Claims {
+	if rl.adminRequests <= 0 || rl.jwtManager == nil {
+		return nil
+	}
+	token, err := auth.ExtractToken(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil
+	}
+	claims, err := rl.jwtManager.ValidateAccessToken(token)
+	if err != nil || (claims.Role != models.RoleAdmin && claims.Role != models.RoleSuperAdmin) {
+		return nil
+	}
+	return claims
+}
+
+// isExempt reports whether a request path bypasses rate limiting
+func (rl *RateLimiter) isExempt(path string) bool {
+	return matchesPath(rl.exempt, path)
+}
+
+// matchesPath reports whether path equals one of patterns, or falls under a pattern
+// ending in /
+func matchesPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if path == pattern || (strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns the rate limiting middleware. It runs before authentication, so it
+// limits by IP. With SetAdminLimit, a request to an AdminTierRoute whose bearer token is
+// a valid admin token is charged to that admin's own bucket instead; AdminTierMiddleware
+// then confirms the role once the user is loaded. Everything else is limited by IP.
 func (rl *RateLimiter) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get client IP
-			ip := r.RemoteAddr
-			// Handle X-Forwarded-For header for proxied requests
-			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-				ip = forwarded
+			if rl.isExempt(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			limiter := rl.GetLimiter(ip)
-			if !limiter.Allow() {
+			if claims := rl.adminClaims(r); claims != nil && rl.adminTierRoute(r) {
+				if !rl.allowAdmin(claims.UserID, r.URL.Path) {
+					writeError(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminTierContextKey, true)))
+				return
+			}
+
+			if !rl.allow(httputil.RealIP(r, rl.proxies), r.URL.Path) {
 				writeError(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
 				return
 			}
@@ -63,16 +262,45 @@ func (rl *RateLimiter) Middleware() func(http.Handler) http.Handler {
 	}
 }
 
-// CleanupOldLimiters removes limiters that haven't been used recently
+// AdminTierMiddleware runs after AuthMiddleware. A token's role may be stale, so a
+// request Middleware charged to an admin's bucket is charged to its IP after all when
+// the stored user is no longer an admin.
+func (rl *RateLimiter) AdminTierMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if adminTier, _ := r.Context().Value(adminTierContextKey).(bool); adminTier {
+				if user, ok := GetUserFromContext(r.Context()); ok && !user.IsAdmin() {
+					if !rl.allow(httputil.RealIP(r, rl.proxies), r.URL.Path) {
+						writeError(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
+						return
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CleanupOldLimiters periodically removes the limiters and counters of IPs idle for
+// longer than RateLimitIdleTTL, which keeps memory bounded by recent traffic
 func (rl *RateLimiter) CleanupOldLimiters() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(RateLimitIdleTTL / 4)
 	go func() {
 		for range ticker.C {
-			rl.mu.Lock()
-			// In production, you'd track last access time and remove old entries
-			// For now, we'll just clear all to prevent memory leaks
-			rl.limiters = make(map[string]*rate.Limiter)
-			rl.mu.Unlock()
+			rl.evictIdle(time.Now().Add(-RateLimitIdleTTL))
 		}
 	}()
 }
+
+// evictIdle removes every IP and admin whose last request was before cutoff
+func (rl *RateLimiter) evictIdle(cutoff time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for _, clients := range []map[string]*clientLimit{rl.clients, rl.admins} {
+		for key, client := range clients {
+			if client.lastSeen.Before(cutoff) {
+				delete(clients, key)
+			}
+		}
+	}
+}
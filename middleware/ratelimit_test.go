@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"gatekeeper/auth"
+	"gatekeeper/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rateLimitedMux allows one request per IP and ten per admin. /api/entries is an
+// AdminTierRoute; /api/login is public.
+func rateLimitedMux(jwtManager *auth.JWTManager) http.Handler {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/login", ok)
+	mux.Handle("/api/entries", AdminTierRoute{Handler: ok})
+
+	rl := NewRateLimiter(1, time.Minute)
+	rl.SetAdminLimit(jwtManager, 10)
+	rl.SetRoutes(mux)
+	return rl.Middleware()(mux)
+}
+
+func statuses(t *testing.T, handler http.Handler, path, token string, n int) []int {
+	t.Helper()
+	codes := make([]int, n)
+	for i := range codes {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+	return codes
+}
+
+func TestAdminTokenOnPublicRouteChargedToIP(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret-at-least-32-characters-long", time.Hour, 24*time.Hour)
+	token, err := jwtManager.GenerateToken(&models.User{UserID: "admin-1", Username: "admin", Role: models.RoleAdmin})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	login := statuses(t, rateLimitedMux(jwtManager), "/api/login", token, 2)
+	if login[1] != http.StatusTooManyRequests {
+		t.Errorf("second login with an admin token = %d, want %d", login[1], http.StatusTooManyRequests)
+	}
+
+	entries := statuses(t, rateLimitedMux(jwtManager), "/api/entries", token, 2)
+	if entries[1] != http.StatusOK {
+		t.Errorf("second admin request to an authenticated route = %d, want %d", entries[1], http.StatusOK)
+	}
+}
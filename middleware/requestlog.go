@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const requestLogContextKey contextKey = "request_log"
+
+// redactedKeys are query parameters and headers whose values are never logged
+var redactedKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"refresh_token": true,
+	"authorization": true,
+}
+
+// requestLogInfo collects what inner middleware learns about a request, such as the
+// authenticated user, for the request log line
+type requestLogInfo struct {
+	userID string
+}
+
+// byteCounter wraps a ResponseWriter to capture the status code and body size
+type byteCounter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (c *byteCounter) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.bytes += n
+	return n, err
+}
+
+// Flush forwards to the wrapped writer so streaming responses still reach the client as
+// they are written
+func (c *byteCounter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (c *byteCounter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// countingReader counts the bytes a handler reads from the request body
+type countingReader struct {
+	io.ReadCloser
+	bytes int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytes += n
+	return n, err
+}
+
+// RequestLogMiddleware logs one line per request with method, path, redacted query,
+// status, duration, user ID and body sizes. Bodies and credentials are never logged.
+// It is meant for debugging sync issues and is off unless LOG_REQUESTS is set.
+func RequestLogMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			info := &requestLogInfo{}
+			recorder := &byteCounter{ResponseWriter: w, status: http.StatusOK}
+
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestLogContextKey, info)))
+
+			userID := info.userID
+			if userID == "" {
+				userID = "-"
+			}
+			log.Printf("🔎 %s %s query=%q status=%d duration=%s user=%s req_bytes=%d resp_bytes=%d",
+				r.Method, r.URL.Path, RedactQuery(r.URL.Query()), recorder.status, time.Since(start).Round(time.Millisecond),
+				userID, body.bytes, recorder.bytes)
+		})
+	}
+}
+
+// setRequestLogUser records the authenticated user for the request log line
+func setRequestLogUser(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(requestLogContextKey).(*requestLogInfo); ok {
+		info.userID = userID
+	}
+}
+
+// Redact returns a copy of values (query parameters or headers) with the values of
+// sensitive keys replaced, matching keys case-insensitively
+func Redact(values map[string][]string) map[string][]string {
+	redacted := make(map[string][]string, len(values))
+	for key, vals := range values {
+		if redactedKeys[strings.ToLower(key)] {
+			redacted[key] = []string{"[REDACTED]"}
+			continue
+		}
+		redacted[key] = vals
+	}
+	return redacted
+}
+
+// RedactQuery encodes query parameters for logging with sensitive values redacted
+func RedactQuery(query url.Values) string {
+	return url.Values(Redact(query)).Encode()
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	query := url.Values{
+		"token":     {"secret-token"},
+		"Password":  {"hunter2"},
+		"limit":     {"50"},
+		"device_id": {"tablet-7"},
+	}
+
+	logged := RedactQuery(query)
+	for _, secret := range []string{"secret-token", "hunter2"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("logged query %q contains %q", logged, secret)
+		}
+	}
+	for _, kept := range []string{"limit=50", "device_id=tablet-7", "token=%5BREDACTED%5D", "Password=%5BREDACTED%5D"} {
+		if !strings.Contains(logged, kept) {
+			t.Errorf("logged query %q lacks %q", logged, kept)
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{
+		"Authorization": {"Bearer abc.def.ghi"},
+		"User-Agent":    {"gatekeeper-android/3.2"},
+	}
+
+	redacted := Redact(headers)
+	if got := redacted["Authorization"]; len(got) != 1 || got[0] != "[REDACTED]" {
+		t.Errorf("Authorization = %v, want [REDACTED]", got)
+	}
+	if got := redacted["User-Agent"]; len(got) != 1 || got[0] != "gatekeeper-android/3.2" {
+		t.Errorf("User-Agent = %v, want it unchanged", got)
+	}
+	if headers.Get("Authorization") != "Bearer abc.def.ghi" {
+		t.Error("Redact modified its input")
+	}
+}
+
+func TestRequestLogForwardsFlush(t *testing.T) {
+	var flushErr error
+	handler := RequestLogMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first chunk"))
+		flushErr = http.NewResponseController(w).Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export", nil))
+
+	if flushErr != nil {
+		t.Fatalf("Flush through the request log writer: %v", flushErr)
+	}
+	if !rec.Flushed {
+		t.Error("the underlying writer was not flushed")
+	}
+}
+
+func TestByteCounterUnwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	counter := &byteCounter{ResponseWriter: rec}
+	if counter.Unwrap() != rec {
+		t.Error("Unwrap did not return the wrapped writer")
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HSTSMiddleware sets the Strict-Transport-Security header so browsers only use HTTPS
+func HSTSMiddleware(maxAge time.Duration) func(http.Handler) http.Handler {
+	header := fmt.Sprintf("max-age=%d; includeSubDomains", int(maxAge.Seconds()))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Strict-Transport-Security", header)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HTTPSRedirectHandler redirects plain HTTP requests to the same host on the HTTPS port
+func HTTPSRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
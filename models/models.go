@@ -4,6 +4,8 @@
 package models
 
 import (
+	"slices"
+	"sort"
 	"time"
 )
 
@@ -17,6 +19,29 @@ const (
 	EntryTypeOther     EntryType = "OTHER"
 )
 
+// validEntryTypes is the set of accepted entry types. Register new types here.
+var validEntryTypes = map[EntryType]bool{
+	EntryTypePersonnel: true,
+	EntryTypeTruck:     true,
+	EntryTypeCar:       true,
+	EntryTypeOther:     true,
+}
+
+// IsValid reports whether the entry type is one of the known values.
+func (t EntryType) IsValid() bool {
+	return validEntryTypes[t]
+}
+
+// EntryTypes returns every known entry type in sorted order.
+func EntryTypes() []EntryType {
+	types := make([]EntryType, 0, len(validEntryTypes))
+	for t := range validEntryTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
 // EntryStatus defines the synchronization status of a document.
 type EntryStatus string
 
@@ -25,6 +50,17 @@ const (
 	StatusDeleted EntryStatus = "DELETED"
 )
 
+// validEntryStatuses is the set of accepted entry statuses. Register new statuses here.
+var validEntryStatuses = map[EntryStatus]bool{
+	StatusActive:  true,
+	StatusDeleted: true,
+}
+
+// IsValid reports whether the entry status is one of the known values.
+func (s EntryStatus) IsValid() bool {
+	return validEntryStatuses[s]
+}
+
 // Entry is the unified struct for all checkpoint entries (Personnel, Vehicle, Other).
 // This struct maps directly to a Firestore document and is used for Go API request/response payloads.
 type Entry struct {
@@ -39,19 +75,108 @@ type Entry struct {
 	UpdatedAt     time.Time   `firestore:"updated_at" json:"updated_at"`         // CRITICAL: Server-authoritative timestamp for Last Write Wins
 	CreatedAt     time.Time   `firestore:"created_at" json:"created_at"`         // Server-validated creation time
 	Status        EntryStatus `firestore:"status" json:"status"`               // e.g., "ACTIVE", "DELETED"
+	OriginalUserID string     `firestore:"original_user_id,omitempty" json:"original_user_id,omitempty"` // Set when an admin reassigns the entry to another operator
+	OrgID         string      `firestore:"org_id,omitempty" json:"org_id,omitempty"` // Organization the entry belongs to (set from the pushing user)
+	Sequence      int64       `firestore:"sequence,omitempty" json:"sequence,omitempty"` // Per-checkpoint number assigned on first write; gaps reveal missing entries. 0 on entries that predate numbering
+
+	// === Supervisor Review (Set only via the review endpoint, preserved across pushes) ===
+	Reviewed      bool        `firestore:"reviewed" json:"reviewed"`
+	ReviewedBy    string      `firestore:"reviewed_by,omitempty" json:"reviewed_by,omitempty"` // Supervisor or admin who last set the review state
+	ReviewedAt    time.Time   `firestore:"reviewed_at" json:"reviewed_at"`
+	FlagReason    string      `firestore:"flag_reason,omitempty" json:"flag_reason,omitempty"` // Non-empty when flagged for follow-up
 
 	// === Type-Specific Data (Flexible Payload) ===
 	// This map holds the specific data fields for the entry type.
 	Payload       map[string]interface{} `firestore:"payload" json:"payload"` 
+	PayloadSchemaVersion int             `firestore:"payload_schema_version,omitempty" json:"payload_schema_version,omitempty"` // Form schema that produced the payload; 0 for clients that predate versioning
+}
+
+// AuditAction identifies the kind of event an audit log records.
+type AuditAction string
+
+const (
+	AuditActionAdminRequest        AuditAction = "ADMIN_REQUEST" // Audited request whose handler did not name an action
+	AuditActionCreateUser          AuditAction = "ADMIN_CREATE_USER"
+	AuditActionUpdateUser          AuditAction = "ADMIN_UPDATE_USER"
+	AuditActionUpdateRole          AuditAction = "ADMIN_UPDATE_ROLE"
+	AuditActionBulkUpdateRoles     AuditAction = "ADMIN_BULK_UPDATE_ROLES"
+	AuditActionUnassignSupervisor  AuditAction = "ADMIN_UNASSIGN_SUPERVISOR"
+	AuditActionSetUserCheckpoints  AuditAction = "ADMIN_SET_USER_CHECKPOINTS"
+	AuditActionDeleteUser          AuditAction = "ADMIN_DELETE_USER"
+	AuditActionImportUsers         AuditAction = "ADMIN_IMPORT_USERS"
+	AuditActionUnlockUser          AuditAction = "ADMIN_UNLOCK_USER"
+	AuditActionCreateCheckpoint    AuditAction = "ADMIN_CREATE_CHECKPOINT"
+	AuditActionUpdateCheckpoint    AuditAction = "ADMIN_UPDATE_CHECKPOINT"
+	AuditActionImportCheckpoints   AuditAction = "ADMIN_IMPORT_CHECKPOINTS"
+	AuditActionAssignCheckpoint    AuditAction = "ADMIN_ASSIGN_CHECKPOINT"
+	AuditActionUnassignCheckpoint  AuditAction = "ADMIN_UNASSIGN_CHECKPOINT"
+	AuditActionReassignEntries     AuditAction = "ADMIN_REASSIGN_ENTRIES"
+	AuditActionDeleteEntries       AuditAction = "ADMIN_DELETE_ENTRIES"
+	AuditActionEntryRetentionPurge AuditAction = "ENTRY_RETENTION_PURGE"
+	AuditActionBackfillEntries     AuditAction = "ADMIN_BACKFILL_ENTRIES"
+	AuditActionDataExport          AuditAction = "DATA_EXPORT"
+	AuditActionReviewEntries       AuditAction = "REVIEW_ENTRIES"
+	AuditActionResetPassword       AuditAction = "RESET_PASSWORD"
+	AuditActionAuditLogArchival    AuditAction = "AUDIT_LOG_ARCHIVAL"
+	AuditActionEntryStatus         AuditAction = "ENTRY_STATUS_CHANGE"
+	AuditActionResolveEntry        AuditAction = "SYNC_RESOLVE_ENTRY"
+)
+
+// validAuditActions is the set of accepted audit actions. Register new actions here.
+var validAuditActions = map[AuditAction]bool{
+	AuditActionAdminRequest:        true,
+	AuditActionCreateUser:          true,
+	AuditActionUpdateUser:          true,
+	AuditActionUpdateRole:          true,
+	AuditActionBulkUpdateRoles:     true,
+	AuditActionUnassignSupervisor:  true,
+	AuditActionSetUserCheckpoints:  true,
+	AuditActionDeleteUser:          true,
+	AuditActionImportUsers:         true,
+	AuditActionUnlockUser:          true,
+	AuditActionCreateCheckpoint:    true,
+	AuditActionUpdateCheckpoint:    true,
+	AuditActionImportCheckpoints:   true,
+	AuditActionAssignCheckpoint:    true,
+	AuditActionUnassignCheckpoint:  true,
+	AuditActionReassignEntries:     true,
+	AuditActionDeleteEntries:       true,
+	AuditActionEntryRetentionPurge: true,
+	AuditActionBackfillEntries:     true,
+	AuditActionDataExport:          true,
+	AuditActionReviewEntries:       true,
+	AuditActionResetPassword:       true,
+	AuditActionAuditLogArchival:    true,
+	AuditActionEntryStatus:         true,
+	AuditActionResolveEntry:        true,
+}
+
+// IsValid reports whether the audit action is one of the known values.
+func (a AuditAction) IsValid() bool {
+	return validAuditActions[a]
+}
+
+// AuditActions returns every known audit action in sorted order.
+func AuditActions() []AuditAction {
+	actions := make([]AuditAction, 0, len(validAuditActions))
+	for action := range validAuditActions {
+		actions = append(actions, action)
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+	return actions
 }
 
 // AuditLog represents an audit log entry.
 type AuditLog struct {
-	LogID    string `firestore:"log_id" json:"log_id"`
-	Timestamp string `firestore:"timestamp" json:"timestamp"`
-	UserID   string `firestore:"user_id" json:"user_id"`
-	Action   string `firestore:"action" json:"action"`
-	Details  string `firestore:"details" json:"details"`
+	LogID      string      `firestore:"log_id" json:"log_id"`
+	Timestamp  string      `firestore:"timestamp" json:"timestamp"`
+	UserID     string      `firestore:"user_id" json:"user_id"`
+	Action     AuditAction `firestore:"action" json:"action"`
+	Details    string      `firestore:"details" json:"details"`
+	Route      string      `firestore:"route,omitempty" json:"route,omitempty"`             // Request method and path, set by the audit middleware
+	StatusCode int         `firestore:"status_code,omitempty" json:"status_code,omitempty"` // Response status of the audited request
+	OrgID      string      `firestore:"org_id,omitempty" json:"org_id,omitempty"`
+	RecordIDs  []string    `firestore:"record_ids,omitempty" json:"record_ids,omitempty"` // Entries the event changed, for the entry trail
 }
 
 // Checkpoint represents a checkpoint in the system.
@@ -59,6 +184,14 @@ type Checkpoint struct {
 	CheckpointID string `firestore:"checkpoint_id" json:"checkpoint_id"`
 	Name        string `firestore:"name" json:"name"`
 	Location    string `firestore:"location" json:"location"`
+	OrgID       string `firestore:"org_id,omitempty" json:"org_id,omitempty"`
+	// AllowedEntryTypes restricts a specialized gate to some kinds of entry; empty allows all
+	AllowedEntryTypes []EntryType `firestore:"allowed_entry_types,omitempty" json:"allowed_entry_types,omitempty"`
+}
+
+// AllowsEntryType reports whether entries of type t may be logged at the checkpoint
+func (c *Checkpoint) AllowsEntryType(t EntryType) bool {
+	return len(c.AllowedEntryTypes) == 0 || slices.Contains(c.AllowedEntryTypes, t)
 }
 
 // UserRole defines the access level of a user.
@@ -68,8 +201,22 @@ const (
 	RoleAdmin       UserRole = "ADMIN"
 	RoleSupervisor  UserRole = "SUPERVISOR"
 	RoleGateOperator UserRole = "GATE_OPERATOR"
+	RoleSuperAdmin  UserRole = "SUPER_ADMIN" // Cross-organization management
 )
 
+// validUserRoles is the set of accepted user roles.
+var validUserRoles = map[UserRole]bool{
+	RoleAdmin:        true,
+	RoleSupervisor:   true,
+	RoleGateOperator: true,
+	RoleSuperAdmin:   true,
+}
+
+// IsValid reports whether the role is one of the known values.
+func (r UserRole) IsValid() bool {
+	return validUserRoles[r]
+}
+
 // User represents an authenticated user in the system.
 // This struct is essential for Role-Based Access Control (RBAC).
 type User struct {
@@ -80,6 +227,44 @@ type User struct {
 	SupervisorID       string   `firestore:"supervisor_id,omitempty" json:"supervisor_id,omitempty"` // For GATE_OPERATOR: which supervisor manages them
 	ManagedOperators   []string `firestore:"managed_operators,omitempty" json:"managed_operators,omitempty"` // For SUPERVISOR: list of operator user_ids they manage
 	LastLogin          time.Time `firestore:"last_login" json:"last_login"`
+	OrgID              string   `firestore:"org_id,omitempty" json:"org_id,omitempty"` // Organization the user belongs to; empty in single-tenant deployments
+	PasswordChangedAt  time.Time `firestore:"password_changed_at" json:"-"` // Tokens issued before this are rejected
+	LastSyncAt         time.Time `firestore:"last_sync_at" json:"last_sync_at"` // Last successful push or pull; updated with a targeted field write
+	LastActivityAt     time.Time `firestore:"last_activity_at" json:"-"` // Last authenticated request, recorded at most once a minute; drives idle expiry
+	Permissions        []Permission `firestore:"permissions,omitempty" json:"permissions,omitempty"` // Granted on top of the role's default permissions
+}
+
+// RefreshSession is the server-side record of an issued refresh token.
+// A refresh token is only honored while its session exists and is not revoked.
+type RefreshSession struct {
+	SessionID string    `firestore:"session_id" json:"session_id"` // Matches the refresh token's jti claim
+	UserID    string    `firestore:"user_id" json:"user_id"`
+	IssuedAt  time.Time `firestore:"issued_at" json:"issued_at"`
+	ExpiresAt time.Time `firestore:"expires_at" json:"expires_at"`
+	Revoked   bool      `firestore:"revoked" json:"revoked"`
+}
+
+// CheckpointSequence is the counter behind Entry.Sequence for one checkpoint
+type CheckpointSequence struct {
+	CheckpointID string `firestore:"checkpoint_id" json:"checkpoint_id"`
+	LastSequence int64  `firestore:"last_sequence" json:"last_sequence"`
+}
+
+// UsernameClaim reserves a username for one user. Its document ID is derived from the
+// username, so two users can never claim the same one.
+type UsernameClaim struct {
+	Username string `firestore:"username" json:"username"`
+	UserID   string `firestore:"user_id" json:"user_id"`
+	OrgID    string `firestore:"org_id,omitempty" json:"org_id,omitempty"`
+}
+
+// SyncCursor records how far a device has confirmed receipt of pulled entries. Pulls
+// that name the device and give no since resume from AckedThrough.
+type SyncCursor struct {
+	UserID       string    `firestore:"user_id" json:"user_id"`
+	DeviceID     string    `firestore:"device_id" json:"device_id"`
+	AckedThrough time.Time `firestore:"acked_through" json:"acked_through"` // Every entry change made at or before this was received
+	AckedAt      time.Time `firestore:"acked_at" json:"acked_at"`
 }
 
 // AuthRequest is the payload for mock login
@@ -0,0 +1,32 @@
+package models
+
+import "encoding/json"
+
+// NormalizePayloadNumbers replaces the json.Number values a decoder with UseNumber leaves
+// in a payload, including inside nested objects and arrays. Integers become int64, which
+// Firestore stores exactly, so IDs such as 16-digit national ID numbers survive; decoding
+// them as float64 would silently round them. Other numbers, and integers beyond int64,
+// become float64.
+func NormalizePayloadNumbers(payload map[string]interface{}) {
+	for key, value := range payload {
+		payload[key] = normalizeNumber(value)
+	}
+}
+
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		NormalizePayloadNumbers(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeNumber(v[i])
+		}
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+package models
+
+import "slices"
+
+// Permission grants a single capability independent of role
+type Permission string
+
+const (
+	PermissionExportEntries  Permission = "EXPORT_ENTRIES"
+	PermissionResetPasswords Permission = "RESET_PASSWORDS"
+	PermissionReviewEntries  Permission = "REVIEW_ENTRIES"
+)
+
+// validPermissions is the set of permissions that can be granted
+var validPermissions = map[Permission]bool{
+	PermissionExportEntries:  true,
+	PermissionResetPasswords: true,
+	PermissionReviewEntries:  true,
+}
+
+// IsValid reports whether the permission is one of the known values.
+func (p Permission) IsValid() bool {
+	return validPermissions[p]
+}
+
+// rolePermissions is the default permission set of each role. Super admins hold every
+// permission. Grants in User.Permissions add to these defaults and never remove any.
+var rolePermissions = map[UserRole][]Permission{
+	RoleAdmin:      {PermissionExportEntries, PermissionResetPasswords, PermissionReviewEntries},
+	RoleSupervisor: {PermissionExportEntries, PermissionResetPasswords, PermissionReviewEntries},
+}
+
+// HasPermission reports whether the user holds the permission through their role or an
+// explicit grant
+func (u *User) HasPermission(p Permission) bool {
+	if u.Role == RoleSuperAdmin {
+		return true
+	}
+	return slices.Contains(rolePermissions[u.Role], p) || slices.Contains(u.Permissions, p)
+}
+
+// EffectivePermissions returns every permission the user holds, sorted
+func (u *User) EffectivePermissions() []Permission {
+	if u.Role == RoleSuperAdmin {
+		all := make([]Permission, 0, len(validPermissions))
+		for p := range validPermissions {
+			all = append(all, p)
+		}
+		slices.Sort(all)
+		return all
+	}
+
+	effective := append(slices.Clone(rolePermissions[u.Role]), u.Permissions...)
+	slices.Sort(effective)
+	return slices.Compact(effective)
+}
+
+// IsAdmin reports whether the user administers their whole organization, or every
+// organization for super admins
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin || u.Role == RoleSuperAdmin
+}
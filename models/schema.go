@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PayloadSchemaUnversioned marks payloads from clients that predate schema versioning.
+// Their shape is unknown, so they are stored as sent without field checks.
+const PayloadSchemaUnversioned = 0
+
+// PayloadSchema describes the payload fields one version of an entry form produces.
+// Fields not listed are accepted so newer clients can add fields ahead of the server.
+type PayloadSchema struct {
+	Required []string
+	Optional []string
+	// Upgrade converts a payload of this version to the next version of the same entry
+	// type. Leave nil on the latest version.
+	Upgrade func(payload map[string]interface{}) map[string]interface{}
+}
+
+type payloadSchemaKey struct {
+	entryType EntryType
+	version   int
+}
+
+// payloadSchemas maps each entry type and version to its schema. Register new versions
+// here, and set Upgrade on the previous version so older payloads are migrated.
+var payloadSchemas = map[payloadSchemaKey]PayloadSchema{
+	{EntryTypePersonnel, 1}: {
+		Required: []string{"full_name"},
+		Optional: []string{"id_number", "company", "purpose", "phone"},
+	},
+	{EntryTypeTruck, 1}: {
+		Required: []string{"plate_number"},
+		Optional: []string{"driver_name", "company", "cargo", "trailer_number"},
+	},
+	{EntryTypeCar, 1}: {
+		Required: []string{"plate_number"},
+		Optional: []string{"driver_name", "passengers", "purpose"},
+	},
+	{EntryTypeOther, 1}: {
+		Required: []string{"description"},
+	},
+}
+
+// PayloadSchemaError lists every problem found in a payload
+type PayloadSchemaError struct {
+	Violations []string
+}
+
+func (e *PayloadSchemaError) Error() string {
+	return "invalid payload: " + strings.Join(e.Violations, "; ")
+}
+
+// LatestPayloadSchemaVersion returns the newest registered schema version of the entry
+// type, or PayloadSchemaUnversioned when none is registered.
+func LatestPayloadSchemaVersion(t EntryType) int {
+	latest := PayloadSchemaUnversioned
+	for key := range payloadSchemas {
+		if key.entryType == t && key.version > latest {
+			latest = key.version
+		}
+	}
+	return latest
+}
+
+// LookupPayloadSchema returns the schema of the entry type at the given version
+func LookupPayloadSchema(t EntryType, version int) (PayloadSchema, bool) {
+	schema, ok := payloadSchemas[payloadSchemaKey{t, version}]
+	return schema, ok
+}
+
+// MigratePayload validates the entry's payload against its schema version, upgrading it
+// to the latest version first where upgrades are registered. The entry's payload and
+// version are updated in place. Unknown versions and missing required fields are rejected.
+func MigratePayload(entry *Entry) error {
+	if entry.PayloadSchemaVersion == PayloadSchemaUnversioned {
+		return nil
+	}
+
+	schema, ok := LookupPayloadSchema(entry.EntryType, entry.PayloadSchemaVersion)
+	if !ok {
+		return fmt.Errorf("unknown payload schema version %d for entry type %s", entry.PayloadSchemaVersion, entry.EntryType)
+	}
+	for schema.Upgrade != nil {
+		next, ok := LookupPayloadSchema(entry.EntryType, entry.PayloadSchemaVersion+1)
+		if !ok {
+			break
+		}
+		entry.Payload = schema.Upgrade(entry.Payload)
+		entry.PayloadSchemaVersion++
+		schema = next
+	}
+
+	var violations []string
+	for _, field := range schema.Required {
+		if value, ok := entry.Payload[field]; !ok || value == nil || value == "" {
+			violations = append(violations, fmt.Sprintf("%s is required", field))
+		}
+	}
+	if len(violations) > 0 {
+		return &PayloadSchemaError{Violations: violations}
+	}
+	return nil
+}
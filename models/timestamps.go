@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Now returns the current time in UTC. Timestamps the server sets use it, so stored
+// values never depend on the host's time zone.
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// FormatTimestamp renders t in UTC as RFC 3339, the format of audit log timestamps
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NormalizeTimestamps converts the entry's timestamps to UTC. The db layer calls it
+// before writing, so client-supplied times in other zones are stored alike.
+func (e *Entry) NormalizeTimestamps() {
+	e.ClientTS = e.ClientTS.UTC()
+	e.UpdatedAt = e.UpdatedAt.UTC()
+	e.CreatedAt = e.CreatedAt.UTC()
+	e.ReviewedAt = e.ReviewedAt.UTC()
+}
+
+// NormalizeTimestamps converts the user's timestamps to UTC before they are written
+func (u *User) NormalizeTimestamps() {
+	u.LastLogin = u.LastLogin.UTC()
+	u.PasswordChangedAt = u.PasswordChangedAt.UTC()
+	u.LastSyncAt = u.LastSyncAt.UTC()
+	u.LastActivityAt = u.LastActivityAt.UTC()
+}
@@ -0,0 +1,53 @@
+package models
+
+import "fmt"
+
+// entryStatusTransitions lists every legal entry status change and the roles allowed to
+// make it. Super admins may make any listed change. Add new statuses here together with
+// the transitions into and out of them.
+var entryStatusTransitions = map[EntryStatus]map[EntryStatus][]UserRole{
+	StatusActive: {
+		StatusDeleted: {RoleGateOperator, RoleSupervisor, RoleAdmin},
+	},
+	StatusDeleted: {
+		// Restoring a deleted entry undoes a decision made at the gate
+		StatusActive: {RoleAdmin},
+	},
+}
+
+// StatusTransitionError explains why an entry status change was refused
+type StatusTransitionError struct {
+	From EntryStatus
+	To   EntryStatus
+	Role UserRole
+	// Illegal is set when no role may make the change, as opposed to just this one
+	Illegal bool
+}
+
+func (e *StatusTransitionError) Error() string {
+	if e.Illegal {
+		return fmt.Sprintf("Entries cannot change from %s to %s", e.From, e.To)
+	}
+	return fmt.Sprintf("Role %s cannot change entries from %s to %s", e.Role, e.From, e.To)
+}
+
+// CheckStatusTransition reports whether the role may change an entry's status from one
+// value to another. Keeping the same status is always allowed.
+func CheckStatusTransition(from, to EntryStatus, role UserRole) error {
+	if from == to {
+		return nil
+	}
+	roles, ok := entryStatusTransitions[from][to]
+	if !ok {
+		return &StatusTransitionError{From: from, To: to, Role: role, Illegal: true}
+	}
+	if role == RoleSuperAdmin {
+		return nil
+	}
+	for _, allowed := range roles {
+		if allowed == role {
+			return nil
+		}
+	}
+	return &StatusTransitionError{From: from, To: to, Role: role}
+}
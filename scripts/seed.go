@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"gatekeeper/auth"
 	"gatekeeper/config"
 	"gatekeeper/db"
 	"gatekeeper/models"
 	"log"
-	"time"
+	"os"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	force := flag.Bool("force", false, "Overwrite documents that already exist (resets seeded users' passwords)")
+	only := flag.String("only", "", "Seed only one collection: users or checkpoints")
+	orgID := flag.String("org", "", "Organization the seeded users and checkpoints belong to (empty for single-tenant deployments)")
+	flag.Parse()
+
+	if *only != "" && *only != "users" && *only != "checkpoints" {
+		log.Fatalf("Invalid -only value %q (use users or checkpoints)", *only)
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
@@ -25,28 +36,50 @@ func main() {
 
 	// Initialize Firestore
 	ctx := context.Background()
-	firestoreDB, err := db.NewFirestoreDB(ctx, cfg.Firebase.ProjectID, cfg.Firebase.CredentialsPath)
+	firestoreDB, err := db.NewFirestoreDB(ctx, cfg.FirestoreOptions())
 	if err != nil {
 		log.Fatalf("Failed to initialize Firestore: %v", err)
 	}
 	defer firestoreDB.Close()
 
 	log.Println("🌱 Starting database seeding...")
+	summary := &seedSummary{}
+	if *force {
+		log.Println("⚠️  -force set: existing documents will be overwritten")
+	}
 
 	// Seed checkpoints
-	if err := seedCheckpoints(firestoreDB); err != nil {
-		log.Fatalf("Failed to seed checkpoints: %v", err)
+	if *only == "" || *only == "checkpoints" {
+		if err := seedCheckpoints(firestoreDB, *orgID, *force, summary); err != nil {
+			log.Fatalf("Failed to seed checkpoints: %v", err)
+		}
 	}
 
 	// Seed users
-	if err := seedUsers(firestoreDB); err != nil {
-		log.Fatalf("Failed to seed users: %v", err)
+	if *only == "" || *only == "users" {
+		password := os.Getenv("SEED_PASSWORD")
+		if password == "" {
+			log.Fatal("SEED_PASSWORD must be set to the password for seeded users")
+		}
+		if err := auth.ValidatePasswordStrength(password); err != nil {
+			log.Fatalf("SEED_PASSWORD is too weak: %v", err)
+		}
+
+		if err := seedUsers(firestoreDB, *orgID, password, *force, summary); err != nil {
+			log.Fatalf("Failed to seed users: %v", err)
+		}
 	}
 
-	log.Println("✅ Database seeding completed successfully!")
+	log.Printf("✅ Database seeding completed successfully! (created: %d, skipped: %d)", summary.created, summary.skipped)
+}
+
+// seedSummary counts documents created and skipped across a seeding run
+type seedSummary struct {
+	created int
+	skipped int
 }
 
-func seedCheckpoints(db *db.FirestoreDB) error {
+func seedCheckpoints(firestoreDB *db.FirestoreDB, orgID string, force bool, summary *seedSummary) error {
 	checkpoints := []models.Checkpoint{
 		{
 			CheckpointID: "CP-EAST-MAIN",
@@ -71,16 +104,28 @@ func seedCheckpoints(db *db.FirestoreDB) error {
 	}
 
 	for _, checkpoint := range checkpoints {
-		if err := db.CreateCheckpoint(&checkpoint); err != nil {
+		checkpoint.OrgID = orgID
+
+		// CreateCheckpoint refuses taken IDs, so forced reseeds update in place
+		err := firestoreDB.CreateCheckpoint(&checkpoint)
+		if errors.Is(err, db.ErrCheckpointExists) && force {
+			_, _, err = firestoreDB.UpsertCheckpoint(&checkpoint)
+		} else if errors.Is(err, db.ErrCheckpointExists) {
+			log.Printf("  - Skipped existing checkpoint: %s", checkpoint.Name)
+			summary.skipped++
+			continue
+		}
+		if err != nil {
 			return fmt.Errorf("failed to create checkpoint %s: %w", checkpoint.CheckpointID, err)
 		}
 		log.Printf("  ✓ Created checkpoint: %s", checkpoint.Name)
+		summary.created++
 	}
 
 	return nil
 }
 
-func seedUsers(firestoreDB *db.FirestoreDB) error {
+func seedUsers(firestoreDB *db.FirestoreDB, orgID, password string, force bool, summary *seedSummary) error {
 	users := []struct {
 		User     models.User
 		Password string
@@ -91,9 +136,9 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 				Username:           "admin",
 				Role:               models.RoleAdmin,
 				AllowedCheckpoints: []string{},
-				LastLogin:          time.Now(),
+				LastLogin:          models.Now(),
 			},
-			Password: "password",
+			Password: password,
 		},
 		{
 			User: models.User{
@@ -102,9 +147,9 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 				Role:               models.RoleSupervisor,
 				AllowedCheckpoints: []string{"CP-EAST-MAIN", "CP-NORTH-01"},
 				ManagedOperators:   []string{},
-				LastLogin:          time.Now(),
+				LastLogin:          models.Now(),
 			},
-			Password: "password",
+			Password: password,
 		},
 		{
 			User: models.User{
@@ -113,9 +158,9 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 				Role:               models.RoleGateOperator,
 				AllowedCheckpoints: []string{"CP-EAST-MAIN"},
 				SupervisorID:       "user-supervisor-john",
-				LastLogin:          time.Now(),
+				LastLogin:          models.Now(),
 			},
-			Password: "password",
+			Password: password,
 		},
 		{
 			User: models.User{
@@ -123,15 +168,29 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 				Username:           "op_west",
 				Role:               models.RoleGateOperator,
 				AllowedCheckpoints: []string{"CP-WEST-GATE"},
-				LastLogin:          time.Now(),
+				LastLogin:          models.Now(),
 			},
-			Password: "password",
+			Password: password,
 		},
 	}
 
 	for _, userData := range users {
-		// Create user
-		if err := firestoreDB.CreateUser(&userData.User); err != nil {
+		userData.User.OrgID = orgID
+		_, err := firestoreDB.GetUser(userData.User.UserID)
+		exists := err == nil
+		if exists && !force {
+			log.Printf("  - Skipped existing user: %s", userData.User.Username)
+			summary.skipped++
+			continue
+		}
+
+		// CreateUser refuses taken usernames, so forced reseeds overwrite in place
+		if exists {
+			err = firestoreDB.UpdateUser(&userData.User)
+		} else {
+			err = firestoreDB.CreateUser(&userData.User)
+		}
+		if err != nil {
 			return fmt.Errorf("failed to create user %s: %w", userData.User.Username, err)
 		}
 
@@ -146,6 +205,7 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 		}
 
 		log.Printf("  ✓ Created user: %s (role: %s)", userData.User.Username, userData.User.Role)
+		summary.created++
 	}
 
 	// Update supervisor's managed operators
@@ -154,7 +214,15 @@ func seedUsers(firestoreDB *db.FirestoreDB) error {
 		return fmt.Errorf("failed to get supervisor: %w", err)
 	}
 
-	supervisor.ManagedOperators = []string{"user-op-east"}
+	// Add the seeded operator without dropping operators assigned since the last run
+	for _, operatorID := range supervisor.ManagedOperators {
+		if operatorID == "user-op-east" {
+			log.Println("  - Supervisor relationships already up to date")
+			return nil
+		}
+	}
+
+	supervisor.ManagedOperators = append(supervisor.ManagedOperators, "user-op-east")
 	if err := firestoreDB.UpdateUser(supervisor); err != nil {
 		return fmt.Errorf("failed to update supervisor: %w", err)
 	}
@@ -8,6 +8,7 @@ echo "================================"
 echo ""
 
 BASE_URL="http://localhost:8080"
+SEED_PASSWORD="${SEED_PASSWORD:?Set SEED_PASSWORD to the password used when seeding}"
 
 # Colors for output
 GREEN='\033[0;32m'
@@ -65,7 +66,7 @@ echo "----------------------------"
 
 # Test admin login
 echo -n "Admin Login... "
-admin_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d '{"username":"admin","password":"password"}')
+admin_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d "{\"username\":\"admin\",\"password\":\"$SEED_PASSWORD\"}")
 admin_token=$(echo $admin_response | grep -o '"token":"[^"]*' | cut -d'"' -f4)
 
 if [ -n "$admin_token" ]; then
@@ -79,7 +80,7 @@ fi
 
 # Test supervisor login
 echo -n "Supervisor Login... "
-supervisor_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d '{"username":"supervisor_john","password":"password"}')
+supervisor_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d "{\"username\":\"supervisor_john\",\"password\":\"$SEED_PASSWORD\"}")
 supervisor_token=$(echo $supervisor_response | grep -o '"token":"[^"]*' | cut -d'"' -f4)
 
 if [ -n "$supervisor_token" ]; then
@@ -93,7 +94,7 @@ fi
 
 # Test operator login
 echo -n "Operator Login... "
-operator_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d '{"username":"op_east","password":"password"}')
+operator_response=$(curl -s -X POST "$BASE_URL/api/login" -H "Content-Type: application/json" -d "{\"username\":\"op_east\",\"password\":\"$SEED_PASSWORD\"}")
 operator_token=$(echo $operator_response | grep -o '"token":"[^"]*' | cut -d'"' -f4)
 
 if [ -n "$operator_token" ]; then